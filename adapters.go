@@ -0,0 +1,189 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var _ Worker = (*funcWorker)(nil)
+
+// funcWorker defines a worker running a function until it is terminated.
+type funcWorker struct {
+	run    func(ctx context.Context) error
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newFuncWorker(run func(ctx context.Context) error) *funcWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &funcWorker{
+		run:    run,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Init implements the Worker interface.
+func (w *funcWorker) Init(*zap.Logger) error {
+	return nil
+}
+
+// Run implements the Worker interface.
+func (w *funcWorker) Run() error {
+	started := false
+	w.once.Do(func() { started = true })
+	if !started {
+		return nil
+	}
+	defer close(w.done)
+	return w.run(w.ctx)
+}
+
+// Terminate implements the Worker interface. It cancels the function's context
+// and waits for the function to return.
+func (w *funcWorker) Terminate() error {
+	w.cancel()
+	started := true
+	w.once.Do(func() { started = false })
+	if started {
+		<-w.done
+	}
+	return nil
+}
+
+var (
+	_ Worker    = (*ctxWorker)(nil)
+	_ unwrapper = (*ctxWorker)(nil)
+)
+
+// ctxWorker adapts a WorkerCtx to the Worker interface.
+type ctxWorker struct {
+	w      WorkerCtx
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newCtxWorker(parent context.Context, w WorkerCtx) *ctxWorker {
+	ctx, cancel := context.WithCancel(parent)
+	return &ctxWorker{
+		w:      w,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Init implements the Worker interface.
+func (w *ctxWorker) Init(logger *zap.Logger) error {
+	return w.w.Init(logger)
+}
+
+// Run implements the Worker interface. Returning the context's error once it
+// is canceled is not a failure.
+func (w *ctxWorker) Run() error {
+	started := false
+	w.once.Do(func() { started = true })
+	if !started {
+		return nil
+	}
+	defer close(w.done)
+	err := w.w.Run(w.ctx)
+	if w.ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// Terminate implements the Worker interface. It cancels the worker's context,
+// waits for Run to return, and terminates the worker if it implements
+// Terminate.
+func (w *ctxWorker) Terminate() error {
+	w.cancel()
+	started := true
+	w.once.Do(func() { started = false })
+	if started {
+		<-w.done
+	}
+	if t, ok := w.w.(interface{ Terminate() error }); ok {
+		return t.Terminate()
+	}
+	return nil
+}
+
+func (w *ctxWorker) unwrapWorker() interface{} {
+	return w.w
+}
+
+var _ Worker = (*WorkerFromFuncs)(nil)
+
+// WorkerFromFuncs adapts functions to the Worker interface. Nil functions are
+// no-ops.
+type WorkerFromFuncs struct {
+	InitFunc      func(*zap.Logger) error
+	RunFunc       func() error
+	TerminateFunc func() error
+}
+
+// Init implements the Worker interface.
+func (w *WorkerFromFuncs) Init(logger *zap.Logger) error {
+	if w.InitFunc == nil {
+		return nil
+	}
+	return w.InitFunc(logger)
+}
+
+// Run implements the Worker interface.
+func (w *WorkerFromFuncs) Run() error {
+	if w.RunFunc == nil {
+		return nil
+	}
+	return w.RunFunc()
+}
+
+// Terminate implements the Worker interface.
+func (w *WorkerFromFuncs) Terminate() error {
+	if w.TerminateFunc == nil {
+		return nil
+	}
+	return w.TerminateFunc()
+}
+
+// WorkerFromCloser returns a worker running the blocking run function and
+// closing c on termination, e.g. a server's Serve method and the server.
+func WorkerFromCloser(run func() error, c io.Closer) Worker {
+	return &WorkerFromFuncs{
+		RunFunc:       run,
+		TerminateFunc: c.Close,
+	}
+}
+
+// WorkerFromStartStop returns a worker for components exposing a non-blocking
+// start and a stop function. The worker runs until it is terminated.
+func WorkerFromStartStop(start, stop func() error) Worker {
+	stopped := make(chan struct{})
+	var once sync.Once
+	return &WorkerFromFuncs{
+		RunFunc: func() error {
+			if err := start(); err != nil {
+				return err
+			}
+			<-stopped
+			return nil
+		},
+		TerminateFunc: func() error {
+			defer once.Do(func() { close(stopped) })
+			return stop()
+		},
+	}
+}
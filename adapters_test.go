@@ -0,0 +1,105 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type closerMock struct {
+	closed chan struct{}
+}
+
+func (c *closerMock) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestWorkerFromFuncs(t *testing.T) {
+	w := &WorkerFromFuncs{}
+	assert.NoError(t, w.Init(zap.NewNop()))
+	assert.NoError(t, w.Run())
+	assert.NoError(t, w.Terminate())
+
+	errBoom := errors.New("boom")
+	w = &WorkerFromFuncs{RunFunc: func() error { return errBoom }}
+	assert.Equal(t, errBoom, w.Run())
+}
+
+func TestWorkerFromCloser(t *testing.T) {
+	c := &closerMock{closed: make(chan struct{})}
+	w := WorkerFromCloser(func() error { <-c.closed; return nil }, c)
+
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+	require.NoError(t, w.Terminate())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Worker has not stopped")
+	}
+}
+
+func TestWorkerFromStartStop(t *testing.T) {
+	var started, stopped bool
+	w := WorkerFromStartStop(
+		func() error { started = true; return nil },
+		func() error { stopped = true; return nil },
+	)
+
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, w.Terminate())
+	require.NoError(t, w.Terminate())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Worker has not stopped")
+	}
+	assert.True(t, started)
+	assert.True(t, stopped)
+}
+
+type ctxWorkerMock struct {
+	run        func(ctx context.Context) error
+	terminated bool
+}
+
+func (w *ctxWorkerMock) Init(*zap.Logger) error        { return nil }
+func (w *ctxWorkerMock) Run(ctx context.Context) error { return w.run(ctx) }
+func (w *ctxWorkerMock) Terminate() error              { w.terminated = true; return nil }
+func (w *ctxWorkerMock) Healthy() error                { return errors.New("dummy error") }
+
+func TestAddWorkerCtx(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	canceled := &ctxWorkerMock{run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	s.AddWorkerCtx("canceled-worker", canceled)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { s.Shutdown(); return nil },
+		TerminateFunc: func() error { return nil },
+	})
+
+	res, checked := s.checkHealth("canceled-worker", s.workers["canceled-worker"])
+	require.True(t, checked)
+	assert.Equal(t, HealthCritical, res.Status)
+
+	require.NoError(t, s.RunE())
+	assert.True(t, canceled.terminated)
+	assert.Empty(t, s.self.runningWorkers())
+}
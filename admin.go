@@ -0,0 +1,120 @@
+package svc
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Admin exposes the service's life-cycle as a stable, typed API for platform
+// tooling, as an alternative to scraping the debug HTTP routes. It is meant to
+// back an internal admin service, e.g. a gRPC service whose handlers delegate
+// to it, served with mutual TLS. The service itself does not depend on gRPC.
+type Admin struct {
+	s *SVC
+}
+
+// Admin returns the service's admin API.
+func (s *SVC) Admin() *Admin {
+	return &Admin{s: s}
+}
+
+// WorkerStatus defines the status of a worker, as reported by the admin API.
+type WorkerStatus struct {
+	Name    string
+	Running bool
+	// Checked reports whether the worker has a health check, in which case
+	// Health holds its result.
+	Checked      bool
+	Health       HealthResult
+	FailingSince time.Time
+	// Described reports whether the worker implements Describer or has
+	// declared dependencies, in which case Info holds them.
+	Described bool
+	Info      WorkerInfo
+}
+
+// WorkerStatus returns the status of the workers, in the order they were
+// added, followed by the framework's own health.
+func (a *Admin) WorkerStatus() []WorkerStatus {
+	checks := map[string]readyCheck{}
+	for _, c := range a.s.readyChecks() {
+		checks[c.Worker] = c
+	}
+	running := map[string]bool{}
+	for _, name := range a.s.self.runningWorkers() {
+		running[name] = true
+	}
+
+	names := append(append([]string{}, a.s.workersAdded...), SelfHealthName)
+	statuses := make([]WorkerStatus, 0, len(names))
+	for _, name := range names {
+		st := WorkerStatus{Name: name, Running: running[name]}
+		st.Info, st.Described = a.s.describeWorker(name)
+		if c, ok := checks[name]; ok {
+			st.Checked = true
+			st.Health = c.HealthResult
+			st.FailingSince = c.FailingSince
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
+
+// LogLevel returns the current log level, e.g. "info".
+func (a *Admin) LogLevel() string {
+	return a.s.LogLevel().String()
+}
+
+// SetLogLevel sets the log level, e.g. "debug".
+func (a *Admin) SetLogLevel(level string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	a.s.SetLogLevel(l)
+	return nil
+}
+
+// Drain reports the service not ready and blocks for the termination wait
+// period, or until ctx is done, before starting the shutdown. See
+// WithPreStopHandler.
+func (a *Admin) Drain(ctx context.Context) {
+	a.s.drain(ctx)
+}
+
+// Shutdown starts the shutdown of the service. See SVC.Shutdown.
+func (a *Admin) Shutdown() {
+	a.s.Shutdown()
+}
+
+// Features returns the status of the degradable features. See SVC.Degrade.
+func (a *Admin) Features() []FeatureStatus {
+	return a.s.Features()
+}
+
+// SetFeatureMode forces a degradable feature degraded or normal, or back to
+// auto. See SVC.SetFeatureMode.
+func (a *Admin) SetFeatureMode(name, mode string) error {
+	return a.s.SetFeatureMode(name, mode)
+}
+
+// Route defines a route of the internal HTTP server and the option or caller
+// that registered it.
+type Route struct {
+	Pattern string
+	Owner   string
+}
+
+// ListRoutes returns the routes of the internal HTTP server, sorted by
+// pattern.
+func (a *Admin) ListRoutes() []Route {
+	routes := make([]Route, 0, len(a.s.routes))
+	for pattern, owner := range a.s.routes {
+		routes = append(routes, Route{Pattern: pattern, Owner: owner})
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
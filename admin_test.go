@@ -0,0 +1,44 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		HealthyFunc: func() error { return errors.New("dummy error") },
+	})
+	s.AddWorker("plain-worker", &healthCheckerMock{result: HealthResult{Status: HealthOK}})
+	admin := s.Admin()
+
+	statuses := admin.WorkerStatus()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "dummy-worker", statuses[0].Name)
+	assert.True(t, statuses[0].Checked)
+	assert.Equal(t, HealthCritical, statuses[0].Health.Status)
+	assert.Equal(t, "dummy error", statuses[0].Health.Detail)
+	assert.False(t, statuses[0].FailingSince.IsZero())
+	assert.Equal(t, "plain-worker", statuses[1].Name)
+	assert.Equal(t, HealthOK, statuses[1].Health.Status)
+	assert.Equal(t, SelfHealthName, statuses[2].Name)
+
+	require.NoError(t, admin.SetLogLevel("warn"))
+	assert.Equal(t, "warn", admin.LogLevel())
+	require.Error(t, admin.SetLogLevel("loud"))
+
+	routes := admin.ListRoutes()
+	require.NotEmpty(t, routes)
+	assert.Equal(t, Route{Pattern: "/healthz/workers", Owner: "WithHealthz"}, routes[0])
+
+	admin.Drain(context.Background())
+	assert.Equal(t, syscall.SIGTERM, <-s.signals)
+	assert.Equal(t, HealthCritical, s.self.CheckHealth().Status)
+}
@@ -0,0 +1,166 @@
+package svc
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// revisionLength is the length revisions are abbreviated to in versions.
+const revisionLength = 12
+
+// BuildInfo defines the build metadata of the service's binary, read from
+// debug.ReadBuildInfo.
+type BuildInfo struct {
+	// Module is the main module's path and ModuleVersion its version, set when
+	// built with `go install module@version`.
+	Module        string    `json:"module,omitempty"`
+	ModuleVersion string    `json:"module_version,omitempty"`
+	Revision      string    `json:"revision,omitempty"`
+	Time          time.Time `json:"time,omitempty"`
+	// Dirty reports whether the working tree had uncommitted changes.
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"go_version"`
+}
+
+// BuildInfo returns the build metadata of the service's binary.
+func (s *SVC) BuildInfo() BuildInfo {
+	return s.build
+}
+
+// WithInfoHandler is an option that serves the service's identity and build
+// metadata on the `/info` route, e.g. to check which version a replica runs.
+func WithInfoHandler() Option {
+	return func(s *SVC) error {
+		s.handle("WithInfoHandler", "/info", http.HandlerFunc(s.infoHandler))
+
+		return nil
+	}
+}
+
+func (s *SVC) infoHandler(w http.ResponseWriter, _ *http.Request) {
+	writeResponse(w, http.StatusOK, InfoResponse{
+		Name:          s.Name,
+		Version:       s.Version,
+		InstanceID:    s.instanceID,
+		Role:          s.role,
+		Build:         s.build,
+		StartedAt:     s.startedAt,
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+	})
+}
+
+// BuildInfoOption defines WithBuildInfoHandler's option type.
+type BuildInfoOption func(*buildInfoConfig)
+
+type buildInfoConfig struct {
+	authorize func(r *http.Request) error
+}
+
+// BuildInfoAuthorizer gates the route with authorize, e.g. checking an admin
+// token or the client certificate's SPIFFE ID. Requests it returns an error for
+// are rejected with 403 Forbidden.
+func BuildInfoAuthorizer(authorize func(r *http.Request) error) BuildInfoOption {
+	return func(c *buildInfoConfig) {
+		c.authorize = authorize
+	}
+}
+
+// WithBuildInfoHandler is an option that serves the binary's full build
+// metadata, as read by debug.ReadBuildInfo, on the `/debug/buildinfo` route:
+// the main module, every module dependency with its version and checksum, and
+// the build settings, e.g. for security tooling auditing running services.
+// As it discloses the dependencies, gate it with BuildInfoAuthorizer unless
+// the internal server is not exposed.
+func WithBuildInfoHandler(opts ...BuildInfoOption) Option {
+	return func(s *SVC) error {
+		cfg := &buildInfoConfig{}
+		for _, o := range opts {
+			o(cfg)
+		}
+		s.handle("WithBuildInfoHandler", "/debug/buildinfo", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.authorize != nil {
+				if err := cfg.authorize(r); err != nil {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+			}
+			info, ok := debug.ReadBuildInfo()
+			if !ok {
+				http.Error(w, "build info not available", http.StatusNotFound)
+				return
+			}
+			writeResponse(w, http.StatusOK, newBuildInfoResponse(info))
+		}))
+
+		return nil
+	}
+}
+
+func newBuildInfoResponse(info *debug.BuildInfo) BuildInfoResponse {
+	resp := BuildInfoResponse{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Main:      newModule(&info.Main),
+		Deps:      make([]Module, 0, len(info.Deps)),
+		Settings:  make([]BuildSetting, 0, len(info.Settings)),
+	}
+	for _, dep := range info.Deps {
+		resp.Deps = append(resp.Deps, newModule(dep))
+	}
+	for _, setting := range info.Settings {
+		resp.Settings = append(resp.Settings, BuildSetting{Key: setting.Key, Value: setting.Value})
+	}
+	return resp
+}
+
+func newModule(m *debug.Module) Module {
+	mod := Module{Path: m.Path, Version: m.Version, Sum: m.Sum}
+	if m.Replace != nil {
+		replace := newModule(m.Replace)
+		mod.Replace = &replace
+	}
+	return mod
+}
+
+func readBuildInfo() BuildInfo {
+	b := BuildInfo{GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.Module = info.Main.Path
+	if info.Main.Version != "(devel)" {
+		b.ModuleVersion = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			b.Revision = setting.Value
+		case "vcs.time":
+			b.Time, _ = time.Parse(time.RFC3339, setting.Value)
+		case "vcs.modified":
+			b.Dirty, _ = strconv.ParseBool(setting.Value)
+		}
+	}
+	return b
+}
+
+// version returns the version derived from the build metadata: the module's
+// version, or else the abbreviated revision, suffixed with "-dirty" if the
+// working tree was.
+func (b BuildInfo) version() string {
+	if b.ModuleVersion != "" {
+		return b.ModuleVersion
+	}
+	v := b.Revision
+	if len(v) > revisionLength {
+		v = v[:revisionLength]
+	}
+	if v != "" && b.Dirty {
+		v += "-dirty"
+	}
+	return v
+}
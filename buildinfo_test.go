@@ -0,0 +1,165 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfoVersion(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenBuildInfo  BuildInfo
+		expectedVersion string
+	}{
+		{
+			name:            "should return the module version",
+			givenBuildInfo:  BuildInfo{ModuleVersion: "v1.2.3", Revision: "0123456789abcdef"},
+			expectedVersion: "v1.2.3",
+		},
+		{
+			name:            "should return the abbreviated revision",
+			givenBuildInfo:  BuildInfo{Revision: "0123456789abcdef"},
+			expectedVersion: "0123456789ab",
+		},
+		{
+			name:            "should return the dirty revision",
+			givenBuildInfo:  BuildInfo{Revision: "0123456789abcdef", Dirty: true},
+			expectedVersion: "0123456789ab-dirty",
+		},
+		{
+			name:            "should return nothing without metadata",
+			givenBuildInfo:  BuildInfo{Dirty: true},
+			expectedVersion: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedVersion, tc.givenBuildInfo.version())
+		})
+	}
+}
+
+func TestNewWithoutVersion(t *testing.T) {
+	s, err := New("dummy-service", "")
+	require.NoError(t, err)
+	assert.Equal(t, runtime.Version(), s.BuildInfo().GoVersion)
+	assert.Equal(t, s.BuildInfo().version(), s.Version)
+
+	s, err = New("dummy-service", "v0.0.0", WithMetrics())
+	require.NoError(t, err)
+	assert.Equal(t, "v0.0.0", s.Version)
+	mfs, err := s.internalRegister.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, mf := range mfs {
+		names = append(names, mf.GetName())
+	}
+	assert.Contains(t, names, "svc_build_info")
+	assert.Contains(t, names, "svc_start_time_seconds")
+}
+
+func TestInfoHandler(t *testing.T) {
+	s, err := New("dummy-service", "v1.2.3", WithInfoHandler())
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/info", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp InfoResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "dummy-service", resp.Name)
+	assert.Equal(t, "v1.2.3", resp.Version)
+	assert.Equal(t, s.InstanceID(), resp.InstanceID)
+	assert.Equal(t, runtime.Version(), resp.Build.GoVersion)
+	assert.True(t, resp.StartedAt.Equal(s.startedAt))
+	assert.GreaterOrEqual(t, resp.UptimeSeconds, float64(0))
+}
+
+func TestBuildInfoHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []BuildInfoOption
+		header       string
+		expectedCode int
+	}{
+		{
+			name:         "no authorizer",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "authorized",
+			opts:         []BuildInfoOption{BuildInfoAuthorizer(requireAuditToken)},
+			header:       "Bearer dummy-token",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unauthorized",
+			opts:         []BuildInfoOption{BuildInfoAuthorizer(requireAuditToken)},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithBuildInfoHandler(tc.opts...))
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/debug/buildinfo", nil)
+			r.Header.Set("Authorization", tc.header)
+			s.Router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp BuildInfoResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, runtime.Version(), resp.GoVersion)
+			deps := map[string]string{}
+			for _, dep := range resp.Deps {
+				deps[dep.Path] = dep.Version
+			}
+			assert.Contains(t, deps, "go.uber.org/zap")
+		})
+	}
+}
+
+func TestNewBuildInfoResponse(t *testing.T) {
+	resp := newBuildInfoResponse(&debug.BuildInfo{
+		GoVersion: "go1.21.0",
+		Path:      "example.com/dummy",
+		Main:      debug.Module{Path: "example.com/dummy", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "example.com/dep", Version: "v1.0.0", Sum: "h1:dummy", Replace: &debug.Module{Path: "../dep", Version: ""}},
+		},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+	})
+	assert.Equal(t, BuildInfoResponse{
+		GoVersion: "go1.21.0",
+		Path:      "example.com/dummy",
+		Main:      Module{Path: "example.com/dummy", Version: "(devel)"},
+		Deps: []Module{
+			{Path: "example.com/dep", Version: "v1.0.0", Sum: "h1:dummy", Replace: &Module{Path: "../dep"}},
+		},
+		Settings: []BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+	}, resp)
+}
+
+func requireAuditToken(r *http.Request) error {
+	if r.Header.Get("Authorization") != "Bearer dummy-token" {
+		return errors.New("invalid token")
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+// Package cache provides an in-memory TTL/LRU cache managed as a svc worker.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/voi-oss/svc"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCapacity         = 10000
+	defaultEvictionInterval = time.Minute
+)
+
+var (
+	_ svc.Worker   = (*Cache)(nil)
+	_ svc.Gatherer = (*Cache)(nil)
+)
+
+// LoaderFunc loads the value of a key missing from the cache.
+type LoaderFunc func(ctx context.Context, key string) (interface{}, error)
+
+// FlushFunc is handed the live entries when the cache worker terminates.
+type FlushFunc func(entries map[string]interface{}) error
+
+// Option defines Cache's option type.
+type Option func(*Cache)
+
+// WithTTL sets how long entries live. Zero, the default, never expires entries.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithCapacity sets the maximum number of entries, evicting the least recently
+// used entries beyond it. Defaults to 10000.
+func WithCapacity(n int) Option {
+	return func(c *Cache) {
+		c.capacity = n
+	}
+}
+
+// WithEvictionInterval sets how often expired entries are evicted. Defaults to
+// a minute.
+func WithEvictionInterval(d time.Duration) Option {
+	return func(c *Cache) {
+		c.evictionInterval = d
+	}
+}
+
+// WithLoader sets the function GetOrLoad loads missing entries with.
+func WithLoader(fn LoaderFunc) Option {
+	return func(c *Cache) {
+		c.loader = fn
+	}
+}
+
+// WithFlush sets the function the live entries are handed to on termination,
+// e.g. to persist them.
+func WithFlush(fn FlushFunc) Option {
+	return func(c *Cache) {
+		c.flush = fn
+	}
+}
+
+// Cache defines a TTL/LRU cache. It is a svc.Worker evicting expired entries
+// periodically, and a svc.Gatherer exposing hit/miss metrics.
+type Cache struct {
+	ttl              time.Duration
+	capacity         int
+	evictionInterval time.Duration
+	loader           LoaderFunc
+	flush            FlushFunc
+
+	logger *zap.Logger
+	done   chan struct{}
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+
+	registry  *prometheus.Registry
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	evictions prometheus.Counter
+	entries   prometheus.Gauge
+}
+
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// New returns a named cache. The name labels the cache's metrics.
+func New(name string, opts ...Option) *Cache {
+	labels := prometheus.Labels{"cache": name}
+	c := &Cache{
+		capacity:         defaultCapacity,
+		evictionInterval: defaultEvictionInterval,
+		logger:           zap.NewNop(),
+		done:             make(chan struct{}),
+		items:            map[string]*list.Element{},
+		lru:              list.New(),
+		registry:         prometheus.NewRegistry(),
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_cache_hits_total", Help: "Number of cache hits.", ConstLabels: labels,
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_cache_misses_total", Help: "Number of cache misses.", ConstLabels: labels,
+		}),
+		evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_cache_evictions_total", Help: "Number of evicted cache entries.", ConstLabels: labels,
+		}),
+		entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "svc_cache_entries", Help: "Number of cache entries.", ConstLabels: labels,
+		}),
+	}
+	c.registry.MustRegister(c.hits, c.misses, c.evictions, c.entries)
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Init implements the svc.Worker interface.
+func (c *Cache) Init(logger *zap.Logger) error {
+	c.logger = logger
+
+	return nil
+}
+
+// Run implements the svc.Worker interface.
+func (c *Cache) Run() error {
+	ticker := time.NewTicker(c.evictionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n := c.EvictExpired(); n > 0 {
+				c.logger.Debug("Evicted expired entries", zap.Int("entries", n))
+			}
+		case <-c.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the svc.Worker interface. It hands the live entries to
+// the flush function, if any.
+func (c *Cache) Terminate() error {
+	close(c.done)
+	if c.flush == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	entries := make(map[string]interface{}, len(c.items))
+	for key, el := range c.items {
+		if e := el.Value.(*entry); !c.expired(e, now) {
+			entries[key] = e.value
+		}
+	}
+	c.mu.Unlock()
+
+	return c.flush(entries)
+}
+
+// Gatherer implements the svc.Gatherer interface.
+func (c *Cache) Gatherer() prometheus.Gatherer {
+	return c.registry
+}
+
+// Get returns the value of the key, if cached and not expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || c.expired(el.Value.(*entry), time.Now()) {
+		c.misses.Inc()
+		return nil, false
+	}
+	c.hits.Inc()
+	c.lru.MoveToFront(el)
+	return el.Value.(*entry).value, true
+}
+
+// GetOrLoad returns the value of the key, loading and caching it with the
+// loader set with WithLoader if missing.
+func (c *Cache) GetOrLoad(ctx context.Context, key string) (interface{}, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	if c.loader == nil {
+		return nil, ErrNoLoader
+	}
+	v, err := c.loader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(key, v)
+	return v, nil
+}
+
+// Set caches the value of the key.
+func (c *Cache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expiresAt = value, expiresAt
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	for c.capacity > 0 && c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back())
+		c.evictions.Inc()
+	}
+	c.entries.Set(float64(c.lru.Len()))
+}
+
+// Delete removes the key from the cache.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+		c.entries.Set(float64(c.lru.Len()))
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not evicted
+// yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// EvictExpired evicts the expired entries and returns how many were evicted.
+func (c *Cache) EvictExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if c.expired(el.Value.(*entry), now) {
+			c.remove(el)
+			n++
+		}
+		el = prev
+	}
+	c.evictions.Add(float64(n))
+	c.entries.Set(float64(c.lru.Len()))
+	return n
+}
+
+func (c *Cache) expired(e *entry, now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := New("test", WithCapacity(2))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", 3)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.hits))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.misses))
+	assert.Equal(t, float64(1), testutil.ToFloat64(c.evictions))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.entries))
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := New("test", WithTTL(10*time.Millisecond))
+	c.Set("a", 1)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, 1, c.EvictExpired())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	_, err := New("test").GetOrLoad(context.Background(), "a")
+	require.ErrorIs(t, err, ErrNoLoader)
+
+	loads := 0
+	c := New("test", WithLoader(func(_ context.Context, key string) (interface{}, error) {
+		loads++
+		if key == "bad" {
+			return nil, errors.New("dummy error")
+		}
+		return key + "-value", nil
+	}))
+	for i := 0; i < 2; i++ {
+		v, err := c.GetOrLoad(context.Background(), "a")
+		require.NoError(t, err)
+		assert.Equal(t, "a-value", v)
+	}
+	_, err = c.GetOrLoad(context.Background(), "bad")
+	require.EqualError(t, err, "dummy error")
+	assert.Equal(t, 2, loads)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCacheWorker(t *testing.T) {
+	var flushed map[string]interface{}
+	c := New("test",
+		WithTTL(time.Hour),
+		WithEvictionInterval(time.Millisecond),
+		WithFlush(func(entries map[string]interface{}) error {
+			flushed = entries
+			return nil
+		}),
+	)
+	require.NoError(t, c.Init(zap.NewNop()))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Delete("b")
+
+	done := make(chan error)
+	go func() { done <- c.Run() }()
+	require.NoError(t, c.Terminate())
+	require.NoError(t, <-done)
+	assert.Equal(t, map[string]interface{}{"a": 1}, flushed)
+}
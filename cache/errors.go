@@ -0,0 +1,6 @@
+package cache
+
+import "errors"
+
+// ErrNoLoader is returned by GetOrLoad if no loader was set.
+var ErrNoLoader = errors.New("cache: no loader")
@@ -0,0 +1,201 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCallerMaxAttempts       = 3
+	defaultCallerRetryBudgetTokens = 10
+	defaultCallerRetryBudgetRatio  = 0.1
+)
+
+// Call attempt kinds, as labeled in the svc_client_attempts_total metric.
+const (
+	CallAttemptFirst = "first"
+	CallAttemptRetry = "retry"
+	CallAttemptHedge = "hedge"
+)
+
+// CallerOption defines NewCaller's option type.
+type CallerOption func(*callerConfig)
+
+type callerConfig struct {
+	maxAttempts   int
+	perTryTimeout time.Duration
+	hedgeDelay    time.Duration
+	budgetTokens  float64
+	budgetRatio   float64
+	retryIf       func(error) bool
+}
+
+// CallerMaxAttempts caps the number of attempts per call, including retries
+// and hedges. Defaults to 3.
+func CallerMaxAttempts(n int) CallerOption {
+	return func(c *callerConfig) {
+		c.maxAttempts = n
+	}
+}
+
+// CallerPerTryTimeout bounds each attempt, on top of the call's context.
+// Defaults to none.
+func CallerPerTryTimeout(d time.Duration) CallerOption {
+	return func(c *callerConfig) {
+		c.perTryTimeout = d
+	}
+}
+
+// CallerHedgeDelay enables hedging: while no attempt succeeded after d,
+// another one is started concurrently, and the first success wins. Only use it
+// for idempotent calls. Defaults to no hedging.
+func CallerHedgeDelay(d time.Duration) CallerOption {
+	return func(c *callerConfig) {
+		c.hedgeDelay = d
+	}
+}
+
+// CallerRetryBudget limits retries and hedges to those of a token bucket
+// holding up to tokens: each failure takes a token, each success gives back
+// ratio of one, and retries stop while the bucket is less than half full, so a
+// failing backend is not overloaded. Defaults to 10 tokens and a ratio of 0.1.
+func CallerRetryBudget(tokens int, ratio float64) CallerOption {
+	return func(c *callerConfig) {
+		c.budgetTokens = float64(tokens)
+		c.budgetRatio = ratio
+	}
+}
+
+// CallerRetryIf sets which errors are retried. Defaults to all of them.
+func CallerRetryIf(fn func(error) bool) CallerOption {
+	return func(c *callerConfig) {
+		c.retryIf = fn
+	}
+}
+
+// Caller makes calls to a backend, e.g. through a client returned by
+// GRPCClient, with per-try timeouts, budgeted retries and hedging for
+// latency-sensitive read paths. Attempts are counted in the
+// svc_client_attempts_total metric, and hedges succeeding first in
+// svc_client_hedge_wins_total.
+type Caller[T any] struct {
+	s    *SVC
+	name string
+	cfg  callerConfig
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewCaller returns a named Caller, e.g. named after the backend.
+func NewCaller[T any](s *SVC, name string, opts ...CallerOption) *Caller[T] {
+	cfg := callerConfig{
+		maxAttempts:  defaultCallerMaxAttempts,
+		budgetTokens: defaultCallerRetryBudgetTokens,
+		budgetRatio:  defaultCallerRetryBudgetRatio,
+		retryIf:      func(error) bool { return true },
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.maxAttempts < 1 {
+		cfg.maxAttempts = 1
+	}
+	return &Caller[T]{s: s, name: name, cfg: cfg, tokens: cfg.budgetTokens}
+}
+
+type callResult[T any] struct {
+	value T
+	err   error
+	kind  string
+}
+
+// Do calls fn until an attempt succeeds, the attempts or the retry budget are
+// exhausted, or ctx is done, and returns the first successful result or the
+// last error. Attempts still in flight are canceled once Do returns.
+func (c *Caller[T]) Do(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan callResult[T], c.cfg.maxAttempts)
+	attempts, inFlight := 0, 0
+	attempt := func(kind string) {
+		attempts++
+		inFlight++
+		c.s.metrics.clientAttempts.WithLabelValues(c.name, kind).Inc()
+		go func() {
+			actx := ctx
+			if c.cfg.perTryTimeout > 0 {
+				var cancel context.CancelFunc
+				actx, cancel = context.WithTimeout(ctx, c.cfg.perTryTimeout)
+				defer cancel()
+			}
+			v, err := fn(actx)
+			results <- callResult[T]{value: v, err: err, kind: kind}
+		}()
+	}
+
+	var hedge <-chan time.Time
+	if c.cfg.hedgeDelay > 0 {
+		t := time.NewTicker(c.cfg.hedgeDelay)
+		defer t.Stop()
+		hedge = t.C
+	}
+
+	attempt(CallAttemptFirst)
+	for {
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				c.succeeded()
+				if r.kind == CallAttemptHedge {
+					c.s.metrics.clientHedgeWins.WithLabelValues(c.name).Inc()
+				}
+				return r.value, nil
+			}
+			c.failed()
+			if ctx.Err() == nil && c.cfg.retryIf(r.err) && attempts < c.cfg.maxAttempts && c.allowed() {
+				attempt(CallAttemptRetry)
+			} else if inFlight == 0 {
+				return zero, r.err
+			}
+		case <-hedge:
+			if attempts < c.cfg.maxAttempts && c.allowed() {
+				attempt(CallAttemptHedge)
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// allowed reports whether the retry budget allows another attempt.
+func (c *Caller[T]) allowed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tokens > c.cfg.budgetTokens/2
+}
+
+func (c *Caller[T]) succeeded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens += c.cfg.budgetRatio
+	if c.tokens > c.cfg.budgetTokens {
+		c.tokens = c.cfg.budgetTokens
+	}
+}
+
+func (c *Caller[T]) failed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens--
+	if c.tokens < 0 {
+		c.tokens = 0
+	}
+}
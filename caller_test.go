@@ -0,0 +1,107 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaller(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	tests := []struct {
+		name      string
+		opts      []CallerOption
+		call      func(ctx context.Context, attempt int32) (string, error)
+		want      string
+		wantErr   error
+		attempts  map[string]float64
+		hedgeWins float64
+	}{
+		{
+			name: "first attempt succeeds",
+			call: func(context.Context, int32) (string, error) { return "ok", nil },
+			want: "ok", attempts: map[string]float64{CallAttemptFirst: 1},
+		},
+		{
+			name: "retries failures",
+			call: func(_ context.Context, attempt int32) (string, error) {
+				if attempt < 3 {
+					return "", errUnavailable
+				}
+				return "ok", nil
+			},
+			want: "ok", attempts: map[string]float64{CallAttemptFirst: 1, CallAttemptRetry: 2},
+		},
+		{
+			name:    "exhausts attempts",
+			opts:    []CallerOption{CallerMaxAttempts(2)},
+			call:    func(context.Context, int32) (string, error) { return "", errUnavailable },
+			wantErr: errUnavailable, attempts: map[string]float64{CallAttemptFirst: 1, CallAttemptRetry: 1},
+		},
+		{
+			name:    "exhausts retry budget",
+			opts:    []CallerOption{CallerRetryBudget(2, 0.1)},
+			call:    func(context.Context, int32) (string, error) { return "", errUnavailable },
+			wantErr: errUnavailable, attempts: map[string]float64{CallAttemptFirst: 1},
+		},
+		{
+			name:    "retries only retryable errors",
+			opts:    []CallerOption{CallerRetryIf(func(err error) bool { return !errors.Is(err, errUnavailable) })},
+			call:    func(context.Context, int32) (string, error) { return "", errUnavailable },
+			wantErr: errUnavailable, attempts: map[string]float64{CallAttemptFirst: 1},
+		},
+		{
+			name: "per-try timeout",
+			opts: []CallerOption{CallerPerTryTimeout(10 * time.Millisecond)},
+			call: func(ctx context.Context, attempt int32) (string, error) {
+				if attempt == 1 {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}
+				return "ok", nil
+			},
+			want: "ok", attempts: map[string]float64{CallAttemptFirst: 1, CallAttemptRetry: 1},
+		},
+		{
+			name: "hedge wins",
+			opts: []CallerOption{CallerHedgeDelay(10 * time.Millisecond)},
+			call: func(ctx context.Context, attempt int32) (string, error) {
+				if attempt == 1 {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}
+				return "hedged", nil
+			},
+			want: "hedged", attempts: map[string]float64{CallAttemptFirst: 1, CallAttemptHedge: 1}, hedgeWins: 1,
+		},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0")
+			require.NoError(t, err)
+			c := NewCaller[string](s, "users", tc.opts...)
+
+			var attempts int32
+			got, err := c.Do(context.Background(), func(ctx context.Context) (string, error) {
+				return tc.call(ctx, atomic.AddInt32(&attempts, 1))
+			})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, got)
+			for _, kind := range []string{CallAttemptFirst, CallAttemptRetry, CallAttemptHedge} {
+				assert.Equal(t, tc.attempts[kind], testutil.ToFloat64(s.metrics.clientAttempts.WithLabelValues("users", kind)), kind)
+			}
+			assert.Equal(t, tc.hedgeWins, testutil.ToFloat64(s.metrics.clientHedgeWins.WithLabelValues("users")))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package svc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupCPUQuota returns the number of CPUs the process' cgroup is limited to,
+// supporting both cgroup v2 and v1. It reports false if there is no limit.
+func cgroupCPUQuota() (float64, bool) {
+	// cgroup v2: "<quota> <period>" or "max <period>"
+	if b, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return cpuQuota(fields[0], fields[1])
+	}
+
+	// cgroup v1: a quota of -1 means no limit
+	quota, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return cpuQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func cpuQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+// cgroupMemoryLimit returns the memory limit in bytes of the process' cgroup,
+// supporting both cgroup v2 and v1. It reports false if there is no limit.
+func cgroupMemoryLimit() (int64, bool) {
+	b, err := os.ReadFile(filepath.Join(cgroupRoot, "memory.max"))
+	if err != nil {
+		b, err = os.ReadFile(filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"))
+		if err != nil {
+			return 0, false
+		}
+	}
+	limit, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	// cgroup v1 reports no limit as a huge page-aligned number.
+	if err != nil || limit <= 0 || limit >= 1<<62 {
+		return 0, false
+	}
+	return limit, true
+}
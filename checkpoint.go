@@ -0,0 +1,131 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// ErrNoCheckpoint is returned by CheckpointStore.Load if no checkpoint was saved
+// for the key yet.
+var ErrNoCheckpoint = errors.New("no checkpoint")
+
+// CheckpointStore defines a store for worker checkpoints, e.g. a file system,
+// an object store, or a database.
+type CheckpointStore interface {
+	Load(ctx context.Context, key string) ([]byte, error)
+	Save(ctx context.Context, key string, data []byte) error
+}
+
+// Checkpointer defines a worker whose progress (offsets, cursors) is saved on
+// termination and restored on initialization, given a CheckpointStore is set
+// with WithCheckpointStore.
+type Checkpointer interface {
+	// Checkpoint returns the worker's progress. It is called after the worker
+	// terminated successfully.
+	Checkpoint() ([]byte, error)
+	// Restore restores the worker's progress. It is called after the worker
+	// was initialized, only if a checkpoint was saved before.
+	Restore(data []byte) error
+}
+
+// WithCheckpointStore is an option that sets the store the checkpoints of
+// workers implementing Checkpointer are saved to and loaded from, keyed by
+// worker name.
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(s *SVC) error {
+		s.checkpoints = store
+
+		return nil
+	}
+}
+
+// restoreCheckpoint restores the worker's checkpoint, if any.
+func (s *SVC) restoreCheckpoint(name string, w Worker) error {
+	c, ok := unwrapWorker(w).(Checkpointer)
+	if !ok || s.checkpoints == nil {
+		return nil
+	}
+	data, err := s.checkpoints.Load(context.Background(), name)
+	if errors.Is(err, ErrNoCheckpoint) {
+		s.logger.Info("No checkpoint to restore", zap.String("worker", name))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Restoring checkpoint", zap.String("worker", name))
+	return c.Restore(data)
+}
+
+// saveCheckpoint saves the worker's checkpoint, if it implements Checkpointer.
+func (s *SVC) saveCheckpoint(ctx context.Context, name string, w Worker) {
+	c, ok := unwrapWorker(w).(Checkpointer)
+	if !ok || s.checkpoints == nil {
+		return
+	}
+	data, err := c.Checkpoint()
+	if err == nil {
+		err = s.checkpoints.Save(ctx, name, data)
+	}
+	if err != nil {
+		s.logger.Error("Could not save checkpoint", zap.String("worker", name), zap.Error(err))
+		return
+	}
+	s.logger.Info("Checkpoint saved", zap.String("worker", name))
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// FileCheckpointStore stores checkpoints as files in a directory.
+type FileCheckpointStore struct {
+	dir string
+}
+
+// NewFileCheckpointStore returns a store keeping checkpoints in the given
+// directory, creating it if needed.
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+// Load implements the CheckpointStore interface.
+func (f *FileCheckpointStore) Load(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoCheckpoint
+	}
+	return data, err
+}
+
+// Save implements the CheckpointStore interface. The checkpoint is written
+// atomically.
+func (f *FileCheckpointStore) Save(_ context.Context, key string, data []byte) error {
+	tmp, err := os.CreateTemp(f.dir, filepath.Base(key)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(key))
+}
+
+func (f *FileCheckpointStore) path(key string) string {
+	return filepath.Join(f.dir, filepath.Base(key)+".checkpoint")
+}
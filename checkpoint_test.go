@@ -0,0 +1,66 @@
+package svc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type checkpointerMock struct {
+	WorkerMock
+	offset []byte
+}
+
+func (w *checkpointerMock) Checkpoint() ([]byte, error) {
+	return w.offset, nil
+}
+
+func (w *checkpointerMock) Restore(data []byte) error {
+	w.offset = data
+	return nil
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = store.Load(context.Background(), "backfill")
+	require.ErrorIs(t, err, ErrNoCheckpoint)
+
+	require.NoError(t, store.Save(context.Background(), "backfill", []byte("42")))
+	require.NoError(t, store.Save(context.Background(), "backfill", []byte("43")))
+	data, err := store.Load(context.Background(), "backfill")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("43"), data)
+}
+
+func TestCheckpoints(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+
+	run := func(progress string) []byte {
+		s, err := New("dummy-service", "v0.0.0", WithCheckpointStore(store))
+		require.NoError(t, err)
+
+		var restored []byte
+		w := &checkpointerMock{}
+		w.InitFunc = func(*zap.Logger) error { return nil }
+		w.RunFunc = func() error {
+			restored = w.offset
+			w.offset = []byte(progress)
+			return nil
+		}
+		w.TerminateFunc = func() error { return nil }
+		s.AddWorker("backfill", w)
+		s.Run()
+
+		return restored
+	}
+
+	assert.Nil(t, run("100"))
+	assert.Equal(t, []byte("100"), run("200"))
+	assert.Equal(t, []byte("200"), run("300"))
+}
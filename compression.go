@@ -0,0 +1,259 @@
+package svc
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultCompressionMinSize = 1024
+
+var defaultCompressionContentTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/css",
+	"text/csv",
+	"text/html",
+	"text/javascript",
+	"text/plain",
+	"text/xml",
+}
+
+// EncoderFunc returns a writer compressing to w at the given level.
+type EncoderFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+// CompressionOption defines WithHTTPCompression's option type.
+type CompressionOption func(*compressionConfig)
+
+type compressionConfig struct {
+	encodings    []string
+	encoders     map[string]EncoderFunc
+	levels       map[string]int
+	contentTypes map[string]bool
+	minSize      int
+}
+
+// CompressionEncoder registers an encoder for the given content-coding, e.g.
+// "br" or "zstd". Registered encoders are preferred over the ones registered
+// before and the built-in gzip and deflate encoders. The level passed to the
+// encoder is set with CompressionLevel, and 0 otherwise.
+func CompressionEncoder(encoding string, fn EncoderFunc) CompressionOption {
+	return func(c *compressionConfig) {
+		for i, e := range c.encodings {
+			if e == encoding {
+				c.encodings = append(c.encodings[:i], c.encodings[i+1:]...)
+				break
+			}
+		}
+		c.encodings = append([]string{encoding}, c.encodings...)
+		c.encoders[encoding] = fn
+	}
+}
+
+// CompressionLevel sets the compression level of the given content-coding.
+func CompressionLevel(encoding string, level int) CompressionOption {
+	return func(c *compressionConfig) {
+		c.levels[encoding] = level
+	}
+}
+
+// CompressionContentTypes sets the media types, without parameters, that are
+// compressed.
+func CompressionContentTypes(types ...string) CompressionOption {
+	return func(c *compressionConfig) {
+		c.contentTypes = map[string]bool{}
+		for _, t := range types {
+			c.contentTypes[t] = true
+		}
+	}
+}
+
+// CompressionMinSize sets the response size in bytes below which responses are
+// not compressed. Defaults to 1024.
+func CompressionMinSize(n int) CompressionOption {
+	return func(c *compressionConfig) {
+		c.minSize = n
+	}
+}
+
+// WithHTTPCompression is an option that compresses the responses of the
+// internal HTTP server with the content-coding accepted by the client. gzip and
+// deflate are supported out of the box, see CompressionEncoder for others.
+func WithHTTPCompression(opts ...CompressionOption) Option {
+	return func(s *SVC) error {
+		cfg := &compressionConfig{
+			encodings: []string{"gzip", "deflate"},
+			encoders: map[string]EncoderFunc{
+				"gzip": func(w io.Writer, level int) (io.WriteCloser, error) {
+					return gzip.NewWriterLevel(w, level)
+				},
+				"deflate": func(w io.Writer, level int) (io.WriteCloser, error) {
+					return flate.NewWriter(w, level)
+				},
+			},
+			levels:  map[string]int{"gzip": gzip.DefaultCompression, "deflate": flate.DefaultCompression},
+			minSize: defaultCompressionMinSize,
+		}
+		CompressionContentTypes(defaultCompressionContentTypes...)(cfg)
+		for _, o := range opts {
+			o(cfg)
+		}
+		s.middlewares = append(s.middlewares, cfg.middleware)
+
+		return nil
+	}
+}
+
+func (c *compressionConfig) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, cfg: c, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the preferred content-coding accepted by the client.
+func (c *compressionConfig) negotiate(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(f, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, e := range c.encodings {
+		if accepted[e] {
+			return e
+		}
+	}
+	return ""
+}
+
+// compressResponseWriter buffers the response until its size reaches the
+// minimum size, then decides whether to compress it.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	cfg      *compressionConfig
+	encoding string
+
+	code    int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.cfg.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements the http.Flusher interface.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.cfg.minSize)
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.decided = true
+	return h.Hijack()
+}
+
+func (w *compressResponseWriter) decide(bigEnough bool) error {
+	w.decided = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+
+	if bigEnough && w.cfg.contentTypes[mediaType] && h.Get("Content-Encoding") == "" &&
+		w.code != http.StatusNoContent && w.code != http.StatusNotModified {
+		enc, err := w.cfg.encoders[w.encoding](w.ResponseWriter, w.cfg.levels[w.encoding])
+		if err == nil {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			w.encoder = enc
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *compressResponseWriter) close() {
+	if !w.decided {
+		if w.code == 0 && len(w.buf) == 0 {
+			return
+		}
+		_ = w.decide(false)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+	}
+}
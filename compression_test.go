@@ -0,0 +1,118 @@
+package svc
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPCompression(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("a", 2048) + `"}`
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		contentType      string
+		body             string
+		expectedEncoding string
+	}{
+		{
+			name:             "should compress large JSON responses",
+			acceptEncoding:   "deflate;q=0.5, gzip",
+			contentType:      "application/json; charset=utf-8",
+			body:             large,
+			expectedEncoding: "gzip",
+		},
+		{
+			name:             "should not compress small responses",
+			acceptEncoding:   "gzip",
+			contentType:      "application/json",
+			body:             `{}`,
+			expectedEncoding: "",
+		},
+		{
+			name:             "should not compress other content types",
+			acceptEncoding:   "gzip",
+			contentType:      "application/octet-stream",
+			body:             large,
+			expectedEncoding: "",
+		},
+		{
+			name:             "should not compress without accepted encoding",
+			acceptEncoding:   "gzip;q=0, br",
+			contentType:      "application/json",
+			body:             large,
+			expectedEncoding: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithHTTPCompression())
+			require.NoError(t, err)
+			s.HandleFunc("/data", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.WriteHeader(http.StatusCreated)
+				for _, chunk := range strings.SplitAfter(tc.body, ",") {
+					_, _ = w.Write([]byte(chunk))
+				}
+			})
+
+			req := httptest.NewRequest("GET", "/data", nil)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			rec := httptest.NewRecorder()
+			s.serveHTTP(rec, req)
+
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, tc.expectedEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+			var body io.Reader = rec.Body
+			if tc.expectedEncoding == "gzip" {
+				body, err = gzip.NewReader(rec.Body)
+				require.NoError(t, err)
+			}
+			b, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.body, string(b))
+		})
+	}
+}
+
+func TestHTTPCompressionEncoder(t *testing.T) {
+	var level int
+	s, err := New("dummy-service", "v0.0.0", WithHTTPCompression(
+		CompressionMinSize(0),
+		CompressionContentTypes("text/plain"),
+		CompressionLevel("identity-test", 7),
+		CompressionEncoder("identity-test", func(w io.Writer, l int) (io.WriteCloser, error) {
+			level = l
+			return nopWriteCloser{w}, nil
+		}),
+	))
+	require.NoError(t, err)
+	s.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, identity-test")
+	rec := httptest.NewRecorder()
+	s.serveHTTP(rec, req)
+
+	assert.Equal(t, "identity-test", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Equal(t, 7, level)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -1,7 +1,12 @@
 package svc
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/go-playground/validator/v10"
@@ -14,12 +19,166 @@ func LoadFromEnv(config interface{}) error {
 
 // LoadFromEnvWithParsers parses environment variables into a given struct and validates
 // its fields' values, also allows for custom type parsers
+//
+// Fields are bound with the `env` tag, and support `envDefault` defaults,
+// `env:"NAME,required"`, durations, URLs, and ByteSize values, and are
+// validated with the `validate` tag, e.g. `validate:"required,url"`. All
+// invalid fields are reported at once in a *ConfigError.
 func LoadFromEnvWithParsers(config interface{}, parsers map[reflect.Type]env.ParserFunc) error {
-	if err := env.ParseWithFuncs(config, parsers); err != nil {
-		return err
+	return loadFromEnv(config, parsers)
+}
+
+// loadFromEnv loads the configuration from the environment given in the
+// options, if any, or the process's.
+func loadFromEnv(config interface{}, parsers map[reflect.Type]env.ParserFunc, opts ...env.Options) error {
+	ref := reflect.ValueOf(config)
+	if ref.Kind() != reflect.Ptr || ref.IsNil() || ref.Elem().Kind() != reflect.Struct {
+		return env.ErrNotAStructPtr
 	}
+
+	cerr := &ConfigError{}
+	parseEnvFields(ref.Elem(), "", parsers, cerr, opts)
+
 	if err := validator.New().Struct(config); err != nil {
-		return err
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return err
+		}
+		unparsed := map[string]bool{}
+		for _, f := range cerr.Fields {
+			unparsed[f.Path] = true
+		}
+		root := ref.Elem().Type().Name()
+		for _, fe := range verrs {
+			path := strings.TrimPrefix(fe.StructNamespace(), root+".")
+			if unparsed[path] {
+				continue
+			}
+			rule := fe.Tag()
+			if fe.Param() != "" {
+				rule += "=" + fe.Param()
+			}
+			cerr.Fields = append(cerr.Fields, FieldError{Path: path, Err: fmt.Errorf("failed on the '%s' validation", rule)})
+		}
+	}
+
+	if len(cerr.Fields) > 0 {
+		return cerr
+	}
+	return nil
+}
+
+// parseEnvFields parses the struct's fields one by one to collect the errors of
+// all fields rather than only the first one.
+func parseEnvFields(v reflect.Value, path string, parsers map[reflect.Type]env.ParserFunc, cerr *ConfigError, opts []env.Options) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		fieldPath := sf.Name
+		if path != "" {
+			fieldPath = path + "." + sf.Name
+		}
+
+		if _, tagged := sf.Tag.Lookup("env"); !tagged {
+			switch {
+			case fv.Kind() == reflect.Struct:
+				parseEnvFields(fv, fieldPath, parsers, cerr, opts)
+			case fv.Kind() == reflect.Ptr && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct:
+				parseEnvFields(fv.Elem(), fieldPath, parsers, cerr, opts)
+			}
+			continue
+		}
+
+		single := reflect.New(reflect.StructOf([]reflect.StructField{{Name: sf.Name, Type: sf.Type, Tag: sf.Tag}}))
+		single.Elem().Field(0).Set(fv)
+		if err := env.ParseWithFuncs(single.Interface(), parsers, opts...); err != nil {
+			cerr.Fields = append(cerr.Fields, FieldError{Path: fieldPath, Err: err})
+			continue
+		}
+		fv.Set(single.Elem().Field(0))
+	}
+}
+
+// FieldError defines the error of a configuration field, labelled by its path,
+// e.g. "HTTP.Timeout".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the field's error.
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError aggregates the errors of all invalid configuration fields.
+type ConfigError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the fields' errors.
+func (e *ConfigError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// ByteSize defines a configuration value in bytes, parsed from e.g. "512",
+// "64KB" (decimal), or "1.5GiB" (binary).
+type ByteSize int64
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"K":   1e3,
+	"KB":  1e3,
+	"KIB": 1 << 10,
+	"M":   1e6,
+	"MB":  1e6,
+	"MIB": 1 << 20,
+	"G":   1e9,
+	"GB":  1e9,
+	"GIB": 1 << 30,
+	"T":   1e12,
+	"TB":  1e12,
+	"TIB": 1 << 40,
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte size %q", s)
+	}
+	unit, ok := byteSizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return fmt.Errorf("invalid byte size unit in %q", s)
 	}
+	*b = ByteSize(n * unit)
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/stretchr/testify/require"
@@ -28,7 +29,7 @@ func TestLoadFromEnv(t *testing.T) {
 
 	err = LoadFromEnv(&test)
 	require.Error(t, err)
-	require.Equal(t, "Key: 'EmptyRequiredVal' Error:Field validation for 'EmptyRequiredVal' failed on the 'required' tag", err.Error())
+	require.Equal(t, "invalid configuration: EmptyRequiredVal: failed on the 'required' validation", err.Error())
 	require.Equal(t, "testStrVal", test.StrVal)
 	require.Equal(t, 123, test.IntVal)
 }
@@ -49,3 +50,65 @@ func TestLoadFromEnvWithParsers(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, map[string]string{"testKey": "testVal"}, test.MapVal)
 }
+
+func TestLoadFromEnvAggregatedErrors(t *testing.T) {
+	type httpConfig struct {
+		Timeout time.Duration `env:"TEST_HTTP_TIMEOUT"`
+		BaseURL string        `env:"TEST_HTTP_BASE_URL" validate:"required,url"`
+	}
+	type config struct {
+		HTTP     httpConfig
+		Port     int      `env:"TEST_PORT" envDefault:"8080"`
+		Token    string   `env:"TEST_TOKEN,required"`
+		MaxBody  ByteSize `env:"TEST_MAX_BODY" envDefault:"1.5KiB"`
+		Replicas int      `env:"TEST_REPLICAS" validate:"min=1"`
+	}
+	t.Setenv("TEST_HTTP_TIMEOUT", "soon")
+	t.Setenv("TEST_HTTP_BASE_URL", "not a url")
+	t.Setenv("TEST_REPLICAS", "0")
+
+	var cfg config
+	err := LoadFromEnv(&cfg)
+	var cerr *ConfigError
+	require.ErrorAs(t, err, &cerr)
+	paths := []string{}
+	for _, f := range cerr.Fields {
+		paths = append(paths, f.Path)
+	}
+	require.Equal(t, []string{"HTTP.Timeout", "Token", "HTTP.BaseURL", "Replicas"}, paths)
+	require.Contains(t, err.Error(), `Token: env: required environment variable "TEST_TOKEN" is not set`)
+	require.Contains(t, err.Error(), "HTTP.BaseURL: failed on the 'url' validation")
+	require.Contains(t, err.Error(), "Replicas: failed on the 'min=1' validation")
+	require.Equal(t, 8080, cfg.Port)
+	require.Equal(t, ByteSize(1536), cfg.MaxBody)
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      ByteSize
+		expectedError string
+	}{
+		{value: "512", expected: 512},
+		{value: "64KB", expected: 64000},
+		{value: "64 kib", expected: 65536},
+		{value: "1.5GiB", expected: 3 << 29},
+		{value: "2TB", expected: 2e12},
+		{value: "MB", expectedError: `invalid byte size "MB"`},
+		{value: "10 parsecs", expectedError: `invalid byte size unit in "10 parsecs"`},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.value, func(t *testing.T) {
+			var b ByteSize
+			err := b.UnmarshalText([]byte(tc.value))
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, b)
+		})
+	}
+}
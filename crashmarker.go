@@ -0,0 +1,255 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCrashMarkerWindow    = 10 * time.Minute
+	defaultCrashMarkerThreshold = 3
+	anomalyCrashLoop            = "crash_loop"
+)
+
+// CrashMarkerOption defines WithCrashMarker's option type.
+type CrashMarkerOption func(*crashMarker)
+
+// CrashMarkerWindow sets how far back crashes are counted. Defaults to 10m.
+func CrashMarkerWindow(d time.Duration) CrashMarkerOption {
+	return func(c *crashMarker) {
+		c.window = d
+	}
+}
+
+// CrashMarkerThreshold sets how many crashes within the window make a crash
+// loop. Defaults to 3.
+func CrashMarkerThreshold(n int) CrashMarkerOption {
+	return func(c *crashMarker) {
+		c.threshold = n
+	}
+}
+
+// CrashLoopBackoff delays starting the service when crash-looping, by base
+// doubling with each crash beyond the threshold, up to max.
+func CrashLoopBackoff(base, max time.Duration) CrashMarkerOption {
+	return func(c *crashMarker) {
+		c.backoffBase, c.backoffMax = base, max
+	}
+}
+
+// CrashLoopFailFast refuses to start the service when crash-looping, shutting
+// it down with ShutdownCrashLoop, until the crashes are out of the window.
+func CrashLoopFailFast() CrashMarkerOption {
+	return func(c *crashMarker) {
+		c.failFast = true
+	}
+}
+
+// WithCrashMarker is an option that records the service's crashes in a marker
+// file, to detect crash loops of the service's logic at startup, e.g. a worker
+// failing a while after starting, which a container restart policy alone does
+// not make obvious. A crash is a worker or startup failure, or the process
+// dying without shutting down, e.g. on Fatal or when OOM-killed. The path must
+// outlive the process, e.g. on an emptyDir volume; an empty path uses
+// "<name>.crash" in the temporary directory. A crash loop is logged, reported
+// as a health warning, and the crashes within the window exported as the
+// svc_recent_crashes metric; starting is delayed with CrashLoopBackoff, or
+// refused with CrashLoopFailFast.
+func WithCrashMarker(path string, opts ...CrashMarkerOption) Option {
+	return func(s *SVC) error {
+		if path == "" {
+			path = filepath.Join(os.TempDir(), s.Name+".crash")
+		}
+		c := &crashMarker{
+			path:      path,
+			window:    defaultCrashMarkerWindow,
+			threshold: defaultCrashMarkerThreshold,
+		}
+		for _, o := range opts {
+			o(c)
+		}
+		if c.window <= 0 {
+			return errors.New("crash marker window must be positive")
+		}
+		if c.threshold < 1 {
+			return errors.New("crash marker threshold must be at least 1")
+		}
+		if c.backoffBase < 0 || c.backoffMax < c.backoffBase {
+			return errors.New("crash loop backoff must be positive and not exceed its maximum")
+		}
+		if s.crashMarker == nil {
+			if err := s.metrics.registerCrashMarker(s.internalRegister); err != nil {
+				return err
+			}
+		}
+		s.crashMarker = c
+
+		return nil
+	}
+}
+
+// crashMarker defines the marker file's configuration and state.
+type crashMarker struct {
+	path                    string
+	window                  time.Duration
+	threshold               int
+	backoffBase, backoffMax time.Duration
+	failFast                bool
+
+	state crashMarkerState
+	armed bool
+}
+
+// crashMarkerState defines the content of the marker file.
+type crashMarkerState struct {
+	// RunningSince is set while the service runs, thus left over by a process
+	// dying without shutting down.
+	RunningSince time.Time   `json:"running_since,omitempty"`
+	Crashes      []time.Time `json:"crashes,omitempty"`
+	// LastShutdown is the previous run's workers termination, if it got that
+	// far.
+	LastShutdown *shutdownStats `json:"last_shutdown,omitempty"`
+}
+
+// shutdownStats defines the outcome of a workers termination.
+type shutdownStats struct {
+	Duration            time.Duration `json:"duration"`
+	WorkersExceeded     int           `json:"workers_exceeding_deadline"`
+	GracePeriodExceeded bool          `json:"grace_period_exceeded"`
+}
+
+// errCrashLoop is returned by checkCrashLoop when refusing to start.
+var errCrashLoop = errors.New("crash loop")
+
+// checkCrashLoop loads the marker file, reports a crash loop, if any, and
+// marks the service running. It fails when refusing to start.
+func (s *SVC) checkCrashLoop() error {
+	c := s.crashMarker
+	if c == nil {
+		return nil
+	}
+
+	now := time.Now()
+	b, err := os.ReadFile(c.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("read crash marker: %w", err)
+	default:
+		if err := json.Unmarshal(b, &c.state); err != nil {
+			s.logger.Warn("Ignoring invalid crash marker", zap.String("path", c.path), zap.Error(err))
+			c.state = crashMarkerState{}
+		}
+	}
+	if !c.state.RunningSince.IsZero() {
+		s.logger.Warn("Previous run did not shut down", zap.Time("running_since", c.state.RunningSince))
+		c.state.Crashes = append(c.state.Crashes, now)
+	}
+	c.prune(now)
+	crashes := len(c.state.Crashes)
+	s.metrics.recentCrashes.Set(float64(crashes))
+	if l := c.state.LastShutdown; l != nil {
+		// Exported now, as the metrics are no longer served by then.
+		s.metrics.shutdownDuration.Set(l.Duration.Seconds())
+		s.metrics.shutdownWorkersExceeded.Set(float64(l.WorkersExceeded))
+		if l.GracePeriodExceeded {
+			s.metrics.shutdownGracePeriodExceeded.Set(1)
+		}
+		c.state.LastShutdown = nil
+	}
+
+	if crashes >= c.threshold {
+		detail := strconv.Itoa(crashes) + " crashes within " + c.window.String()
+		s.logger.Error("Crash loop detected", zap.Int("crashes", crashes), zap.Duration("window", c.window))
+		s.reportAnomaly(anomalyCrashLoop, detail)
+		if c.failFast {
+			// Left unchanged for the crashes to leave the window.
+			return fmt.Errorf("%w: %s", errCrashLoop, detail)
+		}
+		if delay := c.backoff(crashes); delay > 0 {
+			s.logger.Info("Delaying startup after crashes", zap.Duration("delay", delay))
+			time.Sleep(delay)
+		}
+	}
+
+	c.state.RunningSince = now
+	c.armed = true
+	return c.write()
+}
+
+// recordShutdownStats keeps the workers termination's outcome, to be written to
+// the marker file once shut down.
+func (s *SVC) recordShutdownStats(stats shutdownStats) {
+	if c := s.crashMarker; c != nil && c.armed {
+		c.state.LastShutdown = &stats
+	}
+}
+
+// recordShutdown updates the marker file once shut down, recording a crash if
+// the service failed.
+func (s *SVC) recordShutdown(cause ShutdownCause) {
+	c := s.crashMarker
+	if c == nil || !c.armed {
+		return
+	}
+
+	now := time.Now()
+	c.state.RunningSince = time.Time{}
+	if cause == ShutdownWorkerFailure || cause == ShutdownStartupFailure {
+		c.state.Crashes = append(c.state.Crashes, now)
+	}
+	c.prune(now)
+	if err := c.write(); err != nil {
+		s.logger.Error("Could not write crash marker", zap.String("path", c.path), zap.Error(err))
+	}
+}
+
+// prune drops the crashes out of the window.
+func (c *crashMarker) prune(now time.Time) {
+	kept := c.state.Crashes[:0]
+	for _, t := range c.state.Crashes {
+		if now.Sub(t) <= c.window {
+			kept = append(kept, t)
+		}
+	}
+	c.state.Crashes = kept
+}
+
+// backoff returns how long to delay starting after the given number of
+// crashes.
+func (c *crashMarker) backoff(crashes int) time.Duration {
+	if c.backoffBase == 0 {
+		return 0
+	}
+	delay := c.backoffBase
+	for i := c.threshold; i < crashes && delay < c.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > c.backoffMax {
+		delay = c.backoffMax
+	}
+	return delay
+}
+
+// write replaces the marker file atomically.
+func (c *crashMarker) write() error {
+	b, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write crash marker: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("write crash marker: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithCrashMarker(t *testing.T) {
+	recent := time.Now().Add(-time.Minute)
+	old := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name            string
+		marker          *crashMarkerState
+		opts            []CrashMarkerOption
+		runErr          error
+		expectedError   error
+		expectedInit    bool
+		expectedCrashes int
+	}{
+		{
+			name:         "clean run",
+			expectedInit: true,
+		},
+		{
+			name:            "worker failure",
+			runErr:          errors.New("dummy error"),
+			expectedError:   errors.New("worker dummy-worker exited: dummy error"),
+			expectedInit:    true,
+			expectedCrashes: 1,
+		},
+		{
+			name:            "previous run did not shut down",
+			marker:          &crashMarkerState{RunningSince: recent},
+			expectedInit:    true,
+			expectedCrashes: 1,
+		},
+		{
+			name:         "old crashes pruned",
+			marker:       &crashMarkerState{Crashes: []time.Time{old, old, old}},
+			expectedInit: true,
+		},
+		{
+			name:            "crash loop",
+			marker:          &crashMarkerState{Crashes: []time.Time{recent, recent, recent}},
+			expectedInit:    true,
+			expectedCrashes: 3,
+		},
+		{
+			name:            "crash loop fail fast",
+			marker:          &crashMarkerState{Crashes: []time.Time{recent, recent, recent}},
+			opts:            []CrashMarkerOption{CrashLoopFailFast()},
+			expectedError:   errCrashLoop,
+			expectedCrashes: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "svc.crash")
+			if tc.marker != nil {
+				b, err := json.Marshal(tc.marker)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(path, b, 0o644))
+			}
+			s, err := New("dummy-service", "v0.0.0", WithCrashMarker(path, tc.opts...))
+			require.NoError(t, err)
+			initialized := false
+			s.AddWorker("dummy-worker", &WorkerMock{
+				InitFunc: func(*zap.Logger) error {
+					initialized = true
+					return nil
+				},
+				RunFunc:       func() error { return tc.runErr },
+				TerminateFunc: func() error { return nil },
+			})
+
+			err = s.RunE()
+			switch {
+			case tc.expectedError == nil:
+				require.NoError(t, err)
+			case errors.Is(tc.expectedError, errCrashLoop):
+				require.ErrorIs(t, err, errCrashLoop)
+			default:
+				require.EqualError(t, err, tc.expectedError.Error())
+			}
+			assert.Equal(t, tc.expectedInit, initialized)
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var state crashMarkerState
+			require.NoError(t, json.Unmarshal(b, &state))
+			assert.True(t, state.RunningSince.IsZero())
+			assert.Len(t, state.Crashes, tc.expectedCrashes)
+			_, loop := s.self.anomalies[anomalyCrashLoop]
+			assert.Equal(t, tc.expectedCrashes >= defaultCrashMarkerThreshold, loop)
+		})
+	}
+}
+
+func TestCrashMarkerRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.crash")
+	s, err := New("dummy-service", "v0.0.0", WithCrashMarker(path))
+	require.NoError(t, err)
+	require.NoError(t, s.checkCrashLoop())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var state crashMarkerState
+	require.NoError(t, json.Unmarshal(b, &state))
+	assert.False(t, state.RunningSince.IsZero(), "marker should be left running if the process dies")
+	assert.Equal(t, float64(0), testutil.ToFloat64(s.metrics.recentCrashes))
+}
+
+func TestCrashMarkerMetrics(t *testing.T) {
+	names := []string{
+		"svc_recent_crashes",
+		"svc_shutdown_duration_seconds",
+		"svc_shutdown_workers_exceeding_deadline",
+		"svc_shutdown_grace_period_exceeded",
+	}
+	registered := func(s *SVC) []string {
+		mfs, err := s.internalRegister.Gather()
+		require.NoError(t, err)
+		var found []string
+		for _, mf := range mfs {
+			for _, n := range names {
+				if mf.GetName() == n {
+					found = append(found, n)
+				}
+			}
+		}
+		return found
+	}
+
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, registered(s), "crash marker metrics should not be exported without it")
+
+	path := filepath.Join(t.TempDir(), "svc.crash")
+	s, err = New("dummy-service", "v0.0.0", WithCrashMarker(path), WithCrashMarker(path))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, names, registered(s))
+}
+
+func TestCrashLoopBackoff(t *testing.T) {
+	c := &crashMarker{threshold: 3, backoffBase: time.Second, backoffMax: 5 * time.Second}
+	assert.Equal(t, time.Second, c.backoff(3))
+	assert.Equal(t, 2*time.Second, c.backoff(4))
+	assert.Equal(t, 4*time.Second, c.backoff(5))
+	assert.Equal(t, 5*time.Second, c.backoff(6))
+	assert.Equal(t, 5*time.Second, c.backoff(100))
+
+	_, err := New("dummy-service", "v0.0.0", WithCrashMarker("", CrashLoopBackoff(time.Minute, time.Second)))
+	require.EqualError(t, err, "crash loop backoff must be positive and not exceed its maximum")
+}
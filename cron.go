@@ -0,0 +1,258 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// NewCronWorker returns a worker calling fn on a schedule, either a standard
+// cron expression with five fields (minute, hour, day of month, month, day of
+// week), e.g. "*/15 9-17 * * 1-5", one of the descriptors @hourly, @daily,
+// @weekly, @monthly and @yearly, or "@every <duration>", in local time. Runs
+// never overlap: ticks elapsing during a run are skipped. fn's context is
+// canceled when the worker is terminated. The last run's failure is reported
+// as a health warning. Add it with AddWorker under the same name.
+func NewCronWorker(name, schedule string, fn func(ctx context.Context) error) (Worker, error) {
+	sched, err := parseCronSchedule(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("cron worker %s: %w", name, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cronWorker{
+		name:     name,
+		schedule: sched,
+		fn:       fn,
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+var (
+	_ Worker        = (*cronWorker)(nil)
+	_ HealthChecker = (*cronWorker)(nil)
+)
+
+// cronWorker defines the worker running a function on a schedule.
+type cronWorker struct {
+	name     string
+	logger   *zap.Logger
+	schedule cronSchedule
+	fn       func(ctx context.Context) error
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+// Init implements the Worker interface.
+func (c *cronWorker) Init(logger *zap.Logger) error {
+	c.logger = logger
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (c *cronWorker) Run() error {
+	for {
+		next := c.schedule.next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("cron worker %s: schedule never fires", c.name)
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			timer.Stop()
+			return nil
+		}
+		c.run()
+	}
+}
+
+// Terminate implements the Worker interface. It cancels the run in progress,
+// if any.
+func (c *cronWorker) Terminate() error {
+	c.cancel()
+
+	return nil
+}
+
+// CheckHealth implements the HealthChecker interface.
+func (c *cronWorker) CheckHealth() HealthResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastErr == nil {
+		return HealthResult{Status: HealthOK, CheckedAt: time.Now()}
+	}
+	return HealthResult{
+		Status:    HealthWarn,
+		Detail:    fmt.Sprintf("run at %s failed: %s", c.lastRun.Format(time.RFC3339), c.lastErr),
+		CheckedAt: time.Now(),
+	}
+}
+
+func (c *cronWorker) run() {
+	start := time.Now()
+	err := c.fn(c.ctx)
+	if err != nil && c.ctx.Err() != nil {
+		// Canceled by termination.
+		return
+	}
+
+	c.mu.Lock()
+	c.lastRun, c.lastErr = start, err
+	c.mu.Unlock()
+	if err != nil {
+		c.logger.Error("Scheduled run failed", zap.Error(err))
+		return
+	}
+	c.logger.Debug("Scheduled run completed", zap.Duration("duration", time.Since(start)))
+}
+
+// cronSchedule defines a parsed schedule. Fields are bit sets of the allowed
+// values.
+type cronSchedule struct {
+	every                         time.Duration
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseCronSchedule(spec string) (cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every <= 0 {
+			return cronSchedule{}, fmt.Errorf("invalid schedule %q: positive duration expected", spec)
+		}
+		return cronSchedule{every: every}, nil
+	}
+	if expr, ok := cronDescriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("invalid schedule %q: 5 fields expected", spec)
+	}
+	var s cronSchedule
+	var err error
+	for i, f := range []struct {
+		bits     *uint64
+		min, max uint
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		// 7 is Sunday too.
+		{&s.dow, 0, 7},
+	} {
+		if *f.bits, err = parseCronField(fields[i], f.min, f.max); err != nil {
+			return cronSchedule{}, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = !strings.HasPrefix(fields[2], "*")
+	s.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges, or *,
+// each with an optional step, e.g. "1,10-20/5,*/30".
+func parseCronField(field string, min, max uint) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := uint64(1)
+		if hasStep {
+			var err error
+			if step, err = strconv.ParseUint(stepStr, 10, 8); err != nil || step == 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			l, err := strconv.ParseUint(loStr, 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", item)
+			}
+			lo, hi = uint(l), uint(l)
+			if isRange {
+				h, err := strconv.ParseUint(hiStr, 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid range in %q", item)
+				}
+				hi = uint(h)
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += uint(step) {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first time the schedule fires after t, or zero if it does
+// not within 5 years, e.g. on February 30th.
+func (s cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day matches: as in cron, the day of month and
+// the day of week are alternatives when both are restricted.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,71 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC) // Wednesday
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"5,10 9-17 * * *", time.Date(2024, time.January, 31, 11, 5, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 5", time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.schedule, func(t *testing.T) {
+			s, err := parseCronSchedule(tc.schedule)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, s.next(from))
+		})
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, schedule := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@often"} {
+		_, err := parseCronSchedule(schedule)
+		assert.Error(t, err, schedule)
+	}
+}
+
+func TestNewCronWorker(t *testing.T) {
+	var runs int32
+	w, err := NewCronWorker("dummy-cron", "@every 5ms", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	c := w.(*cronWorker)
+	require.NoError(t, c.Init(zap.NewNop()))
+	done := make(chan error)
+	go func() { done <- c.Run() }()
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 1 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 2 && c.CheckHealth().Status == HealthOK },
+		time.Second, time.Millisecond)
+	require.NoError(t, c.Terminate())
+	require.NoError(t, <-done)
+
+	_, err = NewCronWorker("dummy-cron", "* *", nil)
+	require.EqualError(t, err, `cron worker dummy-cron: invalid schedule "* *": 5 fields expected`)
+}
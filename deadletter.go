@@ -0,0 +1,82 @@
+package svc
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DeadLetter describes a message that could not be processed after all retries
+// were exhausted.
+type DeadLetter struct {
+	Source   string
+	Key      []byte
+	Payload  []byte
+	Headers  map[string]string
+	Attempts int
+	Err      error
+	FailedAt time.Time
+}
+
+// DeadLetterer defines a sink that messages exhausting their retries are handed
+// to, e.g. a dead-letter topic, an object store, or a log.
+type DeadLetterer interface {
+	DeadLetter(ctx context.Context, msg DeadLetter) error
+}
+
+// DeadLettererFunc is an adapter to allow the use of ordinary functions as
+// DeadLetterer.
+type DeadLettererFunc func(ctx context.Context, msg DeadLetter) error
+
+// DeadLetter implements the DeadLetterer interface.
+func (f DeadLettererFunc) DeadLetter(ctx context.Context, msg DeadLetter) error {
+	return f(ctx, msg)
+}
+
+// NewLogDeadLetterer returns a DeadLetterer that only logs the dead-lettered
+// messages. It is the default sink.
+func NewLogDeadLetterer(logger *zap.Logger) DeadLetterer {
+	return DeadLettererFunc(func(_ context.Context, msg DeadLetter) error {
+		logger.Error("Message dead-lettered",
+			zap.String("source", msg.Source),
+			zap.ByteString("key", msg.Key),
+			zap.Int("attempts", msg.Attempts),
+			zap.Time("failed_at", msg.FailedAt),
+			zap.Error(msg.Err))
+		return nil
+	})
+}
+
+// WithDeadLetterer is an option that sets the sink consumer workers hand
+// messages to once they exhausted their retries.
+func WithDeadLetterer(d DeadLetterer) Option {
+	return func(s *SVC) error {
+		s.deadLetterer = d
+
+		return nil
+	}
+}
+
+// DeadLetter hands the given message to the configured DeadLetterer and keeps
+// track of the outcome in metrics. Consumer workers should call it for messages
+// that exhausted their retries, e.g. the Kafka consumer of svc/workers/kafka
+// with kafka.WithDeadLetterer(DeadLettererFunc(s.DeadLetter)).
+func (s *SVC) DeadLetter(ctx context.Context, msg DeadLetter) error {
+	if msg.FailedAt.IsZero() {
+		msg.FailedAt = time.Now()
+	}
+	d := s.deadLetterer
+	if d == nil {
+		d = NewLogDeadLetterer(s.logger.Named("dead-letter"))
+	}
+
+	if err := d.DeadLetter(ctx, msg); err != nil {
+		s.metrics.deadLetters.WithLabelValues(msg.Source, "error").Inc()
+		s.logger.Error("Could not dead-letter message", zap.String("source", msg.Source), zap.Error(err))
+		return err
+	}
+	s.metrics.deadLetters.WithLabelValues(msg.Source, "ok").Inc()
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetter(t *testing.T) {
+	var received []DeadLetter
+	sink := DeadLettererFunc(func(_ context.Context, msg DeadLetter) error {
+		received = append(received, msg)
+		if msg.Err == nil {
+			return fmt.Errorf("sink unavailable")
+		}
+		return nil
+	})
+
+	s, err := New("dummy-service", "v0.0.0", WithDeadLetterer(sink))
+	require.NoError(t, err)
+
+	err = s.DeadLetter(context.Background(), DeadLetter{Source: "orders", Attempts: 3, Err: fmt.Errorf("boom")})
+	require.NoError(t, err)
+	err = s.DeadLetter(context.Background(), DeadLetter{Source: "orders"})
+	require.Error(t, err)
+
+	require.Len(t, received, 2)
+	assert.False(t, received[0].FailedAt.IsZero())
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.metrics.deadLetters.WithLabelValues("orders", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.metrics.deadLetters.WithLabelValues("orders", "error")))
+}
+
+func TestDeadLetterDefaultSink(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	err = s.DeadLetter(context.Background(), DeadLetter{Source: "orders", Err: fmt.Errorf("boom")})
+	require.NoError(t, err)
+}
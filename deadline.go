@@ -0,0 +1,221 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deadline budget headers, set by DeadlineTransport and read by
+// WithDeadlinePropagation.
+const (
+	// RequestTimeoutHeader carries the time remaining to handle the request,
+	// in the grpc-timeout format, e.g. "250m" for 250ms. Being relative, it is
+	// not affected by clock skew between hosts.
+	RequestTimeoutHeader = "X-Request-Timeout"
+	// RequestDeadlineHeader carries the absolute deadline of the request, in
+	// RFC 3339 format, for clients that cannot send a relative timeout.
+	RequestDeadlineHeader = "X-Request-Deadline"
+)
+
+// grpc-timeout units, from the finest.
+var timeoutUnits = []struct {
+	unit byte
+	d    time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// timeoutMaxDigits is the maximum number of digits of a grpc-timeout value.
+const timeoutMaxDigits = 8
+
+// DeadlineOption defines WithDeadlinePropagation's option type.
+type DeadlineOption func(*deadlineConfig)
+
+type deadlineConfig struct {
+	minBudget time.Duration
+	maxBudget time.Duration
+	clockSkew time.Duration
+}
+
+// DeadlineMinBudget sets the remaining time under which requests are rejected
+// rather than handled, e.g. the time the handler needs at least. Defaults to
+// 0, i.e. only requests whose budget is exhausted are rejected.
+func DeadlineMinBudget(d time.Duration) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.minBudget = d
+	}
+}
+
+// DeadlineMaxBudget caps the budgets accepted from clients. Defaults to none.
+func DeadlineMaxBudget(d time.Duration) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.maxBudget = d
+	}
+}
+
+// DeadlineClockSkew sets the clock skew tolerated between the clients and the
+// service, subtracted from the absolute deadlines of RequestDeadlineHeader so
+// the service gives up before the client does. Relative timeouts are not
+// affected. Defaults to 0.
+func DeadlineClockSkew(d time.Duration) DeadlineOption {
+	return func(c *deadlineConfig) {
+		c.clockSkew = d
+	}
+}
+
+// WithDeadlinePropagation is an option that bounds the requests' context on
+// the internal HTTP server by the budget sent by the client, in the
+// RequestTimeoutHeader or RequestDeadlineHeader header, so the remaining time
+// propagates across hops when the handlers call other services with
+// DeadlineTransport. Requests whose budget is exhausted, see
+// DeadlineMinBudget, fail fast with 504 Gateway Timeout and are counted in the
+// svc_http_deadline_exhausted_total metric. Requests without a budget are
+// served as is.
+func WithDeadlinePropagation(opts ...DeadlineOption) Option {
+	return func(s *SVC) error {
+		cfg := &deadlineConfig{}
+		for _, o := range opts {
+			o(cfg)
+		}
+		if cfg.minBudget < 0 || cfg.maxBudget < 0 || cfg.clockSkew < 0 {
+			return errors.New("deadline budgets and clock skew must not be negative")
+		}
+		s.middlewares = append(s.middlewares, func(next http.Handler) http.Handler {
+			return s.deadlineMiddleware(cfg, next)
+		})
+
+		return nil
+	}
+}
+
+func (s *SVC) deadlineMiddleware(cfg *deadlineConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		budget, ok, err := cfg.budget(r.Header, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if budget <= 0 || budget < cfg.minBudget {
+			s.metrics.deadlineExhausted.Inc()
+			http.Error(w, "deadline budget exhausted", http.StatusGatewayTimeout)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), budget)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// budget returns the time remaining to handle a request with the headers,
+// and whether the client sent one.
+func (c *deadlineConfig) budget(h http.Header, now time.Time) (time.Duration, bool, error) {
+	var budget time.Duration
+	if v := h.Get(RequestTimeoutHeader); v != "" {
+		d, err := parseTimeout(v)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %s header: %w", RequestTimeoutHeader, err)
+		}
+		budget = d
+	} else if v := h.Get(RequestDeadlineHeader); v != "" {
+		deadline, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %s header: %w", RequestDeadlineHeader, err)
+		}
+		budget = deadline.Sub(now) - c.clockSkew
+	} else {
+		return 0, false, nil
+	}
+
+	if c.maxBudget > 0 && budget > c.maxBudget {
+		budget = c.maxBudget
+	}
+	return budget, true, nil
+}
+
+// DeadlineTransport returns a RoundTripper sending the time remaining until
+// the request's context deadline, if any, in the RequestTimeoutHeader and
+// RequestDeadlineHeader headers, so the server can bound its work by it. It
+// fails fast with context.DeadlineExceeded once the deadline passed, without
+// sending the request. A nil base defaults to http.DefaultTransport, e.g.:
+//
+//	client := &http.Client{Transport: svc.DeadlineTransport(nil)}
+func DeadlineTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return deadlineTransport{base}
+}
+
+type deadlineTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t deadlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return t.base.RoundTrip(r)
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+
+	// A RoundTripper must not modify the request.
+	r = r.Clone(r.Context())
+	r.Header.Set(RequestTimeoutHeader, formatTimeout(remaining))
+	r.Header.Set(RequestDeadlineHeader, deadline.UTC().Format(time.RFC3339Nano))
+	return t.base.RoundTrip(r)
+}
+
+// formatTimeout formats d in the grpc-timeout format, in the finest unit
+// fitting in 8 digits, rounded up so the budget is not extended.
+func formatTimeout(d time.Duration) string {
+	for _, u := range timeoutUnits {
+		v := (d + u.d - 1) / u.d
+		if v < 1e8 {
+			return strconv.FormatInt(int64(v), 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(1e8-1) + "H"
+}
+
+// parseTimeout parses a timeout in the grpc-timeout format: up to 8 digits
+// followed by a unit, H, M, S, m, u or n.
+func parseTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > timeoutMaxDigits+1 {
+		return 0, fmt.Errorf("malformed timeout %q", s)
+	}
+	v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed timeout %q", s)
+	}
+	for _, u := range timeoutUnits {
+		if u.unit == s[len(s)-1] {
+			// 8 digits of hours do not fit in a time.Duration.
+			if v > uint64(math.MaxInt64/u.d) {
+				return math.MaxInt64, nil
+			}
+			return time.Duration(v) * u.d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit in timeout %q", s)
+}
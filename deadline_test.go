@@ -0,0 +1,182 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeadlinePropagation(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             []DeadlineOption
+		headers          map[string]string
+		expectedCode     int
+		expectedDeadline bool
+		expectedMax      time.Duration
+	}{
+		{
+			name:         "no budget",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:             "relative timeout",
+			headers:          map[string]string{RequestTimeoutHeader: "2S"},
+			expectedCode:     http.StatusOK,
+			expectedDeadline: true,
+			expectedMax:      2 * time.Second,
+		},
+		{
+			name: "relative timeout preferred",
+			headers: map[string]string{
+				RequestTimeoutHeader:  "500m",
+				RequestDeadlineHeader: time.Now().Add(time.Hour).Format(time.RFC3339Nano),
+			},
+			expectedCode:     http.StatusOK,
+			expectedDeadline: true,
+			expectedMax:      500 * time.Millisecond,
+		},
+		{
+			name:             "absolute deadline with clock skew",
+			opts:             []DeadlineOption{DeadlineClockSkew(time.Second)},
+			headers:          map[string]string{RequestDeadlineHeader: time.Now().Add(3 * time.Second).Format(time.RFC3339Nano)},
+			expectedCode:     http.StatusOK,
+			expectedDeadline: true,
+			expectedMax:      2 * time.Second,
+		},
+		{
+			name:             "capped budget",
+			opts:             []DeadlineOption{DeadlineMaxBudget(time.Second)},
+			headers:          map[string]string{RequestTimeoutHeader: "1H"},
+			expectedCode:     http.StatusOK,
+			expectedDeadline: true,
+			expectedMax:      time.Second,
+		},
+		{
+			name:         "exhausted budget",
+			headers:      map[string]string{RequestDeadlineHeader: time.Now().Add(-time.Second).Format(time.RFC3339Nano)},
+			expectedCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:         "budget below minimum",
+			opts:         []DeadlineOption{DeadlineMinBudget(time.Second)},
+			headers:      map[string]string{RequestTimeoutHeader: "100m"},
+			expectedCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:         "malformed timeout",
+			headers:      map[string]string{RequestTimeoutHeader: "soon"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithDeadlinePropagation(tc.opts...))
+			require.NoError(t, err)
+			var deadline time.Time
+			var hasDeadline bool
+			s.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+				deadline, hasDeadline = r.Context().Deadline()
+			})
+
+			req := httptest.NewRequest("GET", "/data", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			s.serveHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, tc.expectedDeadline, hasDeadline)
+			if tc.expectedDeadline {
+				assert.LessOrEqual(t, time.Until(deadline), tc.expectedMax)
+				assert.Greater(t, time.Until(deadline), tc.expectedMax-time.Second/2)
+			}
+			exhausted := float64(0)
+			if tc.expectedCode == http.StatusGatewayTimeout {
+				exhausted = 1
+			}
+			assert.Equal(t, exhausted, testutil.ToFloat64(s.metrics.deadlineExhausted))
+		})
+	}
+
+	_, err := New("dummy-service", "v0.0.0", WithDeadlinePropagation(DeadlineMinBudget(-time.Second)))
+	require.Error(t, err)
+}
+
+func TestDeadlineTransport(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: DeadlineTransport(nil)}
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, headers.Get(RequestTimeoutHeader))
+	assert.Empty(t, headers.Get(RequestDeadlineHeader))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resp, err = client.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	resp.Body.Close()
+	timeout, err := parseTimeout(headers.Get(RequestTimeoutHeader))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, timeout, time.Minute)
+	assert.Greater(t, timeout, 59*time.Second)
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, deadline.UTC().Format(time.RFC3339Nano), headers.Get(RequestDeadlineHeader))
+	assert.Empty(t, req.Header.Get(RequestTimeoutHeader), "request should not be modified")
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = client.Do(req.WithContext(ctx))
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestTimeoutFormat(t *testing.T) {
+	tests := []struct {
+		timeout  time.Duration
+		expected string
+	}{
+		{timeout: 1500 * time.Microsecond, expected: "1500000n"},
+		{timeout: 250 * time.Millisecond, expected: "250000u"},
+		{timeout: 30 * time.Second, expected: "30000000u"},
+		{timeout: 2 * time.Hour, expected: "7200000m"},
+		{timeout: 48 * time.Hour, expected: "172800S"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.expected, func(t *testing.T) {
+			s := formatTimeout(tc.timeout)
+			assert.Equal(t, tc.expected, s)
+			d, err := parseTimeout(s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.timeout, d)
+		})
+	}
+
+	d, err := parseTimeout("99999999H")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(math.MaxInt64), d)
+	for _, s := range []string{"", "1", "100", "1x", "-1S", "123456789S"} {
+		_, err := parseTimeout(s)
+		assert.Error(t, err, s)
+	}
+}
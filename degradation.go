@@ -0,0 +1,341 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDegradationCheckInterval = 10 * time.Second
+	defaultDegradationCheckTimeout  = 5 * time.Second
+	degradationWorkerName           = "internal-degradation"
+)
+
+// Feature modes: a feature is degraded automatically by its dependencies'
+// health, or forced degraded or normal, e.g. by an operator.
+const (
+	FeatureModeAuto     = "auto"
+	FeatureModeDegraded = "degraded"
+	FeatureModeNormal   = "normal"
+)
+
+// ErrUnknownFeature is returned when setting the mode of a feature that was not
+// registered with Degrade.
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// DegradeOption defines Degrade's option type.
+type DegradeOption func(*Feature)
+
+// DegradeOnDependency degrades the feature while the dependency's check
+// fails, e.g. pinging the recommendation engine.
+func DegradeOnDependency(dep Dependency) DegradeOption {
+	return func(f *Feature) {
+		f.deps = append(f.deps, dep)
+	}
+}
+
+// DegradeOnWorker degrades the feature while the named worker's health check
+// is critical.
+func DegradeOnWorker(name string) DegradeOption {
+	return func(f *Feature) {
+		f.workers = append(f.workers, name)
+	}
+}
+
+// Feature defines a degradable feature registered with Degrade.
+type Feature struct {
+	s        *SVC
+	name     string
+	fallback func(ctx context.Context) error
+	deps     []Dependency
+	workers  []string
+
+	mu     sync.Mutex
+	mode   string
+	auto   bool
+	reason string
+	since  time.Time
+}
+
+// Degrade registers a degradable feature, e.g. "recommendations", with the
+// fallback its Run calls instead of the feature while it is degraded, e.g.
+// serving the most popular items. The feature is degraded automatically while
+// a dependency or worker set with DegradeOnDependency or DegradeOnWorker is
+// unhealthy, checked every 10s, or manually with SetFeatureMode. Degraded
+// features are reported as health warnings of the "internal-degradation"
+// worker, and exported as the svc_feature_degraded metric.
+func (s *SVC) Degrade(name string, fallback func(ctx context.Context) error, opts ...DegradeOption) *Feature {
+	f := &Feature{s: s, name: name, fallback: fallback, mode: FeatureModeAuto}
+	for _, o := range opts {
+		o(f)
+	}
+
+	if s.degradation == nil {
+		s.degradation = &degradation{s: s, features: map[string]*Feature{}, done: make(chan struct{})}
+		s.AddWorker(degradationWorkerName, s.degradation)
+	}
+	s.degradation.mu.Lock()
+	s.degradation.features[name] = f
+	s.degradation.mu.Unlock()
+	s.metrics.featureDegraded.WithLabelValues(name).Set(0)
+	return f
+}
+
+// Name returns the feature's name.
+func (f *Feature) Name() string {
+	return f.name
+}
+
+// Degraded returns whether the feature is degraded.
+func (f *Feature) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.degraded()
+}
+
+// degraded returns whether the feature is degraded. The lock must be held.
+func (f *Feature) degraded() bool {
+	switch f.mode {
+	case FeatureModeDegraded:
+		return true
+	case FeatureModeNormal:
+		return false
+	}
+	return f.auto
+}
+
+// Run calls fn, or the fallback while the feature is degraded.
+func (f *Feature) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if f.Degraded() {
+		return f.fallback(ctx)
+	}
+	return fn(ctx)
+}
+
+// status returns the feature's status.
+func (f *Feature) status() FeatureStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st := FeatureStatus{Name: f.name, Degraded: f.degraded(), Mode: f.mode}
+	if st.Degraded {
+		st.Reason = f.reason
+		if f.mode == FeatureModeDegraded {
+			st.Reason = "forced"
+		}
+		since := f.since
+		st.Since = &since
+	}
+	return st
+}
+
+// update sets the feature's mode or automatic state, logging and exporting
+// the changes.
+func (f *Feature) update(set func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	was := f.degraded()
+	set()
+	now := f.degraded()
+	if was == now {
+		return
+	}
+	f.since = time.Now()
+	if now {
+		f.s.metrics.featureDegraded.WithLabelValues(f.name).Set(1)
+		f.s.logger.Warn("Feature degraded", zap.String("feature", f.name),
+			zap.String("mode", f.mode), zap.String("reason", f.reason))
+		return
+	}
+	f.s.metrics.featureDegraded.WithLabelValues(f.name).Set(0)
+	f.s.logger.Info("Feature restored", zap.String("feature", f.name), zap.String("mode", f.mode))
+}
+
+// SetFeatureMode sets the mode of the feature registered with Degrade: force
+// it degraded or normal, e.g. during an incident, or back to auto.
+func (s *SVC) SetFeatureMode(name, mode string) error {
+	switch mode {
+	case FeatureModeAuto, FeatureModeDegraded, FeatureModeNormal:
+	default:
+		return fmt.Errorf("invalid feature mode %q", mode)
+	}
+	if s.degradation == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+	s.degradation.mu.Lock()
+	f, ok := s.degradation.features[name]
+	s.degradation.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownFeature, name)
+	}
+
+	f.update(func() { f.mode = mode })
+	return nil
+}
+
+// Features returns the status of the features registered with Degrade,
+// sorted by name.
+func (s *SVC) Features() []FeatureStatus {
+	features := []FeatureStatus{}
+	if s.degradation == nil {
+		return features
+	}
+	s.degradation.mu.Lock()
+	defer s.degradation.mu.Unlock()
+
+	for _, f := range s.degradation.features {
+		features = append(features, f.status())
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i].Name < features[j].Name })
+	return features
+}
+
+var (
+	_ Worker        = (*degradation)(nil)
+	_ HealthChecker = (*degradation)(nil)
+)
+
+// degradation defines the internal worker degrading the features by their
+// dependencies' health.
+type degradation struct {
+	s    *SVC
+	done chan struct{}
+
+	mu       sync.Mutex
+	features map[string]*Feature
+}
+
+// Init implements the Worker interface.
+func (d *degradation) Init(*zap.Logger) error {
+	return nil
+}
+
+// Run implements the Worker interface.
+func (d *degradation) Run() error {
+	ticker := time.NewTicker(defaultDegradationCheckInterval)
+	defer ticker.Stop()
+	for {
+		d.check()
+		select {
+		case <-ticker.C:
+		case <-d.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (d *degradation) Terminate() error {
+	close(d.done)
+
+	return nil
+}
+
+// CheckHealth implements the HealthChecker interface. Degraded features are
+// reported as warnings, as the service still serves.
+func (d *degradation) CheckHealth() HealthResult {
+	var degraded []string
+	for _, st := range d.s.Features() {
+		if st.Degraded {
+			degraded = append(degraded, st.Name+" ("+st.Reason+")")
+		}
+	}
+	if len(degraded) == 0 {
+		return HealthResult{Status: HealthOK, CheckedAt: time.Now()}
+	}
+	return HealthResult{
+		Status:    HealthWarn,
+		Detail:    "degraded features: " + strings.Join(degraded, ", "),
+		CheckedAt: time.Now(),
+	}
+}
+
+// check degrades the features whose dependencies are unhealthy, and restores
+// the others.
+func (d *degradation) check() {
+	d.mu.Lock()
+	features := make([]*Feature, 0, len(d.features))
+	for _, f := range d.features {
+		features = append(features, f)
+	}
+	d.mu.Unlock()
+
+	for _, f := range features {
+		if len(f.deps) == 0 && len(f.workers) == 0 {
+			continue
+		}
+		reason := d.unhealthy(f)
+		f.update(func() { f.auto, f.reason = reason != "", reason })
+	}
+}
+
+// unhealthy returns why the feature's dependencies are unhealthy, if they are.
+func (d *degradation) unhealthy(f *Feature) string {
+	var reasons []string
+	for _, dep := range f.deps {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDegradationCheckTimeout)
+		err := dep.Check(ctx)
+		cancel()
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("dependency %s: %s", dep.Name, err))
+		}
+	}
+	for _, name := range f.workers {
+		w, ok := d.s.workers[name]
+		if !ok {
+			continue
+		}
+		if res, ok := d.s.checkHealth(name, w); ok && res.Status == HealthCritical {
+			reasons = append(reasons, fmt.Sprintf("worker %s: %s", name, res.Detail))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// WithDegradationHandlers is an option that sets up an HTTP route to list
+// (GET) the features registered with Degrade, and to set (PUT) a feature's
+// mode, e.g. `{"feature": "recommendations", "mode": "degraded"}`.
+func WithDegradationHandlers() Option {
+	return func(s *SVC) error {
+		s.handle("WithDegradationHandlers", "/degradation", http.HandlerFunc(s.degradationHandler))
+
+		return nil
+	}
+}
+
+func (s *SVC) degradationHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload FeatureModeRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("invalid payload: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := s.SetFeatureMode(payload.Feature, payload.Mode); err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, ErrUnknownFeature) {
+				code = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, DegradationResponse{Features: s.Features()})
+}
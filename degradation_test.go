@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDegrade(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	var depErr, workerErr error
+	s.AddWorker("engine", &WorkerMock{HealthyFunc: func() error { return workerErr }})
+	f := s.Degrade("recommendations", func(context.Context) error { return errors.New("fallback") },
+		DegradeOnDependency(Dependency{Name: "cache", Check: func(context.Context) error { return depErr }}),
+		DegradeOnWorker("engine"),
+	)
+	manual := s.Degrade("search", func(context.Context) error { return nil })
+	primary := func(context.Context) error { return nil }
+
+	run := func() error { return f.Run(context.Background(), primary) }
+	degraded := func() float64 {
+		return testutil.ToFloat64(s.metrics.featureDegraded.WithLabelValues("recommendations"))
+	}
+
+	s.degradation.check()
+	assert.False(t, f.Degraded())
+	require.NoError(t, run())
+	assert.Equal(t, HealthOK, s.degradation.CheckHealth().Status)
+
+	depErr = errors.New("connection refused")
+	s.degradation.check()
+	assert.True(t, f.Degraded())
+	require.EqualError(t, run(), "fallback")
+	assert.Equal(t, float64(1), degraded())
+	res := s.degradation.CheckHealth()
+	assert.Equal(t, HealthWarn, res.Status)
+	assert.Equal(t, "degraded features: recommendations (dependency cache: connection refused)", res.Detail)
+
+	depErr, workerErr = nil, errors.New("overloaded")
+	s.degradation.check()
+	assert.True(t, f.Degraded())
+	assert.Equal(t, "worker engine: overloaded", s.Features()[0].Reason)
+
+	workerErr = nil
+	s.degradation.check()
+	assert.False(t, f.Degraded())
+	assert.Equal(t, float64(0), degraded())
+
+	// Manual modes override the dependencies' health.
+	require.NoError(t, s.SetFeatureMode("recommendations", FeatureModeDegraded))
+	assert.True(t, f.Degraded())
+	depErr = errors.New("connection refused")
+	require.NoError(t, s.SetFeatureMode("recommendations", FeatureModeNormal))
+	s.degradation.check()
+	assert.False(t, f.Degraded())
+	require.NoError(t, s.SetFeatureMode("recommendations", FeatureModeAuto))
+	assert.True(t, f.Degraded())
+
+	require.NoError(t, s.Admin().SetFeatureMode("search", FeatureModeDegraded))
+	assert.True(t, manual.Degraded())
+	features := s.Admin().Features()
+	require.Len(t, features, 2)
+	assert.Equal(t, "search", features[1].Name)
+	assert.Equal(t, "forced", features[1].Reason)
+	assert.NotNil(t, features[1].Since)
+
+	require.ErrorIs(t, s.SetFeatureMode("unknown", FeatureModeDegraded), ErrUnknownFeature)
+	require.Error(t, s.SetFeatureMode("search", "off"))
+}
+
+func TestDegradationHandlers(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithDegradationHandlers())
+	require.NoError(t, err)
+	s.Degrade("recommendations", func(context.Context) error { return nil })
+
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		expectedCode     int
+		expectedDegraded bool
+	}{
+		{name: "list", method: http.MethodGet, expectedCode: http.StatusOK},
+		{name: "degrade", method: http.MethodPut, body: `{"feature":"recommendations","mode":"degraded"}`, expectedCode: http.StatusOK, expectedDegraded: true},
+		{name: "unknown feature", method: http.MethodPut, body: `{"feature":"search","mode":"degraded"}`, expectedCode: http.StatusNotFound},
+		{name: "invalid mode", method: http.MethodPut, body: `{"feature":"recommendations","mode":"off"}`, expectedCode: http.StatusBadRequest},
+		{name: "invalid payload", method: http.MethodPut, body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodDelete, expectedCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, s.SetFeatureMode("recommendations", FeatureModeAuto))
+			w := httptest.NewRecorder()
+			s.Router.ServeHTTP(w, httptest.NewRequest(tc.method, "/degradation", strings.NewReader(tc.body)))
+			require.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			var resp DegradationResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Features, 1)
+			assert.Equal(t, tc.expectedDegraded, resp.Features[0].Degraded)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDependencyCheckTimeout = 5 * time.Second
+	dependencyMinBackoff          = 100 * time.Millisecond
+	dependencyMaxBackoff          = 5 * time.Second
+)
+
+// WaitForDependencies checks the dependencies in parallel until all succeeded,
+// retrying each failing check with exponential backoff from 100ms up to 5s,
+// and each check bounded by 5s. It returns the last error of every dependency
+// still failing once ctx is done, which bounds the overall wait, e.g. in a
+// worker's Init or a startup gate:
+//
+//	svc.WithStartupGate(func(ctx context.Context) error {
+//		return svc.WaitForDependencies(ctx, db, cache)
+//	})
+func WaitForDependencies(ctx context.Context, deps ...Dependency) error {
+	errs := make([]error, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep Dependency) {
+			defer wg.Done()
+			errs[i] = waitForDependency(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func waitForDependency(ctx context.Context, dep Dependency) error {
+	backoff := dependencyMinBackoff
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, defaultDependencyCheckTimeout)
+		err := dep.Check(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("dependency %s: %w", dep.Name, err)
+		}
+		if backoff *= 2; backoff > dependencyMaxBackoff {
+			backoff = dependencyMaxBackoff
+		}
+	}
+}
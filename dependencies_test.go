@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDependencies(t *testing.T) {
+	var dbChecks atomic.Int32
+	db := Dependency{Name: "db", Check: func(context.Context) error {
+		if dbChecks.Add(1) < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	cache := Dependency{Name: "cache", Check: func(context.Context) error { return nil }}
+	broker := Dependency{Name: "broker", Check: func(context.Context) error { return errors.New("no route to host") }}
+
+	tests := []struct {
+		name          string
+		deps          []Dependency
+		expectedError string
+	}{
+		{
+			name: "should wait for failing dependencies to succeed",
+			deps: []Dependency{db, cache},
+		},
+		{
+			name:          "should report the dependencies still failing",
+			deps:          []Dependency{cache, broker},
+			expectedError: "dependency broker: no route to host",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			err := WaitForDependencies(ctx, tc.deps...)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+	assert.Equal(t, int32(3), dbChecks.Load())
+}
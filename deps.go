@@ -0,0 +1,59 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AddWorkerWithDeps adds a named worker to the service, depending on the named
+// workers, e.g. a consumer on its cache: the dependencies are initialized and
+// started before the worker, and terminated after it. Workers are otherwise
+// kept in added order. Unknown, disabled, or circular dependencies fail Run.
+func (s *SVC) AddWorkerWithDeps(name string, w Worker, deps ...string) {
+	s.addWorker(name, w, func() { s.workerDeps[name] = deps })
+}
+
+// orderWorkers sorts the workers so that they come after their dependencies,
+// keeping the added order otherwise.
+func (s *SVC) orderWorkers() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	ordered := make([]string, 0, len(s.workersAdded))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("circular worker dependency: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range s.workerDeps[name] {
+			if _, exists := s.workers[dep]; !exists {
+				return fmt.Errorf("worker %s depends on unknown or disabled worker %s", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		ordered = append(ordered, name)
+		return nil
+	}
+
+	for _, name := range s.workersAdded {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	s.workersAdded = ordered
+
+	return nil
+}
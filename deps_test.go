@@ -0,0 +1,62 @@
+package svc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAddWorkerWithDeps(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var inits, terminations []string
+	worker := func(name string) Worker {
+		return &WorkerMock{
+			InitFunc:      func(*zap.Logger) error { inits = append(inits, name); return nil },
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { terminations = append(terminations, name); return nil },
+		}
+	}
+	s.AddWorkerWithDeps("http", worker("http"), "cache", "db")
+	s.AddWorkerWithDeps("cache", worker("cache"), "db")
+	s.AddWorker("metrics", worker("metrics"))
+	s.AddWorker("db", worker("db"))
+
+	require.NoError(t, s.RunE())
+	assert.Equal(t, []string{"db", "cache", "http", "metrics"}, inits)
+	assert.Equal(t, []string{"metrics", "http", "cache", "db"}, terminations)
+}
+
+func TestAddWorkerWithDepsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		wantErr string
+	}{
+		{
+			name:    "unknown",
+			deps:    map[string][]string{"a": {"unknown"}},
+			wantErr: "worker a depends on unknown or disabled worker unknown",
+		},
+		{
+			name:    "circular",
+			deps:    map[string][]string{"a": {"b"}, "b": {"a"}},
+			wantErr: "circular worker dependency: a -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0")
+			require.NoError(t, err)
+			for _, name := range []string{"a", "b"} {
+				s.AddWorkerWithDeps(name, &WorkerMock{}, tc.deps[name]...)
+			}
+
+			require.EqualError(t, s.RunE(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package svc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+)
+
+const diagnoseFlag = "diagnose"
+
+// WithDiagnoseFlag is an option that makes Run print the diagnostics report to
+// stdout and return without running any worker when the binary is started
+// with the `--diagnose` command-line flag, e.g. to validate images in CI.
+func WithDiagnoseFlag() Option {
+	return func(s *SVC) error {
+		_, s.diagnose = lookupArg(os.Args[1:], diagnoseFlag)
+
+		return nil
+	}
+}
+
+// Diagnose writes a report of the resolved service configuration to w: service
+// metadata, runtime and cgroup limits, applied options, workers, and routes.
+func (s *SVC) Diagnose(w io.Writer) error {
+	disabled, err := s.disabledWorkers()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(tw, format+"\n", args...)
+	}
+
+	p("Service:\t%s", s.Name)
+	p("Version:\t%s", s.Version)
+	if s.role != "" {
+		p("Role:\t%s", s.role)
+	}
+	p("Go version:\t%s", runtime.Version())
+	p("GOMAXPROCS:\t%d (%d CPUs)", runtime.GOMAXPROCS(0), runtime.NumCPU())
+	if quota, ok := cgroupCPUQuota(); ok {
+		p("CPU quota:\t%.2f", quota)
+	} else {
+		p("CPU quota:\tunlimited")
+	}
+	if limit, ok := cgroupMemoryLimit(); ok {
+		p("Memory limit:\t%d bytes", limit)
+	} else {
+		p("Memory limit:\tunlimited")
+	}
+	wait, grace := s.terminationPeriods()
+	p("Termination wait period:\t%s", wait)
+	p("Termination grace period:\t%s", grace)
+	p("Options:\t%s", strings.Join(s.options, ", "))
+
+	p("\nWorkers:")
+	for _, name := range s.workersAdded {
+		state := "enabled"
+		if disabled[name] {
+			state = "disabled"
+		}
+		p("  %s\t%s\t%s", name, state, strings.Join(workerInterfaces(s.workers[name]), ", "))
+		if info, ok := s.describeWorker(name); ok {
+			for _, field := range [][2]string{
+				{"description", info.Description},
+				{"owner", info.Owner},
+				{"dependencies", strings.Join(info.Dependencies, ", ")},
+				{"endpoints", strings.Join(info.Endpoints, ", ")},
+			} {
+				if field[1] != "" {
+					p("    %s:\t%s", field[0], field[1])
+				}
+			}
+		}
+	}
+
+	p("\nRoutes:")
+	patterns := make([]string, 0, len(s.routes))
+	for pattern := range s.routes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		p("  %s\t%s", pattern, s.routes[pattern])
+	}
+	if err := s.Validate(); err != nil {
+		p("\nErrors:\n%s", err)
+	}
+
+	return tw.Flush()
+}
+
+// diagnoseAndExit prints the diagnostics report if requested and reports
+// whether it did.
+func (s *SVC) diagnoseAndExit() bool {
+	if !s.diagnose {
+		return false
+	}
+	if err := s.Diagnose(os.Stdout); err != nil {
+		s.logger.Error("Could not diagnose service", zap.Error(err))
+	}
+	return true
+}
+
+// workerInterfaces lists the optional interfaces the worker implements.
+func workerInterfaces(w Worker) []string {
+	var interfaces []string
+	impl := unwrapWorker(w)
+	if _, ok := impl.(WorkerCtx); ok {
+		interfaces = append(interfaces, "WorkerCtx")
+	}
+	if _, ok := impl.(Healther); ok {
+		interfaces = append(interfaces, "Healther")
+	}
+	if _, ok := impl.(HealthChecker); ok {
+		interfaces = append(interfaces, "HealthChecker")
+	}
+	if _, ok := impl.(Aliver); ok {
+		interfaces = append(interfaces, "Aliver")
+	}
+	if _, ok := impl.(Starter); ok {
+		interfaces = append(interfaces, "Starter")
+	}
+	if _, ok := impl.(Describer); ok {
+		interfaces = append(interfaces, "Describer")
+	}
+	if _, ok := impl.(Gatherer); ok {
+		interfaces = append(interfaces, "Gatherer")
+	}
+	return interfaces
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// optionName returns the name of the function that created the option, e.g.
+// svc.WithHealthz.
+func optionName(o Option) string {
+	f := runtime.FuncForPC(reflect.ValueOf(o).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	return closureSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,105 @@
+package svc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithWorkersDisabled("disabled-worker"))
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{})
+	s.AddWorker("disabled-worker", &WorkerMock{})
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Diagnose(&buf))
+
+	out := buf.String()
+	assert.Regexp(t, `Service:\s+dummy-service\n`, out)
+	assert.Regexp(t, `Options:\s+svc.WithHealthz, svc.WithWorkersDisabled\n`, out)
+	assert.Regexp(t, `dummy-worker\s+enabled\s+Healther, Aliver`, out)
+	assert.Regexp(t, `disabled-worker\s+disabled`, out)
+	assert.Regexp(t, `/ready\s+WithHealthz`, out)
+}
+
+type describerMock struct {
+	WorkerMock
+	info WorkerInfo
+}
+
+func (w *describerMock) Describe() WorkerInfo {
+	return w.info
+}
+
+func TestDiagnoseDescribedWorkers(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	healthy := func() error { return nil }
+	s.AddWorker("db", &WorkerMock{HealthyFunc: healthy})
+	s.AddWorkerWithDeps("api", &describerMock{WorkerMock: WorkerMock{HealthyFunc: healthy}, info: WorkerInfo{
+		Description:  "Public REST API",
+		Owner:        "team-rides",
+		Dependencies: []string{"postgres"},
+		Endpoints:    []string{":8080/api"},
+	}}, "db")
+
+	var buf bytes.Buffer
+	require.NoError(t, s.Diagnose(&buf))
+
+	out := buf.String()
+	assert.Regexp(t, `api\s+enabled\s+Healther, Aliver, Describer\n`, out)
+	assert.Regexp(t, `description:\s+Public REST API\n`, out)
+	assert.Regexp(t, `owner:\s+team-rides\n`, out)
+	assert.Regexp(t, `dependencies:\s+postgres, db\n`, out)
+	assert.Regexp(t, `endpoints:\s+:8080/api\n`, out)
+
+	statuses := s.Admin().WorkerStatus()
+	require.Len(t, statuses, 3)
+	assert.False(t, statuses[0].Described)
+	assert.True(t, statuses[1].Described)
+	assert.Equal(t, "team-rides", statuses[1].Info.Owner)
+}
+
+func TestWithDiagnoseFlag(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"dummy", "--diagnose"}
+
+	s, err := New("dummy-service", "v0.0.0", WithDiagnoseFlag())
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{}) // Would panic if initialized.
+
+	s.Run()
+}
+
+func TestCgroupLimits(t *testing.T) {
+	root := cgroupRoot
+	defer func() { cgroupRoot = root }()
+	cgroupRoot = t.TempDir()
+
+	_, ok := cgroupCPUQuota()
+	assert.False(t, ok)
+	_, ok = cgroupMemoryLimit()
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("150000 100000\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("536870912\n"), 0o600))
+	quota, ok := cgroupCPUQuota()
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, quota)
+	limit, ok := cgroupMemoryLimit()
+	assert.True(t, ok)
+	assert.Equal(t, int64(536870912), limit)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("max 100000\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("max\n"), 0o600))
+	_, ok = cgroupCPUQuota()
+	assert.False(t, ok)
+	_, ok = cgroupMemoryLimit()
+	assert.False(t, ok)
+}
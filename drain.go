@@ -0,0 +1,48 @@
+package svc
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// terminatingKey is the reason the service is held not ready once the shutdown
+// started.
+const terminatingKey = "terminating"
+
+// Drainer defines a worker that can drain before being terminated: stop
+// accepting new work, e.g. stop consuming from a queue, while finishing the
+// work in flight. Drain is called once the service reported not ready for the
+// termination wait period, and should return once the work in flight is
+// finished, or ctx, bounded by the termination grace period, is done.
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// drainWorkers drains the workers implementing Drainer concurrently, and waits
+// for them.
+func (s *SVC) drainWorkers(ctx context.Context) {
+	if !s.workersRan {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, name := range s.workersInitialized {
+		d, ok := unwrapWorker(s.workers[name]).(Drainer)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, d Drainer) {
+			defer wg.Done()
+			s.logger.Info("Draining worker", zap.String("worker", name))
+			if err := d.Drain(ctx); err != nil {
+				s.logger.Error("Drained with error", zap.String("worker", name), zap.Error(err))
+				s.recordEvent(EventWorkerDrainFailed, name, err)
+				return
+			}
+			s.recordEvent(EventWorkerDrained, name, nil)
+		}(name, d)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,110 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type drainerMock struct {
+	*WorkerMock
+	drainFunc func(ctx context.Context) error
+}
+
+func (w drainerMock) Drain(ctx context.Context) error {
+	return w.drainFunc(ctx)
+}
+
+func TestDrainWorkers(t *testing.T) {
+	tests := []struct {
+		name          string
+		drainErr      error
+		expectedEvent EventType
+	}{
+		{
+			name:          "drained",
+			expectedEvent: EventWorkerDrained,
+		},
+		{
+			name:          "drain failure",
+			drainErr:      errors.New("dummy error"),
+			expectedEvent: EventWorkerDrainFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithTerminationWaitPeriod(10*time.Millisecond))
+			require.NoError(t, err)
+
+			var mu sync.Mutex
+			var steps []string
+			step := func(name string) {
+				mu.Lock()
+				defer mu.Unlock()
+				steps = append(steps, name)
+			}
+			done := make(chan struct{})
+			s.AddWorker("consumer", drainerMock{
+				WorkerMock: &WorkerMock{
+					InitFunc: func(*zap.Logger) error { return nil },
+					RunFunc: func() error {
+						go s.Shutdown()
+						<-done
+						return nil
+					},
+					TerminateFunc: func() error {
+						step("terminate")
+						close(done)
+						return nil
+					},
+				},
+				drainFunc: func(ctx context.Context) error {
+					res, _ := s.checkHealth(SelfHealthName, s.self)
+					assert.Equal(t, HealthCritical, res.Status)
+					_, hasDeadline := ctx.Deadline()
+					assert.True(t, hasDeadline)
+					step("drain")
+					return tc.drainErr
+				},
+			})
+
+			require.NoError(t, s.RunE())
+			assert.Equal(t, []string{"drain", "terminate"}, steps)
+			var types []EventType
+			for _, e := range s.Events() {
+				if e.Worker == "consumer" {
+					types = append(types, e.Type)
+				}
+			}
+			assert.Contains(t, types, tc.expectedEvent)
+		})
+	}
+}
+
+func TestDrainWorkersNotRun(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	s.AddWorker("consumer", drainerMock{
+		WorkerMock: &WorkerMock{
+			InitFunc:      func(*zap.Logger) error { return nil },
+			TerminateFunc: func() error { return nil },
+		},
+		drainFunc: func(context.Context) error {
+			t.Error("drained a worker that never ran")
+			return nil
+		},
+	})
+	s.AddWorker("db", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return errors.New("dummy error") },
+	})
+
+	require.Error(t, s.RunE())
+}
@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// envDefaults defines the well-known environment variables platform teams can
+// tune the service with fleet-wide. Explicit options take precedence.
+type envDefaults struct {
+	HTTPPort               string        `env:"SVC_HTTP_PORT" validate:"omitempty,numeric"`
+	LogLevel               string        `env:"SVC_LOG_LEVEL" validate:"omitempty,oneof=debug info warn error dpanic panic fatal"`
+	TerminationGracePeriod time.Duration `env:"SVC_TERMINATION_GRACE_PERIOD" validate:"gte=0"`
+	HealthzEnabled         bool          `env:"SVC_HEALTHZ_ENABLED"`
+}
+
+// applyEnvDefaultsBefore applies the defaults that options override by being
+// applied after.
+func (s *SVC) applyEnvDefaultsBefore(cfg envDefaults) {
+	if cfg.TerminationGracePeriod > 0 {
+		s.TerminationGracePeriod = cfg.TerminationGracePeriod
+	}
+}
+
+// applyEnvDefaultsAfter applies the defaults the options did not set: the log
+// level unless a logger option chose one, and the internal server and health
+// probes unless already added.
+func (s *SVC) applyEnvDefaultsAfter(cfg envDefaults) error {
+	if cfg.LogLevel != "" && !s.logLevelSet {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			return err
+		}
+		s.atom.SetLevel(level)
+	}
+	if cfg.HTTPPort != "" {
+		_, hasHTTP := s.workers[internalHTTPServerName]
+		_, hasMultiplexed := s.workers[internalMultiplexedServerName]
+		if !hasHTTP && !hasMultiplexed {
+			if err := WithHTTPServer(cfg.HTTPPort)(s); err != nil {
+				return err
+			}
+			s.options = append(s.options, "svc.WithHTTPServer (SVC_HTTP_PORT)")
+		}
+	}
+	if cfg.HealthzEnabled {
+		if _, exists := s.routes["/live"]; !exists {
+			if err := WithHealthz()(s); err != nil {
+				return err
+			}
+			s.options = append(s.options, "svc.WithHealthz (SVC_HEALTHZ_ENABLED)")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package svc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	tests := []struct {
+		name                   string
+		env                    map[string]string
+		opts                   []Option
+		expectedError          bool
+		expectedLevel          zapcore.Level
+		expectedGracePeriod    time.Duration
+		expectedHTTPServerPort string
+		expectedHealthz        bool
+	}{
+		{
+			name:                "no environment",
+			expectedLevel:       zapcore.DebugLevel,
+			expectedGracePeriod: defaultTerminationGracePeriod,
+		},
+		{
+			name: "environment defaults",
+			env: map[string]string{
+				"SVC_HTTP_PORT":                "8081",
+				"SVC_LOG_LEVEL":                "error",
+				"SVC_TERMINATION_GRACE_PERIOD": "30s",
+				"SVC_HEALTHZ_ENABLED":          "true",
+			},
+			expectedLevel:          zapcore.ErrorLevel,
+			expectedGracePeriod:    30 * time.Second,
+			expectedHTTPServerPort: "8081",
+			expectedHealthz:        true,
+		},
+		{
+			name: "explicit options take precedence",
+			env: map[string]string{
+				"SVC_HTTP_PORT":                "8081",
+				"SVC_LOG_LEVEL":                "debug",
+				"SVC_TERMINATION_GRACE_PERIOD": "30s",
+			},
+			opts: []Option{
+				WithHTTPServer("9090"),
+				WithConsoleLogger(zapcore.WarnLevel),
+				WithTerminationGracePeriod(5 * time.Second),
+			},
+			expectedLevel:          zapcore.WarnLevel,
+			expectedGracePeriod:    5 * time.Second,
+			expectedHTTPServerPort: "9090",
+		},
+		{
+			name:          "invalid log level",
+			env:           map[string]string{"SVC_LOG_LEVEL": "verbose"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid port",
+			env:           map[string]string{"SVC_HTTP_PORT": "http"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			s, err := New("dummy-service", "v0.0.0", tc.opts...)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLevel, s.atom.Level())
+			require.Equal(t, tc.expectedGracePeriod, s.TerminationGracePeriod)
+
+			w, ok := s.workers[internalHTTPServerName]
+			require.Equal(t, tc.expectedHTTPServerPort != "", ok)
+			if ok {
+				require.Equal(t, ":"+tc.expectedHTTPServerPort, w.(*httpServer).httpServer.Addr)
+			}
+			_, ok = s.routes["/live"]
+			require.Equal(t, tc.expectedHealthz, ok)
+		})
+	}
+}
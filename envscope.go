@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/caarlos0/env/v6"
+	"go.uber.org/zap"
+)
+
+// reservedEnvPrefix is the prefix of the service's own environment variables,
+// see envDefaults.
+const reservedEnvPrefix = "SVC_"
+
+// ScopedEnv defines a worker's configuration namespace: the environment
+// variables with a given prefix, looked up without it, so that workers don't
+// read each other's configuration.
+type ScopedEnv struct {
+	prefix string
+	env    map[string]string
+}
+
+// EnvScope returns the scope of the environment variables with the prefix,
+// e.g. "CONSUMER_", as set when called. Hand it to a worker's Init with
+// AddWorkerEnv.
+func EnvScope(prefix string) *ScopedEnv {
+	vars := map[string]string{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if key, ok := strings.CutPrefix(k, prefix); ok && key != "" {
+			vars[key] = v
+		}
+	}
+	return &ScopedEnv{prefix: prefix, env: vars}
+}
+
+// Prefix returns the scope's prefix.
+func (e *ScopedEnv) Prefix() string {
+	return e.prefix
+}
+
+// Lookup returns the value of the variable with the prefix, e.g. "BROKERS" for
+// CONSUMER_BROKERS, and whether it is set.
+func (e *ScopedEnv) Lookup(key string) (string, bool) {
+	v, ok := e.env[key]
+	return v, ok
+}
+
+// Load is a shortcut for LoadWithParsers with empty custom parsers.
+func (e *ScopedEnv) Load(config interface{}) error {
+	return e.LoadWithParsers(config, nil)
+}
+
+// LoadWithParsers parses the scope's variables into the struct like
+// LoadFromEnvWithParsers, with the `env` tags naming the variables without the
+// prefix, e.g. `env:"BROKERS"` for CONSUMER_BROKERS.
+func (e *ScopedEnv) LoadWithParsers(config interface{}, parsers map[reflect.Type]env.ParserFunc) error {
+	err := loadFromEnv(config, parsers, env.Options{Environment: e.env})
+	if err != nil {
+		return fmt.Errorf("%s*: %w", e.prefix, err)
+	}
+	return nil
+}
+
+// WorkerEnv defines a SVC worker whose Init is passed its configuration scope,
+// see AddWorkerEnv.
+type WorkerEnv interface {
+	Init(logger *zap.Logger, env *ScopedEnv) error
+	Run() error
+	Terminate() error
+}
+
+// AddWorkerEnv adds a named worker to the service, passing the scope to its
+// Init, e.g. EnvScope("CONSUMER_"). Scopes overlapping each other, e.g.
+// "CONSUMER_" and "CONSUMER_DLQ_", or the service's own SVC_ variables fail
+// Run, as a worker would read the other's configuration.
+func (s *SVC) AddWorkerEnv(name string, w WorkerEnv, scope *ScopedEnv) {
+	s.addWorker(name, &envWorker{w: w, scope: scope}, func() { s.workerEnvScopes[name] = scope })
+}
+
+// checkEnvScopes checks the enabled workers' scopes don't overlap.
+func (s *SVC) checkEnvScopes() error {
+	var names []string
+	for _, name := range s.workersAdded {
+		if _, scoped := s.workerEnvScopes[name]; scoped {
+			names = append(names, name)
+		}
+	}
+	for i, name := range names {
+		prefix := s.workerEnvScopes[name].prefix
+		if envPrefixesOverlap(prefix, reservedEnvPrefix) {
+			return fmt.Errorf("worker %s environment scope %q overlaps the service's %q", name, prefix, reservedEnvPrefix)
+		}
+		for _, other := range names[:i] {
+			otherPrefix := s.workerEnvScopes[other].prefix
+			if envPrefixesOverlap(prefix, otherPrefix) {
+				return fmt.Errorf("workers %s and %s have overlapping environment scopes %q and %q", other, name, otherPrefix, prefix)
+			}
+		}
+	}
+	return nil
+}
+
+func envPrefixesOverlap(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+var (
+	_ Worker    = (*envWorker)(nil)
+	_ unwrapper = (*envWorker)(nil)
+)
+
+// envWorker adapts a WorkerEnv to the Worker interface.
+type envWorker struct {
+	w     WorkerEnv
+	scope *ScopedEnv
+}
+
+// Init implements the Worker interface.
+func (w *envWorker) Init(logger *zap.Logger) error {
+	return w.w.Init(logger, w.scope)
+}
+
+// Run implements the Worker interface.
+func (w *envWorker) Run() error {
+	return w.w.Run()
+}
+
+// Terminate implements the Worker interface.
+func (w *envWorker) Terminate() error {
+	return w.w.Terminate()
+}
+
+func (w *envWorker) unwrapWorker() interface{} {
+	return w.w
+}
@@ -0,0 +1,131 @@
+package svc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type envWorkerMock struct {
+	*WorkerMock
+	initFunc func(*zap.Logger, *ScopedEnv) error
+}
+
+func (w envWorkerMock) Init(logger *zap.Logger, env *ScopedEnv) error {
+	return w.initFunc(logger, env)
+}
+
+func TestEnvScope(t *testing.T) {
+	t.Setenv("CONSUMER_BROKERS", "kafka:9092")
+	t.Setenv("CONSUMER_TIMEOUT", "5s")
+	t.Setenv("PRODUCER_BROKERS", "other:9092")
+
+	scope := EnvScope("CONSUMER_")
+	assert.Equal(t, "CONSUMER_", scope.Prefix())
+	v, ok := scope.Lookup("BROKERS")
+	assert.True(t, ok)
+	assert.Equal(t, "kafka:9092", v)
+	_, ok = scope.Lookup("CONSUMER_BROKERS")
+	assert.False(t, ok)
+
+	var cfg struct {
+		Brokers string        `env:"BROKERS" validate:"required"`
+		Timeout time.Duration `env:"TIMEOUT"`
+		Group   string        `env:"GROUP" envDefault:"default"`
+	}
+	require.NoError(t, scope.Load(&cfg))
+	assert.Equal(t, "kafka:9092", cfg.Brokers)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, "default", cfg.Group)
+
+	var invalid struct {
+		Topic string `env:"TOPIC,required"`
+	}
+	err := scope.Load(&invalid)
+	require.Error(t, err)
+	var cerr *ConfigError
+	require.ErrorAs(t, err, &cerr)
+	assert.Contains(t, err.Error(), "CONSUMER_*")
+}
+
+func TestAddWorkerEnv(t *testing.T) {
+	t.Setenv("CONSUMER_BROKERS", "kafka:9092")
+
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var brokers string
+	s.AddWorkerEnv("consumer", envWorkerMock{
+		WorkerMock: &WorkerMock{
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { return nil },
+		},
+		initFunc: func(_ *zap.Logger, env *ScopedEnv) error {
+			brokers, _ = env.Lookup("BROKERS")
+			return nil
+		},
+	}, EnvScope("CONSUMER_"))
+
+	require.NoError(t, s.RunE())
+	assert.Equal(t, "kafka:9092", brokers)
+}
+
+func TestAddWorkerEnvCollisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		disabled []string
+		wantErr  string
+	}{
+		{
+			name:     "distinct",
+			prefixes: []string{"CONSUMER_", "PRODUCER_"},
+		},
+		{
+			name:     "same",
+			prefixes: []string{"CONSUMER_", "CONSUMER_"},
+			wantErr:  `workers a and b have overlapping environment scopes "CONSUMER_" and "CONSUMER_"`,
+		},
+		{
+			name:     "nested",
+			prefixes: []string{"CONSUMER_DLQ_", "CONSUMER_"},
+			wantErr:  `workers a and b have overlapping environment scopes "CONSUMER_DLQ_" and "CONSUMER_"`,
+		},
+		{
+			name:     "disabled",
+			prefixes: []string{"CONSUMER_", "CONSUMER_"},
+			disabled: []string{"b"},
+		},
+		{
+			name:     "reserved",
+			prefixes: []string{"SVC_CONSUMER_"},
+			wantErr:  `worker a environment scope "SVC_CONSUMER_" overlaps the service's "SVC_"`,
+		},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithWorkersDisabled(tc.disabled...))
+			require.NoError(t, err)
+			for i, prefix := range tc.prefixes {
+				s.AddWorkerEnv(string(rune('a'+i)), envWorkerMock{
+					WorkerMock: &WorkerMock{
+						RunFunc:       func() error { return nil },
+						TerminateFunc: func() error { return nil },
+					},
+					initFunc: func(*zap.Logger, *ScopedEnv) error { return nil },
+				}, EnvScope(prefix))
+			}
+
+			err = s.RunE()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package svc
+
+import "fmt"
+
+// ErrorPolicy defines how the service handles a worker's Run failing.
+type ErrorPolicy int
+
+// Error policies.
+const (
+	// ShutdownGracefully terminates the remaining workers, then Run exits the
+	// process with status 1, see WithExitCodes. The default.
+	ShutdownGracefully ErrorPolicy = iota
+	// Exit makes Run exit the process with status 1 right away, without
+	// terminating the remaining workers. RunE shuts down gracefully.
+	Exit
+	// Continue logs the failure and keeps running the remaining workers.
+	Continue
+)
+
+// WithErrorPolicy is an option that sets how the service handles a worker's
+// Run failing. Defaults to ShutdownGracefully.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(s *SVC) error {
+		switch policy {
+		case ShutdownGracefully, Exit, Continue:
+		default:
+			return fmt.Errorf("unknown error policy %d", policy)
+		}
+		s.errorPolicy = policy
+
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InitError is returned by RunE when a worker fails to initialize. No worker
+// was run, and the initialized workers were terminated.
+type InitError struct {
+	// Worker is the worker that failed to initialize.
+	Worker string
+	// Initialized are the workers initialized before, in order.
+	Initialized []string
+	// NotInitialized are the workers that were never initialized, in order.
+	NotInitialized []string
+	Err            error
+}
+
+// Error implements the error interface.
+func (e *InitError) Error() string {
+	return fmt.Sprintf("worker %s failed to initialize (initialized: [%s], not initialized: [%s]): %v",
+		e.Worker, strings.Join(e.Initialized, ", "), strings.Join(e.NotInitialized, ", "), e.Err)
+}
+
+// Unwrap returns the worker's error.
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
+
+// initRetryErrorsKept is the number of last attempts' errors kept in an
+// InitRetryExhaustedError.
+const initRetryErrorsKept = 5
+
+// InitRetryExhaustedError is returned when a worker added with
+// AddWorkerWithInitRetry still fails to initialize once its retries are
+// exhausted.
+type InitRetryExhaustedError struct {
+	Worker   string
+	Attempts int
+	Elapsed  time.Duration
+	// Errors are the errors of the last attempts, oldest first.
+	Errors []error
+}
+
+// Error implements the error interface.
+func (e *InitRetryExhaustedError) Error() string {
+	first := e.Attempts - len(e.Errors) + 1
+	msgs := make([]string, 0, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("#%d: %s", first+i, err))
+	}
+	return fmt.Sprintf("init of worker %s failed after %d attempts in %s, last errors: %s",
+		e.Worker, e.Attempts, e.Elapsed.Round(time.Millisecond), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the last attempts.
+func (e *InitRetryExhaustedError) Unwrap() []error {
+	return e.Errors
+}
@@ -0,0 +1,167 @@
+package svc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultEventLogSize = 100
+
+// EventType defines the type of a lifecycle event.
+type EventType string
+
+// Lifecycle event types.
+const (
+	EventServiceStarting   EventType = "service_starting"
+	EventServiceStarted    EventType = "service_started"
+	EventServiceStopping   EventType = "service_stopping"
+	EventServiceReloaded   EventType = "service_reloaded"
+	EventWorkerInitialized EventType = "worker_initialized"
+	EventWorkerInitFailed  EventType = "worker_init_failed"
+	EventWorkerStarted     EventType = "worker_started"
+	EventWorkerFinished    EventType = "worker_finished"
+	EventWorkerFailed      EventType = "worker_failed"
+	EventWorkerDrained     EventType = "worker_drained"
+	EventWorkerDrainFailed EventType = "worker_drain_failed"
+	EventWorkerTerminated  EventType = "worker_terminated"
+	EventWorkerTermFailed  EventType = "worker_terminate_failed"
+	EventWorkerHealthy     EventType = "worker_healthy"
+	EventWorkerUnhealthy   EventType = "worker_unhealthy"
+	EventWorkerAlive       EventType = "worker_alive"
+	EventWorkerNotAlive    EventType = "worker_not_alive"
+)
+
+// Event defines a lifecycle event of the service or one of its workers.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+	Worker  string    `json:"worker,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// eventLog keeps the last lifecycle events in a ring buffer.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+
+	// health keeps the last known health state per probe and worker to only
+	// record changes.
+	health map[string]bool
+
+	listeners []func(Event)
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{
+		events: make([]Event, size),
+		health: map[string]bool{},
+	}
+}
+
+func (l *eventLog) record(e Event) {
+	l.mu.Lock()
+	if len(l.events) > 0 {
+		l.events[l.next] = e
+		l.next = (l.next + 1) % len(l.events)
+		if l.next == 0 {
+			l.full = true
+		}
+	}
+	listeners := l.listeners
+	l.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(e)
+	}
+}
+
+// subscribe registers a function called for every recorded event. It must not
+// block.
+func (l *eventLog) subscribe(fn func(Event)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+func (l *eventLog) list() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Event{}, l.events[:l.next]...)
+	}
+	return append(append([]Event{}, l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// healthChanged stores the health state and reports whether it changed. The
+// first known state of a healthy worker is not considered a change.
+func (l *eventLog) healthChanged(key string, ok bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prev, known := l.health[key]
+	l.health[key] = ok
+	if !known {
+		return !ok
+	}
+	return prev != ok
+}
+
+// WithEventLogSize is an option that sets how many lifecycle events are kept
+// in memory. Defaults to 100.
+func WithEventLogSize(size int) Option {
+	return func(s *SVC) error {
+		if size < 0 {
+			return fmt.Errorf("invalid event log size %d", size)
+		}
+		events := newEventLog(size)
+		events.listeners = s.events.listeners
+		s.events = events
+
+		return nil
+	}
+}
+
+// WithEventsHandler is an option that exposes the last lifecycle events via the
+// `/debug/events` HTTP route.
+func WithEventsHandler() Option {
+	return func(s *SVC) error {
+		s.handle("WithEventsHandler", "/debug/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeResponse(w, http.StatusOK, EventsResponse{Events: s.Events()})
+		}))
+
+		return nil
+	}
+}
+
+// Events returns the last lifecycle events, oldest first.
+func (s *SVC) Events() []Event {
+	return s.events.list()
+}
+
+func (s *SVC) recordEvent(typ EventType, worker string, err error) {
+	e := Event{Time: time.Now(), Type: typ, Worker: worker}
+	if err != nil {
+		e.Message = err.Error()
+	}
+	s.events.record(e)
+	s.workerStates.record(e)
+	if s.runReport != nil {
+		s.runReport.record(e)
+	}
+	s.runHooks(e)
+}
+
+// recordHealth records an event when a worker's probe result changed.
+func (s *SVC) recordHealth(okType, failedType EventType, worker string, err error) {
+	if !s.events.healthChanged(string(okType)+"/"+worker, err == nil) {
+		return
+	}
+	if err != nil {
+		s.recordEvent(failedType, worker, err)
+		return
+	}
+	s.recordEvent(okType, worker, nil)
+}
@@ -0,0 +1,88 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEventLog(t *testing.T) {
+	l := newEventLog(3)
+	assert.Empty(t, l.list())
+
+	for i := 0; i < 5; i++ {
+		l.record(Event{Message: fmt.Sprint(i)})
+	}
+
+	var messages []string
+	for _, e := range l.list() {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, messages)
+}
+
+func TestEvents(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithEventsHandler())
+	require.NoError(t, err)
+
+	s.AddWorker("ok", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	s.AddWorker("failing", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return fmt.Errorf("boom") },
+	})
+	s.Run()
+
+	var types []EventType
+	for _, e := range s.Events() {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{
+		EventServiceStarting,
+		EventWorkerInitialized,
+		EventWorkerInitFailed,
+		EventServiceStopping,
+		EventWorkerTerminated,
+	}, types)
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/events", nil))
+	var body struct {
+		Events []Event `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Events, 5)
+	assert.Equal(t, "boom", body.Events[2].Message)
+}
+
+func TestHealthEvents(t *testing.T) {
+	var healthErr error
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{HealthyFunc: func() error { return healthErr }})
+
+	probe := func() {
+		s.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	}
+	probe()
+	healthErr = fmt.Errorf("not ready")
+	probe()
+	probe()
+	healthErr = nil
+	probe()
+
+	var types []EventType
+	for _, e := range s.Events() {
+		assert.WithinDuration(t, time.Now(), e.Time, time.Minute)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{EventWorkerUnhealthy, EventWorkerHealthy}, types)
+}
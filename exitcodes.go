@@ -0,0 +1,63 @@
+package svc
+
+import "fmt"
+
+// ShutdownCause defines why the service shut down.
+type ShutdownCause int
+
+// Shutdown causes.
+const (
+	// ShutdownCompleted means all workers finished.
+	ShutdownCompleted ShutdownCause = iota
+	// ShutdownSignal means a shutdown signal was caught, or Shutdown called.
+	ShutdownSignal
+	// ShutdownWorkerFailure means a worker's Run failed.
+	ShutdownWorkerFailure
+	// ShutdownStartupFailure means the service failed to start, e.g. a worker
+	// failed to initialize or the configuration is invalid.
+	ShutdownStartupFailure
+	// ShutdownCrashLoop means the service refused to start as it is
+	// crash-looping, see CrashLoopFailFast.
+	ShutdownCrashLoop
+)
+
+// String implements the fmt.Stringer interface.
+func (c ShutdownCause) String() string {
+	switch c {
+	case ShutdownCompleted:
+		return "completed"
+	case ShutdownSignal:
+		return "signal"
+	case ShutdownWorkerFailure:
+		return "worker failure"
+	case ShutdownStartupFailure:
+		return "startup failure"
+	case ShutdownCrashLoop:
+		return "crash loop"
+	}
+	return fmt.Sprintf("ShutdownCause(%d)", int(c))
+}
+
+// WithExitCodes is an option that sets the status Run exits the process with
+// once shut down, by cause. Causes without a code return from Run instead. By
+// default, Run exits with status 1 on ShutdownWorkerFailure and
+// ShutdownCrashLoop only.
+func WithExitCodes(codes map[ShutdownCause]int) Option {
+	return func(s *SVC) error {
+		for cause, code := range codes {
+			if code < 0 || code > 125 {
+				return fmt.Errorf("exit code %d of %s out of range 0-125", code, cause)
+			}
+			s.exitCodes[cause] = code
+		}
+
+		return nil
+	}
+}
+
+// Err returns why the service failed once Run or RunE returned, e.g. an
+// *InitError, or nil if it shut down on a signal or once all workers
+// finished.
+func (s *SVC) Err() error {
+	return s.runErr
+}
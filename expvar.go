@@ -0,0 +1,55 @@
+package svc
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	expvarOnce    sync.Once
+	expvarService atomic.Pointer[SVC]
+)
+
+// WithExpvar is an option that mirrors the service's life-cycle and health
+// into the "svc" expvar variable, served with the other variables on
+// /debug/vars, for environments still scraping it. As expvar variables are
+// global, the variable reports the last service created with this option.
+func WithExpvar() Option {
+	return func(s *SVC) error {
+		expvarOnce.Do(func() {
+			expvar.Publish("svc", expvar.Func(func() interface{} {
+				if s := expvarService.Load(); s != nil {
+					return s.expvars()
+				}
+				return nil
+			}))
+		})
+		expvarService.Store(s)
+		s.handle("WithExpvar", "/debug/vars", expvar.Handler())
+
+		return nil
+	}
+}
+
+// expvars returns the values mirrored into the expvar variable.
+func (s *SVC) expvars() ServiceStatus {
+	workers := map[string]ServiceWorkerStatus{}
+	for _, st := range s.Admin().WorkerStatus() {
+		w := ServiceWorkerStatus{Running: st.Running}
+		if st.Checked {
+			w.Health = st.Health.Status.String()
+			w.Detail = st.Health.Detail
+		}
+		workers[st.Name] = w
+	}
+	return ServiceStatus{
+		Name:          s.Name,
+		Version:       s.Version,
+		InstanceID:    s.instanceID,
+		State:         s.lifecycleState(),
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+		Workers:       workers,
+	}
+}
@@ -0,0 +1,34 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithExpvar(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithExpvar())
+	require.NoError(t, err)
+	s.AddWorker("db", &WorkerMock{HealthyFunc: func() error { return errors.New("connection refused") }})
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var vars struct {
+		SVC ServiceStatus `json:"svc"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	assert.Equal(t, "dummy-service", vars.SVC.Name)
+	assert.Equal(t, stateStarting, vars.SVC.State)
+	assert.Equal(t, ServiceWorkerStatus{Health: "critical", Detail: "connection refused"}, vars.SVC.Workers["db"])
+
+	s.self.setInitialized()
+	assert.Equal(t, stateRunning, s.expvars().State)
+	s.beginTermination()
+	assert.Equal(t, stateTerminating, s.expvars().State)
+}
@@ -0,0 +1,247 @@
+package svc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultGopsAddr        = "127.0.0.1:0"
+	gopsCPUProfileDuration = 30 * time.Second
+	gopsTraceDuration      = 5 * time.Second
+)
+
+// Signals of the gops protocol, sent by the gops CLI as a connection's first
+// byte.
+const (
+	gopsStackTrace   = 0x1
+	gopsGC           = 0x2
+	gopsMemStats     = 0x3
+	gopsVersion      = 0x4
+	gopsHeapProfile  = 0x5
+	gopsCPUProfile   = 0x6
+	gopsStats        = 0x7
+	gopsTrace        = 0x8
+	gopsSetGCPercent = 0x10
+)
+
+// GopsOption defines WithGopsAgent's option type.
+type GopsOption func(*gopsAgent)
+
+// GopsAddr sets the address the agent listens on. Defaults to a random port on
+// localhost.
+func GopsAddr(addr string) GopsOption {
+	return func(g *gopsAgent) {
+		g.addr = addr
+	}
+}
+
+// GopsConfigDir sets the directory the agent's port file is written to, where
+// the gops CLI looks for it. Defaults to $GOPS_CONFIG_DIR, or gops in the
+// user's configuration directory.
+func GopsConfigDir(dir string) GopsOption {
+	return func(g *gopsAgent) {
+		g.configDir = dir
+	}
+}
+
+// WithGopsAgent is an option that runs an agent of the gops diagnostics tool
+// (github.com/google/gops), managed as the "internal-gops-agent" worker, to
+// inspect the running process with e.g. `gops stack <pid>`, `gops memstats
+// <pid>` or `gops gc <pid>` without custom endpoints. The agent implements the
+// gops protocol, thus does not depend on the gops module, and listens on
+// localhost only by default.
+func WithGopsAgent(opts ...GopsOption) Option {
+	return func(s *SVC) error {
+		g := &gopsAgent{addr: defaultGopsAddr}
+		for _, o := range opts {
+			o(g)
+		}
+		if g.configDir == "" {
+			dir, err := gopsConfigDir()
+			if err != nil {
+				return fmt.Errorf("gops config dir: %w", err)
+			}
+			g.configDir = dir
+		}
+		s.AddWorker("internal-gops-agent", g)
+
+		return nil
+	}
+}
+
+// gopsConfigDir returns the directory the gops CLI looks for port files in.
+func gopsConfigDir() (string, error) {
+	if dir := os.Getenv("GOPS_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gops"), nil
+}
+
+var _ Worker = (*gopsAgent)(nil)
+
+// gopsAgent defines the internal worker serving the gops protocol.
+type gopsAgent struct {
+	addr      string
+	configDir string
+	logger    *zap.Logger
+	listener  net.Listener
+	portFile  string
+}
+
+// Init implements the Worker interface. It listens and writes the port file,
+// so the process is inspectable once initialized.
+func (g *gopsAgent) Init(logger *zap.Logger) error {
+	g.logger = logger
+
+	l, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	g.portFile = filepath.Join(g.configDir, strconv.Itoa(os.Getpid()))
+	if err := os.MkdirAll(g.configDir, 0o755); err != nil {
+		_ = l.Close()
+		return err
+	}
+	if err := os.WriteFile(g.portFile, []byte(strconv.Itoa(port)), 0o644); err != nil {
+		_ = l.Close()
+		return err
+	}
+	g.listener = l
+	g.logger.Info("gops agent listening", zap.String("addr", l.Addr().String()))
+	return nil
+}
+
+// Run implements the Worker interface.
+func (g *gopsAgent) Run() error {
+	for {
+		conn, err := g.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go g.serve(conn)
+	}
+}
+
+// Terminate implements the Worker interface. It removes the port file.
+func (g *gopsAgent) Terminate() error {
+	err := g.listener.Close()
+	if rmErr := os.Remove(g.portFile); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		err = errors.Join(err, rmErr)
+	}
+	return err
+}
+
+// serve handles a connection's command, identified by its first byte.
+func (g *gopsAgent) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	sig, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+	if err := gopsHandle(conn, r, sig); err != nil {
+		g.logger.Warn("gops command failed", zap.Uint8("signal", sig), zap.Error(err))
+	}
+}
+
+func gopsHandle(w io.Writer, r io.ByteReader, sig byte) error {
+	switch sig {
+	case gopsStackTrace:
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	case gopsGC:
+		runtime.GC()
+		_, err := io.WriteString(w, "ok")
+		return err
+	case gopsMemStats:
+		return gopsWriteMemStats(w)
+	case gopsVersion:
+		_, err := fmt.Fprintf(w, "%s\n", runtime.Version())
+		return err
+	case gopsHeapProfile:
+		return pprof.WriteHeapProfile(w)
+	case gopsCPUProfile:
+		if err := pprof.StartCPUProfile(w); err != nil {
+			return err
+		}
+		time.Sleep(gopsCPUProfileDuration)
+		pprof.StopCPUProfile()
+		return nil
+	case gopsStats:
+		_, err := fmt.Fprintf(w, "goroutines: %d\nOS threads: %d\nGOMAXPROCS: %d\nnum CPU: %d\n",
+			runtime.NumGoroutine(), pprof.Lookup("threadcreate").Count(), runtime.GOMAXPROCS(0), runtime.NumCPU())
+		return err
+	case gopsTrace:
+		if err := trace.Start(w); err != nil {
+			return err
+		}
+		time.Sleep(gopsTraceDuration)
+		trace.Stop()
+		return nil
+	case gopsSetGCPercent:
+		percent, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		previous := debug.SetGCPercent(int(percent))
+		_, err = fmt.Fprintf(w, "New GC percent set to %d. Previous value was %d.\n", percent, previous)
+		return err
+	}
+	return fmt.Errorf("unknown signal %#x", sig)
+}
+
+func gopsWriteMemStats(w io.Writer) error {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	lastGC := "-"
+	if m.LastGC != 0 {
+		lastGC = time.Unix(0, int64(m.LastGC)).String()
+	}
+	_, err := fmt.Fprintf(w, "alloc: %d bytes\n"+
+		"total-alloc: %d bytes\n"+
+		"sys: %d bytes\n"+
+		"mallocs: %d\n"+
+		"frees: %d\n"+
+		"heap-alloc: %d bytes\n"+
+		"heap-sys: %d bytes\n"+
+		"heap-idle: %d bytes\n"+
+		"heap-in-use: %d bytes\n"+
+		"heap-released: %d bytes\n"+
+		"heap-objects: %d\n"+
+		"stack-in-use: %d bytes\n"+
+		"stack-sys: %d bytes\n"+
+		"next-gc: when heap-alloc >= %d bytes\n"+
+		"last-gc: %s\n"+
+		"gc-pause-total: %s\n"+
+		"num-gc: %d\n"+
+		"num-forced-gc: %d\n"+
+		"gc-cpu-fraction: %v\n",
+		m.Alloc, m.TotalAlloc, m.Sys, m.Mallocs, m.Frees,
+		m.HeapAlloc, m.HeapSys, m.HeapIdle, m.HeapInuse, m.HeapReleased, m.HeapObjects,
+		m.StackInuse, m.StackSys, m.NextGC, lastGC, time.Duration(m.PauseTotalNs),
+		m.NumGC, m.NumForcedGC, m.GCCPUFraction)
+	return err
+}
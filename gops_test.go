@@ -0,0 +1,58 @@
+package svc
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithGopsAgent(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New("dummy-service", "v0.0.0", WithGopsAgent(GopsConfigDir(dir)))
+	require.NoError(t, err)
+	g := s.workers["internal-gops-agent"].(*gopsAgent)
+	require.NoError(t, g.Init(zap.NewNop()))
+	done := make(chan error, 1)
+	go func() { done <- g.Run() }()
+
+	portFile := filepath.Join(dir, strconv.Itoa(os.Getpid()))
+	port, err := os.ReadFile(portFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		signal   byte
+		expected string
+	}{
+		{name: "version", signal: gopsVersion, expected: runtime.Version()},
+		{name: "gc", signal: gopsGC, expected: "ok"},
+		{name: "stack", signal: gopsStackTrace, expected: "goroutine"},
+		{name: "memstats", signal: gopsMemStats, expected: "heap-alloc:"},
+		{name: "stats", signal: gopsStats, expected: "goroutines:"},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", "127.0.0.1:"+string(port))
+			require.NoError(t, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte{tc.signal})
+			require.NoError(t, err)
+			out, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			assert.Contains(t, string(out), tc.expected)
+		})
+	}
+
+	require.NoError(t, g.Terminate())
+	require.NoError(t, <-done)
+	assert.NoFileExists(t, portFile)
+}
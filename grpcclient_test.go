@@ -0,0 +1,65 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dummyConn struct {
+	target string
+	state  string
+	closed bool
+}
+
+func (c *dummyConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestGRPCClient(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	dial := func(ctx context.Context, target string) (*dummyConn, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("no dial deadline")
+		}
+		return &dummyConn{target: target, state: "READY"}, nil
+	}
+
+	var conn *dummyConn
+	conn, err = GRPCClient(s, "users:443", dial, GRPCClientHealthCheck(func() error {
+		if conn.state != "READY" {
+			return errors.New(conn.state)
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "users:443", conn.target)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.grpcClientDials.WithLabelValues("users:443", "success")))
+
+	w, ok := s.workers["grpc-client-users:443"]
+	require.True(t, ok)
+	require.NoError(t, w.(Healther).Healthy())
+	conn.state = "TRANSIENT_FAILURE"
+	require.EqualError(t, w.(Healther).Healthy(), "TRANSIENT_FAILURE")
+
+	go func() { _ = w.Run() }()
+	require.NoError(t, w.Terminate())
+	assert.True(t, conn.closed)
+}
+
+func TestGRPCClientDialError(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	_, err = GRPCClient(s, "users:443", func(context.Context, string) (*dummyConn, error) {
+		return nil, errors.New("dummy error")
+	}, GRPCClientName("users"))
+	require.EqualError(t, err, "dummy error")
+	assert.Empty(t, s.workers)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.grpcClientDials.WithLabelValues("users:443", "error")))
+}
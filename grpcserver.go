@@ -0,0 +1,180 @@
+package svc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultGRPCServerName           = "grpc-server"
+	defaultGRPCServerHealthInterval = 5 * time.Second
+	defaultGRPCServerStopTimeout    = 10 * time.Second
+)
+
+// GRPCServer defines the methods of a *grpc.Server the worker manages, so the
+// service does not depend on gRPC.
+type GRPCServer interface {
+	Serve(lis net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// GRPCServerOption defines AddGRPCServer's option type.
+type GRPCServerOption func(*grpcServer)
+
+// GRPCServerName sets the name of the worker. Defaults to "grpc-server".
+func GRPCServerName(name string) GRPCServerOption {
+	return func(g *grpcServer) {
+		g.name = name
+	}
+}
+
+// GRPCServerHealth ties the standard gRPC health checking protocol to the
+// service's readiness: setServing is called whenever the ready checks start or
+// stop failing, and with false on termination, e.g.
+//
+//	hs := health.NewServer()
+//	healthpb.RegisterHealthServer(srv, hs)
+//	svc.GRPCServerHealth(func(serving bool) {
+//		status := healthpb.HealthCheckResponse_NOT_SERVING
+//		if serving {
+//			status = healthpb.HealthCheckResponse_SERVING
+//		}
+//		hs.SetServingStatus("", status)
+//	})
+func GRPCServerHealth(setServing func(serving bool)) GRPCServerOption {
+	return func(g *grpcServer) {
+		g.setServing = setServing
+	}
+}
+
+// GRPCServerHealthInterval sets how often the readiness is reported to the
+// health service. Defaults to 5s.
+func GRPCServerHealthInterval(d time.Duration) GRPCServerOption {
+	return func(g *grpcServer) {
+		g.healthInterval = d
+	}
+}
+
+// GRPCServerStopTimeout bounds the graceful stop, waiting for in-flight RPCs,
+// after which the server is stopped forcefully. Defaults to 10s.
+func GRPCServerStopTimeout(d time.Duration) GRPCServerOption {
+	return func(g *grpcServer) {
+		g.stopTimeout = d
+	}
+}
+
+// AddGRPCServer adds a worker serving srv, e.g. a *grpc.Server with the
+// services registered, on port. It stops the server gracefully on
+// termination and, with GRPCServerHealth, reports the service's readiness via
+// the gRPC health checking protocol. Reflection is enabled as usual with
+// reflection.Register(srv), and mutual TLS by passing
+// grpc.Creds(credentials.NewTLS(s.MutualTLSConfig())) to grpc.NewServer.
+func (s *SVC) AddGRPCServer(port string, srv GRPCServer, opts ...GRPCServerOption) {
+	g := &grpcServer{
+		s:              s,
+		name:           defaultGRPCServerName,
+		addr:           net.JoinHostPort("", port),
+		srv:            srv,
+		healthInterval: defaultGRPCServerHealthInterval,
+		stopTimeout:    defaultGRPCServerStopTimeout,
+		done:           make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	s.AddWorker(g.name, g)
+}
+
+var _ Worker = (*grpcServer)(nil)
+
+// grpcServer defines the worker serving a gRPC server.
+type grpcServer struct {
+	s              *SVC
+	logger         *zap.Logger
+	name           string
+	addr           string
+	srv            GRPCServer
+	setServing     func(serving bool)
+	healthInterval time.Duration
+	stopTimeout    time.Duration
+	done           chan struct{}
+	doneOnce       sync.Once
+	healthWG       sync.WaitGroup
+}
+
+// Init implements the Worker interface.
+func (g *grpcServer) Init(logger *zap.Logger) error {
+	g.logger = logger
+	// Started here rather than in Run, which may race with Terminate.
+	if g.setServing != nil {
+		g.healthWG.Add(1)
+		go g.reportHealth()
+	}
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (g *grpcServer) Run() error {
+	lis, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	g.logger.Info("Listening and serving gRPC", zap.String("address", lis.Addr().String()))
+	// Serve returns nil once stopped.
+	return g.srv.Serve(lis)
+}
+
+// Terminate implements the Worker interface.
+func (g *grpcServer) Terminate() error {
+	g.doneOnce.Do(func() { close(g.done) })
+	if g.setServing != nil {
+		g.healthWG.Wait()
+		g.setServing(false)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		g.srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(g.stopTimeout):
+		g.logger.Warn("gRPC server graceful stop timed out, stopping", zap.Duration("timeout", g.stopTimeout))
+		g.srv.Stop()
+	}
+
+	return nil
+}
+
+// reportHealth reports the service's readiness to the health service until
+// the worker is terminated.
+func (g *grpcServer) reportHealth() {
+	defer g.healthWG.Done()
+	ticker := time.NewTicker(g.healthInterval)
+	defer ticker.Stop()
+	var last *bool
+	for {
+		serving := true
+		for _, c := range g.s.readyChecks() {
+			if c.Status == HealthCritical {
+				serving = false
+			}
+		}
+		if last == nil || *last != serving {
+			g.setServing(serving)
+			last = &serving
+		}
+
+		select {
+		case <-ticker.C:
+		case <-g.done:
+			return
+		}
+	}
+}
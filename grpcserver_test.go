@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type grpcServerMock struct {
+	stop           chan struct{}
+	stopOnce       sync.Once
+	blockGraceful  bool
+	gracefulCalled bool
+	stopCalled     bool
+}
+
+func (m *grpcServerMock) Serve(lis net.Listener) error {
+	defer lis.Close()
+	<-m.stop
+	return nil
+}
+
+func (m *grpcServerMock) GracefulStop() {
+	m.gracefulCalled = true
+	if m.blockGraceful {
+		// Like in-flight RPCs never finishing, until stopped.
+		<-m.stop
+		return
+	}
+	// Like grpc.Server, stopping again is a no-op.
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+func (m *grpcServerMock) Stop() {
+	m.stopCalled = true
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+func TestAddGRPCServer(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var statuses []bool
+	srv := &grpcServerMock{stop: make(chan struct{})}
+	s.AddGRPCServer("0", srv, GRPCServerHealth(func(serving bool) {
+		mu.Lock()
+		statuses = append(statuses, serving)
+		mu.Unlock()
+	}), GRPCServerHealthInterval(time.Millisecond))
+
+	var healthErr error
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(statuses) == 1
+			}, 3*time.Second, time.Millisecond)
+			mu.Lock()
+			healthErr = errors.New("dummy error")
+			mu.Unlock()
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(statuses) == 2
+			}, 3*time.Second, time.Millisecond)
+			s.Shutdown()
+			return nil
+		},
+		TerminateFunc: func() error { return nil },
+		HealthyFunc: func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			return healthErr
+		},
+	})
+	require.NoError(t, s.RunE())
+
+	assert.True(t, srv.gracefulCalled)
+	assert.False(t, srv.stopCalled)
+	assert.Equal(t, []bool{true, false, false}, statuses)
+}
+
+func TestGRPCServerStopTimeout(t *testing.T) {
+	srv := &grpcServerMock{stop: make(chan struct{}), blockGraceful: true}
+	g := &grpcServer{srv: srv, logger: zap.NewNop(), stopTimeout: time.Millisecond, done: make(chan struct{})}
+	require.NoError(t, g.Terminate())
+	assert.True(t, srv.stopCalled)
+}
+
+func TestGRPCServerTerminateTwice(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	srv := &grpcServerMock{stop: make(chan struct{})}
+	g := &grpcServer{
+		s:              s,
+		srv:            srv,
+		logger:         zap.NewNop(),
+		setServing:     func(bool) {},
+		healthInterval: time.Millisecond,
+		stopTimeout:    time.Second,
+		done:           make(chan struct{}),
+	}
+	require.NoError(t, g.Init(zap.NewNop()))
+	require.NoError(t, g.Terminate())
+	require.NotPanics(t, func() { require.NoError(t, g.Terminate()) })
+	assert.True(t, srv.gracefulCalled)
+}
@@ -0,0 +1,366 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HealthStatus defines the severity of a health check result.
+type HealthStatus int
+
+// Health check statuses. Only HealthCritical flips readiness.
+const (
+	HealthOK HealthStatus = iota
+	HealthWarn
+	HealthCritical
+)
+
+// String implements the fmt.Stringer interface.
+func (st HealthStatus) String() string {
+	switch st {
+	case HealthOK:
+		return "ok"
+	case HealthWarn:
+		return "warn"
+	case HealthCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (st HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(st.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (st *HealthStatus) UnmarshalText(text []byte) error {
+	for _, candidate := range []HealthStatus{HealthOK, HealthWarn, HealthCritical} {
+		if candidate.String() == string(text) {
+			*st = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown health status %q", text)
+}
+
+// HealthResult defines the result of a worker's health check.
+type HealthResult struct {
+	Status HealthStatus `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	// Action optionally suggests on-call engineers what to do about a failing
+	// check, e.g. "check the database credentials secret".
+	Action string `json:"action,omitempty"`
+	// RetryAfter optionally hints when a failing check is expected to
+	// recover, e.g. once a connection is retried. See Progresser.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+	CheckedAt  time.Time     `json:"checked_at"`
+}
+
+// HealthChecker defines a worker that can report its healthz status with a
+// severity. It takes precedence over Healther: warnings are reported without
+// flipping readiness, criticals flip it.
+type HealthChecker interface {
+	CheckHealth() HealthResult
+}
+
+// HealthResultFromError converts a Healther's result: nil is OK, any error is
+// critical.
+func HealthResultFromError(err error) HealthResult {
+	if err == nil {
+		return HealthResult{Status: HealthOK, CheckedAt: time.Now()}
+	}
+	return HealthResult{Status: HealthCritical, Detail: err.Error(), CheckedAt: time.Now()}
+}
+
+// checkHealth runs the worker's health check, if the worker implements one,
+// through the cache if configured.
+func (s *SVC) checkHealth(name string, w interface{}) (HealthResult, bool) {
+	var check func() HealthResult
+	switch hw := unwrapWorker(w).(type) {
+	case HealthChecker:
+		check = func() HealthResult {
+			res := hw.CheckHealth()
+			if res.CheckedAt.IsZero() {
+				res.CheckedAt = time.Now()
+			}
+			return res
+		}
+	case Healther:
+		check = func() HealthResult { return HealthResultFromError(hw.Healthy()) }
+	default:
+		return HealthResult{}, false
+	}
+	var res HealthResult
+	if s.healthCache != nil {
+		res = s.healthCache.check("ready/"+name, check)
+	} else {
+		res = check()
+	}
+	s.metrics.workerHealth.WithLabelValues(name).Set(float64(res.Status))
+	return res, true
+}
+
+// checkAlive runs the worker's liveness check, if the worker implements one,
+// through the cache if configured.
+func (s *SVC) checkAlive(name string, w interface{}) (bool, error) {
+	aw, ok := unwrapWorker(w).(Aliver)
+	if !ok {
+		return false, nil
+	}
+	if s.healthCache == nil {
+		return true, aw.Alive()
+	}
+	res := s.healthCache.check("live/"+name, func() HealthResult { return HealthResultFromError(aw.Alive()) })
+	if res.Status == HealthOK {
+		return true, nil
+	}
+	return true, errors.New(res.Detail)
+}
+
+// readyCheck defines the result of a worker's ready check.
+type readyCheck struct {
+	Worker string
+	HealthResult
+	// FailingSince is when the check started to fail, zero if it is OK.
+	FailingSince time.Time
+	Duration     time.Duration
+	// Progress is the worker's warm-up progress while failing, if reported.
+	Progress *float64
+}
+
+// readyChecks runs the ready checks of the workers and the framework itself,
+// sorted by worker, recording health changes.
+func (s *SVC) readyChecks() []readyCheck {
+	checks := map[string]interface{}{SelfHealthName: s.self}
+	for n, w := range s.workers {
+		checks[n] = w
+	}
+
+	// Run concurrently, for the probe to take as long as the slowest check,
+	// bounded by the check timeout, rather than all of them.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []readyCheck
+	for n, w := range checks {
+		wg.Add(1)
+		go func(n string, w interface{}) {
+			defer wg.Done()
+			start := time.Now()
+			res, ok := s.checkHealth(n, w)
+			if !ok {
+				return
+			}
+			var err error
+			if res.Status == HealthCritical {
+				err = errors.New(res.Detail)
+			}
+			var progress *float64
+			if res.Status != HealthOK {
+				progress, res.RetryAfter = s.recoveryHint(w, res)
+			}
+			s.recordHealth(EventWorkerHealthy, EventWorkerUnhealthy, n, err)
+			c := readyCheck{
+				Worker:       n,
+				HealthResult: res,
+				FailingSince: s.failingSince(n, res),
+				Duration:     time.Since(start),
+				Progress:     progress,
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, c)
+		}(n, w)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Worker < results[j].Worker })
+	return results
+}
+
+// failingSince tracks since when the worker's check has been failing.
+func (s *SVC) failingSince(name string, res HealthResult) time.Time {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	if res.Status == HealthOK {
+		delete(s.healthFailing, name)
+		return time.Time{}
+	}
+	since, ok := s.healthFailing[name]
+	if !ok {
+		since = res.CheckedAt
+		s.healthFailing[name] = since
+	}
+	return since
+}
+
+// readyDetailsHandler serves a human-friendly breakdown of the failing ready
+// checks for on-call triage.
+func (s *SVC) readyDetailsHandler(w http.ResponseWriter, _ *http.Request) {
+	res := ReadyDetailsResponse{Ready: true, Failing: []ReadyDetail{}}
+	now := time.Now()
+	checks := s.readyChecks()
+	for _, c := range checks {
+		if c.Status == HealthOK {
+			continue
+		}
+		if c.Status == HealthCritical {
+			res.Ready = false
+		}
+		detail := ReadyDetail{
+			Worker:       c.Worker,
+			Status:       c.Status,
+			Detail:       c.Detail,
+			Action:       c.Action,
+			FailingSince: c.FailingSince,
+			FailingFor:   now.Sub(c.FailingSince).Round(time.Second).String(),
+			Progress:     c.Progress,
+		}
+		if c.RetryAfter > 0 {
+			recoveryAt := now.Add(c.RetryAfter).UTC()
+			detail.ExpectedRecoveryAt = &recoveryAt
+		}
+		res.Failing = append(res.Failing, detail)
+	}
+
+	code := http.StatusOK
+	if !res.Ready {
+		res.RetryAfterSeconds = setRetryAfter(w, s.readyRetryAfter(checks))
+		code = http.StatusServiceUnavailable
+	}
+	writeResponse(w, code, res)
+}
+
+// liveHandler serves the liveness probe: 200 if all workers are alive, 503
+// otherwise, both with a LiveResponse body.
+func (s *SVC) liveHandler(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+	res := LiveResponse{Results: []ProbeResult{}, InstanceID: s.instanceID}
+	var errs []error
+	// Run concurrently, as the ready checks.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for n, w := range s.workers {
+		wg.Add(1)
+		go func(n string, w Worker) {
+			defer wg.Done()
+			checkStart := time.Now()
+			ok, err := s.checkAlive(n, w)
+			if !ok {
+				return
+			}
+			s.recordHealth(EventWorkerAlive, EventWorkerNotAlive, n, err)
+			result := ProbeResult{Worker: n, Status: HealthOK, DurationSeconds: time.Since(checkStart).Seconds()}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				s.metrics.probeFailures.WithLabelValues("live", n).Inc()
+				errs = append(errs, fmt.Errorf("worker %s: %s", n, err))
+				result.Status, result.Detail = HealthCritical, err.Error()
+			}
+			res.Results = append(res.Results, result)
+		}(n, w)
+	}
+	wg.Wait()
+	sort.Slice(res.Results, func(i, j int) bool { return res.Results[i].Worker < res.Results[j].Worker })
+	res.DurationSeconds = time.Since(start).Seconds()
+
+	if len(errs) == 0 {
+		res.Status = "Still Alive!"
+		writeResponse(w, http.StatusOK, res)
+		return
+	}
+	s.logger.Warn("liveliness probe failed", zap.Errors("errors", errs))
+	for _, err := range errs {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	sort.Strings(res.Errors)
+	writeResponse(w, http.StatusServiceUnavailable, res)
+}
+
+// startupHandler serves the startup probe: 503 until all workers are
+// initialized and those implementing Starter completed their warm-up.
+func (s *SVC) startupHandler(w http.ResponseWriter, _ *http.Request) {
+	var errs []string
+	var retryAfter time.Duration
+	if !s.self.isInitialized() {
+		errs = append(errs, "workers not initialized")
+	}
+	for n, wk := range s.workers {
+		if sw, ok := unwrapWorker(wk).(Starter); ok {
+			if err := sw.Started(); err != nil {
+				s.metrics.probeFailures.WithLabelValues("startup", n).Inc()
+				errs = append(errs, fmt.Sprintf("worker %s: %s", n, err))
+				if _, eta := s.recoveryHint(wk, HealthResult{}); eta > retryAfter {
+					retryAfter = eta
+				}
+			}
+		}
+	}
+	if len(errs) == 0 {
+		writeResponse(w, http.StatusOK, StartupResponse{Status: "started", InstanceID: s.instanceID})
+		return
+	}
+	sort.Strings(errs)
+	if retryAfter == 0 {
+		retryAfter = s.retryAfter
+	}
+	writeResponse(w, http.StatusServiceUnavailable, StartupResponse{
+		Errors:            errs,
+		RetryAfterSeconds: setRetryAfter(w, retryAfter),
+		InstanceID:        s.instanceID,
+	})
+}
+
+// readyHandler serves the ready probe: 200 if no check is critical, 503
+// otherwise, both with a ReadyResponse body.
+func (s *SVC) readyHandler(w http.ResponseWriter, _ *http.Request) {
+	start := time.Now()
+	res := ReadyResponse{
+		Status:     ReadyStatusReady,
+		Checked:    []string{},
+		Results:    []ProbeResult{},
+		Timestamp:  start.UTC(),
+		InstanceID: s.instanceID,
+	}
+	checks := s.readyChecks()
+	for _, c := range checks {
+		res.Checked = append(res.Checked, c.Worker)
+		res.Results = append(res.Results, ProbeResult{
+			Worker:            c.Worker,
+			Status:            c.Status,
+			Detail:            c.Detail,
+			Progress:          c.Progress,
+			RetryAfterSeconds: c.RetryAfter.Seconds(),
+			DurationSeconds:   c.Duration.Seconds(),
+		})
+		switch c.Status {
+		case HealthWarn:
+			res.Warnings = append(res.Warnings, fmt.Sprintf("worker %s: %s", c.Worker, c.Detail))
+		case HealthCritical:
+			res.Errors = append(res.Errors, fmt.Sprintf("worker %s: %s", c.Worker, c.Detail))
+			s.metrics.probeFailures.WithLabelValues("ready", c.Worker).Inc()
+		}
+	}
+	if len(res.Warnings) > 0 {
+		s.logger.Warn("Ready check degraded", zap.Strings("warnings", res.Warnings))
+	}
+
+	res.DurationSeconds = time.Since(start).Seconds()
+
+	code := http.StatusOK
+	if len(res.Errors) > 0 {
+		s.logger.Warn("Ready check failed", zap.Strings("errors", res.Errors))
+		res.Status = ReadyStatusNotReady
+		res.RetryAfterSeconds = setRetryAfter(w, s.readyRetryAfter(checks))
+		code = http.StatusServiceUnavailable
+	}
+	writeResponse(w, code, res)
+}
@@ -0,0 +1,197 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type healthCheckerMock struct {
+	WorkerMock
+	result HealthResult
+}
+
+func (w *healthCheckerMock) CheckHealth() HealthResult {
+	return w.result
+}
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenStatus      HealthStatus
+		expectedCode     int
+		expectedResponse ReadyResponse
+	}{
+		{
+			name:             "should return status ok when ok",
+			givenStatus:      HealthOK,
+			expectedCode:     200,
+			expectedResponse: ReadyResponse{Status: ReadyStatusReady},
+		},
+		{
+			name:         "should return status ok with warnings when warn",
+			givenStatus:  HealthWarn,
+			expectedCode: 200,
+			expectedResponse: ReadyResponse{
+				Status:   ReadyStatusReady,
+				Warnings: []string{"worker dummy-worker: degraded"},
+			},
+		},
+		{
+			name:         "should return status not available when critical",
+			givenStatus:  HealthCritical,
+			expectedCode: 503,
+			expectedResponse: ReadyResponse{
+				Status:            ReadyStatusNotReady,
+				Errors:            []string{"worker dummy-worker: degraded"},
+				RetryAfterSeconds: 5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithHealthz())
+			require.NoError(t, err)
+
+			s.AddWorker("dummy-worker", &healthCheckerMock{
+				result: HealthResult{Status: tc.givenStatus, Detail: "degraded"},
+			})
+
+			req := httptest.NewRequest("GET", "/ready", nil)
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var res ReadyResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.WithinDuration(t, time.Now(), res.Timestamp, time.Minute)
+			tc.expectedResponse.Checked = []string{"dummy-worker", SelfHealthName}
+			tc.expectedResponse.Timestamp = res.Timestamp
+			tc.expectedResponse.InstanceID = s.InstanceID()
+			tc.expectedResponse.Results = []ProbeResult{
+				{Worker: "dummy-worker", Status: tc.givenStatus, Detail: "degraded"},
+				{Worker: SelfHealthName, Status: HealthOK},
+			}
+			tc.expectedResponse.DurationSeconds = res.DurationSeconds
+			for i := range res.Results {
+				assert.GreaterOrEqual(t, res.DurationSeconds, res.Results[i].DurationSeconds)
+				res.Results[i].DurationSeconds = 0
+			}
+			assert.Equal(t, tc.expectedResponse, res)
+			assert.Equal(t, float64(tc.givenStatus),
+				testutil.ToFloat64(s.metrics.workerHealth.WithLabelValues("dummy-worker")))
+		})
+	}
+}
+
+func TestHealthStatusString(t *testing.T) {
+	assert.Equal(t, "ok", HealthOK.String())
+	assert.Equal(t, "warn", HealthWarn.String())
+	assert.Equal(t, "critical", HealthCritical.String())
+	assert.Equal(t, "unknown", HealthStatus(42).String())
+}
+
+func TestReadyDetails(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	failedAt := time.Now().Add(-time.Minute)
+	worker := &healthCheckerMock{result: HealthResult{
+		Status:    HealthCritical,
+		Detail:    "connection refused",
+		Action:    "check the database",
+		CheckedAt: failedAt,
+	}}
+	s.AddWorker("db", worker)
+	s.AddWorker("cache", &healthCheckerMock{result: HealthResult{Status: HealthOK}})
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready/details", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var body struct {
+		Ready   bool          `json:"ready"`
+		Failing []ReadyDetail `json:"failing"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.False(t, body.Ready)
+	require.Len(t, body.Failing, 1)
+	assert.Equal(t, "db", body.Failing[0].Worker)
+	assert.Equal(t, "check the database", body.Failing[0].Action)
+	assert.Equal(t, "1m0s", body.Failing[0].FailingFor)
+
+	// The failure start is kept while the check keeps failing.
+	worker.result.CheckedAt = time.Now()
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready/details", nil))
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "1m0s", body.Failing[0].FailingFor)
+
+	worker.result = HealthResult{Status: HealthOK}
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready/details", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ready": true, "failing": []}`, rec.Body.String())
+}
+
+type starterMock struct {
+	WorkerMock
+	err error
+}
+
+func (w *starterMock) Started() error {
+	return w.err
+}
+
+func TestStartupProbe(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithIDGenerator(func() string { return "dummy-instance" }), WithHealthz())
+	require.NoError(t, err)
+	worker := &starterMock{err: errors.New("warming up")}
+	s.AddWorker("cache", worker)
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/startup", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"errors": ["worker cache: warming up", "workers not initialized"], "retry_after_seconds": 5, "instance_id": "dummy-instance"}`, rec.Body.String())
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+
+	s.self.setInitialized()
+	worker.err = nil
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/startup", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "started", "instance_id": "dummy-instance"}`, rec.Body.String())
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.probeFailures.WithLabelValues("startup", "cache")))
+}
+
+func TestLiveProbe(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	s.AddWorker("db", &WorkerMock{AliveFunc: func() error { return errors.New("connection lost") }})
+	s.AddWorker("cache", &WorkerMock{AliveFunc: func() error { return nil }})
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var res LiveResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []string{"worker db: connection lost"}, res.Errors)
+	require.Len(t, res.Results, 2)
+	for i := range res.Results {
+		res.Results[i].DurationSeconds = 0
+	}
+	assert.Equal(t, []ProbeResult{
+		{Worker: "cache", Status: HealthOK},
+		{Worker: "db", Status: HealthCritical, Detail: "connection lost"},
+	}, res.Results)
+}
@@ -0,0 +1,121 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithHealthzCacheTTL is an option that caches each worker's health and
+// liveness check results for ttl, so that frequent probes, e.g. from the
+// kubelet and a service mesh, do not run every check on every request.
+// Concurrent probes share a single in-flight check per worker.
+func WithHealthzCacheTTL(ttl time.Duration) Option {
+	return func(s *SVC) error {
+		if ttl <= 0 {
+			return errors.New("healthz cache TTL must be positive")
+		}
+		s.healthCacheConfig().ttl = ttl
+
+		return nil
+	}
+}
+
+// WithHealthzCheckTimeout is an option that bounds how long the probes wait for
+// each worker's health or liveness check, so that a blocking check cannot hang
+// the probe endpoints: a check timing out fails. The check keeps running in
+// the background, and is not run again until it returned. The checks of a
+// probe run concurrently, so it responds within about one timeout.
+func WithHealthzCheckTimeout(d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return errors.New("healthz check timeout must be positive")
+		}
+		s.healthCacheConfig().timeout = d
+
+		return nil
+	}
+}
+
+func (s *SVC) healthCacheConfig() *healthCache {
+	if s.healthCache == nil {
+		s.healthCache = &healthCache{entries: map[string]*healthCacheEntry{}}
+	}
+	return s.healthCache
+}
+
+// healthCache caches the results of the health checks, running at most one
+// check per key at a time.
+type healthCache struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*healthCacheEntry
+}
+
+type healthCacheEntry struct {
+	res     HealthResult
+	expires time.Time
+	// call is closed once the check in flight, if any, returned.
+	call chan struct{}
+}
+
+// check returns the cached result of the check under key, running it if
+// expired, or joining the check in flight, waiting for it up to the timeout.
+func (c *healthCache) check(key string, fn func() HealthResult) HealthResult {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.call == nil && time.Now().Before(e.expires) {
+		defer c.mu.Unlock()
+		return e.res
+	}
+	if !ok {
+		e = &healthCacheEntry{}
+		c.entries[key] = e
+	}
+	call := e.call
+	if call == nil {
+		call = make(chan struct{})
+		e.call = call
+		go func() {
+			res := runCheck(fn)
+			c.mu.Lock()
+			e.res, e.expires, e.call = res, time.Now().Add(c.ttl), nil
+			c.mu.Unlock()
+			close(call)
+		}()
+	}
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-call:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return e.res
+	case <-timeout:
+		return HealthResult{
+			Status:    HealthCritical,
+			Detail:    fmt.Sprintf("check timed out after %s", c.timeout),
+			CheckedAt: time.Now(),
+		}
+	}
+}
+
+// runCheck runs the check, turning a panic into a critical result, as it runs
+// outside of the probe handler.
+func runCheck(fn func() HealthResult) (res HealthResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			res = HealthResult{Status: HealthCritical, Detail: fmt.Sprintf("check panicked: %v", p), CheckedAt: time.Now()}
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,126 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHealthzCacheTTL(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHealthzCacheTTL(50*time.Millisecond))
+	require.NoError(t, err)
+	var healthy, alive int32
+	s.AddWorker("dummy-worker", &WorkerMock{
+		HealthyFunc: func() error {
+			atomic.AddInt32(&healthy, 1)
+			return nil
+		},
+		AliveFunc: func() error {
+			atomic.AddInt32(&alive, 1)
+			return nil
+		},
+	})
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, probe("/live"))
+		probe("/ready")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&healthy))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&alive))
+
+	time.Sleep(60 * time.Millisecond)
+	probe("/live")
+	probe("/ready")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&healthy))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&alive))
+}
+
+func TestWithHealthzCheckTimeout(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHealthzCheckTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+	s.self.setInitialized()
+	var calls int32
+	blocked := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		HealthyFunc: func() error {
+			atomic.AddInt32(&calls, 1)
+			<-blocked
+			return nil
+		},
+		AliveFunc: func() error {
+			<-blocked
+			return nil
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "worker dummy-worker: check timed out after 20ms")
+
+		rec = httptest.NewRecorder()
+		s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "blocked check should not run again")
+
+	close(blocked)
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec.Code == http.StatusOK
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHealthzChecksConcurrent(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHealthzCheckTimeout(timeout))
+	require.NoError(t, err)
+	s.self.setInitialized()
+	blocked := make(chan struct{})
+	defer close(blocked)
+	for _, n := range []string{"dummy-worker-1", "dummy-worker-2"} {
+		s.AddWorker(n, &WorkerMock{
+			HealthyFunc: func() error {
+				<-blocked
+				return nil
+			},
+			AliveFunc: func() error {
+				<-blocked
+				return nil
+			},
+		})
+	}
+
+	for _, path := range []string{"/ready", "/live"} {
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, timeout, path)
+		assert.Less(t, elapsed, 2*timeout, path)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), "worker dummy-worker-1: check timed out", path)
+		assert.Contains(t, rec.Body.String(), "worker dummy-worker-2: check timed out", path)
+	}
+}
+
+func TestHealthzCacheOptionsInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithHealthzCacheTTL(0))
+	require.EqualError(t, err, "healthz cache TTL must be positive")
+	_, err = New("dummy-service", "v0.0.0", WithHealthzCheckTimeout(-time.Second))
+	require.EqualError(t, err, "healthz check timeout must be positive")
+}
@@ -0,0 +1,194 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHeapDumpLimitRatio  = 0.8
+	defaultHeapDumpInterval    = 5 * time.Second
+	defaultHeapDumpMinInterval = 10 * time.Minute
+	defaultHeapDumpTimeout     = 30 * time.Second
+)
+
+// HeapDumpStore defines where heap profiles are saved, e.g. a directory
+// mounted from a volume outliving the container, or an object store.
+type HeapDumpStore interface {
+	Save(ctx context.Context, key string, data []byte) error
+}
+
+// HeapDumpDir returns a HeapDumpStore saving heap profiles as files in dir.
+func HeapDumpDir(dir string) HeapDumpStore {
+	return heapDumpDir(dir)
+}
+
+type heapDumpDir string
+
+// Save implements the HeapDumpStore interface.
+func (d heapDumpDir) Save(_ context.Context, key string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(string(d), filepath.Base(key)), data, 0o640)
+}
+
+// HeapDumpOption defines WithHeapDumps' option type.
+type HeapDumpOption func(*heapDumper)
+
+// HeapDumpThreshold sets the memory usage in bytes beyond which the heap is
+// dumped. Defaults to 80% of the cgroup's memory limit.
+func HeapDumpThreshold(n uint64) HeapDumpOption {
+	return func(d *heapDumper) {
+		d.threshold = n
+	}
+}
+
+// HeapDumpInterval sets how often the memory usage is checked. Defaults to 5s.
+func HeapDumpInterval(interval time.Duration) HeapDumpOption {
+	return func(d *heapDumper) {
+		d.interval = interval
+	}
+}
+
+// HeapDumpMinInterval sets the minimum time between two heap dumps. Defaults
+// to 10m.
+func HeapDumpMinInterval(interval time.Duration) HeapDumpOption {
+	return func(d *heapDumper) {
+		d.minInterval = interval
+	}
+}
+
+// WithHeapDumps is an option that dumps a heap profile to store when the
+// process' memory usage, i.e. its RSS, crosses a threshold, so an OOM kill can
+// be analyzed afterwards. Dumps are rate limited, see HeapDumpMinInterval, and
+// counted in the svc_heap_dumps_total metric.
+func WithHeapDumps(store HeapDumpStore, opts ...HeapDumpOption) Option {
+	return func(s *SVC) error {
+		d := &heapDumper{
+			s:           s,
+			store:       store,
+			interval:    defaultHeapDumpInterval,
+			minInterval: defaultHeapDumpMinInterval,
+			usage:       memoryUsage,
+			done:        make(chan struct{}),
+		}
+		for _, o := range opts {
+			o(d)
+		}
+		if d.threshold == 0 {
+			limit, ok := cgroupMemoryLimit()
+			if !ok {
+				return errors.New("heap dumps require a threshold without cgroup memory limit")
+			}
+			d.threshold = uint64(float64(limit) * defaultHeapDumpLimitRatio)
+		}
+		if d.interval <= 0 {
+			return errors.New("heap dump interval must be positive")
+		}
+		s.AddWorker("internal-heap-dump", d)
+
+		return nil
+	}
+}
+
+var _ Worker = (*heapDumper)(nil)
+
+// heapDumper defines the internal worker dumping the heap.
+type heapDumper struct {
+	s           *SVC
+	logger      *zap.Logger
+	store       HeapDumpStore
+	threshold   uint64
+	interval    time.Duration
+	minInterval time.Duration
+	usage       func() uint64
+	done        chan struct{}
+
+	lastDump time.Time
+}
+
+// Init implements the Worker interface.
+func (d *heapDumper) Init(logger *zap.Logger) error {
+	d.logger = logger
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (d *heapDumper) Run() error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.check()
+		case <-d.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (d *heapDumper) Terminate() error {
+	close(d.done)
+
+	return nil
+}
+
+func (d *heapDumper) check() {
+	usage := d.usage()
+	if usage < d.threshold || (!d.lastDump.IsZero() && time.Since(d.lastDump) < d.minInterval) {
+		return
+	}
+	d.lastDump = time.Now()
+
+	key, err := d.dump()
+	if err != nil {
+		d.s.metrics.heapDumps.WithLabelValues("error").Inc()
+		d.logger.Error("Could not dump heap", zap.Uint64("memory_usage", usage), zap.Error(err))
+		return
+	}
+	d.s.metrics.heapDumps.WithLabelValues("success").Inc()
+	d.logger.Warn("Memory usage above threshold, heap dumped",
+		zap.Uint64("memory_usage", usage), zap.Uint64("threshold", d.threshold), zap.String("key", key))
+}
+
+func (d *heapDumper) dump() (string, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		return "", err
+	}
+	host, _ := os.Hostname()
+	key := fmt.Sprintf("%s-%s-%s.heap.pb.gz", d.s.Name, host, d.lastDump.UTC().Format("20060102T150405Z"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHeapDumpTimeout)
+	defer cancel()
+	return key, d.store.Save(ctx, key, buf.Bytes())
+}
+
+// memoryUsage returns the process' resident set size, or the memory obtained
+// from the OS by the Go runtime where it is unavailable.
+func memoryUsage() uint64 {
+	if b, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(b)); len(fields) > 1 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys
+}
@@ -0,0 +1,47 @@
+package svc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithHeapDumps(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New("dummy-service", "v0.0.0", WithHeapDumps(HeapDumpDir(dir), HeapDumpThreshold(100)))
+	require.NoError(t, err)
+	d := s.workers["internal-heap-dump"].(*heapDumper)
+	require.NoError(t, d.Init(zap.NewNop()))
+
+	usage := uint64(99)
+	d.usage = func() uint64 { return usage }
+	d.check()
+	usage = 100
+	d.check()
+	d.check() // rate limited
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Regexp(t, `^dummy-service-.+-\d{8}T\d{6}Z\.heap\.pb\.gz$`, files[0].Name())
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.metrics.heapDumps.WithLabelValues("success")))
+}
+
+func TestWithHeapDumpsThreshold(t *testing.T) {
+	root := cgroupRoot
+	defer func() { cgroupRoot = root }()
+	cgroupRoot = t.TempDir()
+
+	_, err := New("dummy-service", "v0.0.0", WithHeapDumps(HeapDumpDir(t.TempDir())))
+	require.EqualError(t, err, "heap dumps require a threshold without cgroup memory limit")
+
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("1000\n"), 0o600))
+	s, err := New("dummy-service", "v0.0.0", WithHeapDumps(HeapDumpDir(t.TempDir())))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(800), s.workers["internal-heap-dump"].(*heapDumper).threshold)
+}
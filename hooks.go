@@ -0,0 +1,111 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultHookTimeout = 10 * time.Second
+
+// Hook defines a callback run at a lifecycle milestone, receiving the event
+// marking it. Hooks run synchronously, in the order they were registered, and
+// their context is canceled after the hook timeout, 10s by default, see
+// WithHookTimeout, or once the termination grace period elapsed. A hook still
+// running then is no longer waited for. A failing hook is logged and does not
+// affect the service. Hooks must be registered before the service runs.
+type Hook func(ctx context.Context, e Event) error
+
+// WithHookTimeout is an option that sets how long each lifecycle hook may run.
+// Defaults to 10s.
+func WithHookTimeout(d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return errors.New("hook timeout must be positive")
+		}
+		s.hookTimeout = d
+
+		return nil
+	}
+}
+
+// OnStart registers a hook run once all workers are initialized, right before
+// they run, e.g. to register in service discovery.
+func (s *SVC) OnStart(h Hook) {
+	s.addHook(EventServiceStarted, h)
+}
+
+// OnWorkerInit registers a hook run after each worker is initialized.
+func (s *SVC) OnWorkerInit(h Hook) {
+	s.addHook(EventWorkerInitialized, h)
+}
+
+// OnShutdown registers a hook run when the shutdown starts, before terminating
+// the workers, e.g. to deregister from service discovery.
+func (s *SVC) OnShutdown(h Hook) {
+	s.addHook(EventServiceStopping, h)
+}
+
+// OnWorkerTerminated registers a hook run after each worker is terminated,
+// whether successfully or not, see Event.Type.
+func (s *SVC) OnWorkerTerminated(h Hook) {
+	s.addHook(EventWorkerTerminated, h)
+	s.addHook(EventWorkerTermFailed, h)
+}
+
+func (s *SVC) addHook(typ EventType, h Hook) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.started {
+		s.logger.Error("Hook added once the service started, ignoring it",
+			zap.String("event", string(typ)), zap.Error(ErrServiceStarted), zap.Stack("stacktrace"))
+		return
+	}
+	if s.hooks == nil {
+		s.hooks = map[EventType][]Hook{}
+	}
+	s.hooks[typ] = append(s.hooks[typ], h)
+}
+
+// runHooks runs the hooks registered for the event.
+func (s *SVC) runHooks(e Event) {
+	for i, h := range s.hooks[e.Type] {
+		if err := s.runHook(h, e); err != nil {
+			s.logger.Warn("Lifecycle hook failed",
+				zap.String("event", string(e.Type)),
+				zap.String("worker", e.Worker),
+				zap.Int("hook", i),
+				zap.Error(err))
+		}
+	}
+}
+
+// runHook runs the hook until it returns or its context is done, bounded by
+// the hook timeout and the termination grace period.
+func (s *SVC) runHook(h Hook, e Event) error {
+	deadline := time.Now().Add(s.hookTimeout)
+	if grace, ok := s.graceDeadline(); ok && grace.Before(deadline) {
+		deadline = grace
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errs <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errs <- h(ctx, e)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("not done in time: %w", ctx.Err())
+	}
+}
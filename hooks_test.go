@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHooks(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var called []string
+	record := func(name string) Hook {
+		return func(ctx context.Context, e Event) error {
+			require.NoError(t, ctx.Err())
+			mu.Lock()
+			defer mu.Unlock()
+			called = append(called, name+" "+string(e.Type)+" "+e.Worker)
+			return nil
+		}
+	}
+	s.OnStart(record("start"))
+	s.OnWorkerInit(record("init"))
+	s.OnShutdown(func(context.Context, Event) error { return errors.New("dummy error") })
+	s.OnShutdown(func(context.Context, Event) error { panic("dummy panic") })
+	s.OnShutdown(record("shutdown"))
+	s.OnWorkerTerminated(record("terminated"))
+
+	done := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			s.Shutdown()
+			<-done
+			return nil
+		},
+		TerminateFunc: func() error {
+			close(done)
+			return nil
+		},
+	})
+	s.Run()
+
+	assert.Equal(t, []string{
+		"init worker_initialized dummy-worker",
+		"start service_started ",
+		"shutdown service_stopping ",
+		"terminated worker_terminated dummy-worker",
+	}, called)
+}
+
+func TestHooksBounded(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0",
+		WithHookTimeout(20*time.Millisecond),
+		WithTerminationGracePeriod(50*time.Millisecond))
+	require.NoError(t, err)
+
+	block := make(chan struct{})
+	defer close(block)
+	// Not waited for beyond the hook timeout, even ignoring its context.
+	s.OnStart(func(context.Context, Event) error { <-block; return nil })
+	var remaining time.Duration
+	s.OnShutdown(func(ctx context.Context, _ Event) error {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return nil
+	})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			// Ignored once started.
+			s.OnShutdown(func(context.Context, Event) error { panic("late hook") })
+			return nil
+		},
+		TerminateFunc: func() error { return nil },
+	})
+
+	start := time.Now()
+	require.NoError(t, s.RunE())
+	assert.Less(t, time.Since(start), time.Second)
+	// Bounded by the hook timeout, shorter than the grace period.
+	assert.LessOrEqual(t, remaining, 20*time.Millisecond)
+}
+
+func TestShutdownHooksGracePeriod(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithTerminationGracePeriod(20*time.Millisecond))
+	require.NoError(t, err)
+
+	s.OnShutdown(func(ctx context.Context, _ Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+
+	start := time.Now()
+	require.NoError(t, s.RunE())
+	// The default 10s hook timeout is cut short by the grace period.
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWithHookTimeoutInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithHookTimeout(0))
+	require.Error(t, err)
+}
@@ -2,14 +2,23 @@ package svc
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// internalHTTPServerName is the worker name of the internal HTTP server.
+const internalHTTPServerName = "internal-http-server"
+
 var _ Worker = (*httpServer)(nil)
 
 // httpServer defines the internal HTTP Server worker.
@@ -17,24 +26,86 @@ type httpServer struct {
 	logger     *zap.Logger
 	addr       string
 	httpServer *http.Server
+
+	mu         sync.Mutex
+	running    bool
+	terminated bool
+	// listener is the listener bound for httpServer by a restart.
+	listener net.Listener
+	useTLS   bool
+	// tlsConfig is the configuration handshakes are served with, swapped when
+	// the TLS material changes.
+	tlsConfig atomic.Pointer[tls.Config]
+}
+
+// HTTPOption defines WithHTTPServer's option type.
+type HTTPOption func(*http.Server)
+
+// HTTPReadTimeout sets the maximum duration for reading an entire request,
+// including the body. Defaults to none.
+func HTTPReadTimeout(d time.Duration) HTTPOption {
+	return func(srv *http.Server) {
+		srv.ReadTimeout = d
+	}
+}
+
+// HTTPReadHeaderTimeout sets the maximum duration for reading request headers.
+// Defaults to 5s.
+func HTTPReadHeaderTimeout(d time.Duration) HTTPOption {
+	return func(srv *http.Server) {
+		srv.ReadHeaderTimeout = d
+	}
 }
 
-func newHTTPServer(port string, handler http.Handler, logger *log.Logger) *httpServer {
-	addr := net.JoinHostPort("", port)
-	return &httpServer{
-		addr: addr,
-		httpServer: &http.Server{
-			Addr:              addr,
-			Handler:           handler,
-			ErrorLog:          logger,
-			ReadHeaderTimeout: 5 * time.Second, // https://medium.com/a-journey-with-go/go-understand-and-mitigate-slowloris-attack-711c1b1403f6
-		},
+// HTTPWriteTimeout sets the maximum duration before timing out writes of the
+// response. Defaults to none.
+func HTTPWriteTimeout(d time.Duration) HTTPOption {
+	return func(srv *http.Server) {
+		srv.WriteTimeout = d
 	}
 }
 
+// HTTPIdleTimeout sets the maximum duration to wait for the next request on
+// keep-alive connections. Defaults to the read timeout.
+func HTTPIdleTimeout(d time.Duration) HTTPOption {
+	return func(srv *http.Server) {
+		srv.IdleTimeout = d
+	}
+}
+
+// newHTTPServer returns the server of handler listening on addr, either an
+// address such as "127.0.0.1:8080" or a port.
+func newHTTPServer(addr string, handler http.Handler, logger *log.Logger, opts ...HTTPOption) *httpServer {
+	if !strings.Contains(addr, ":") {
+		addr = net.JoinHostPort("", addr)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ErrorLog:          logger,
+		ReadHeaderTimeout: 5 * time.Second, // https://medium.com/a-journey-with-go/go-understand-and-mitigate-slowloris-attack-711c1b1403f6
+	}
+	for _, o := range opts {
+		o(srv)
+	}
+	return &httpServer{addr: addr, httpServer: srv}
+}
+
+// HTTPServerWorker turns a user-constructed http.Server into a Worker with the
+// same lifecycle as the internal HTTP server: it listens on srv.Addr once run,
+// serving TLS if srv.TLSConfig has certificates, and drains in-flight requests
+// on termination. Server errors are logged with the worker's logger unless
+// srv.ErrorLog is set.
+func HTTPServerWorker(srv *http.Server) Worker {
+	return &httpServer{addr: srv.Addr, httpServer: srv}
+}
+
 // Init implements the Worker interface.
 func (s *httpServer) Init(logger *zap.Logger) error {
 	s.logger = logger
+	if s.httpServer.ErrorLog == nil {
+		s.httpServer.ErrorLog = zap.NewStdLog(logger)
+	}
 
 	return nil
 }
@@ -44,16 +115,194 @@ func (s *httpServer) Healthy() error {
 	return nil
 }
 
-// Run implements the Worker interface.
+// Run implements the Worker interface. It keeps serving across restarts.
 func (s *httpServer) Run() error {
+	s.mu.Lock()
+	s.running = true
+	s.useTLS = hasCertificate(s.httpServer.TLSConfig)
+	if s.useTLS {
+		s.tlsConfig.Store(serverTLSConfig(s.httpServer.TLSConfig))
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
 	s.logger.Info("Listening and serving HTTP", zap.String("address", s.addr))
-	if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
-		s.logger.Error("Failed to serve HTTP", zap.Error(err))
+	for {
+		s.mu.Lock()
+		srv, l, useTLS := s.httpServer, s.listener, s.useTLS
+		s.listener = nil
+		s.mu.Unlock()
+
+		if err := s.serve(srv, l, useTLS); err != http.ErrServerClosed {
+			s.logger.Error("Failed to serve HTTP", zap.Error(err))
+			return nil
+		}
+
+		s.mu.Lock()
+		restarted := !s.terminated && s.httpServer != srv
+		s.mu.Unlock()
+		if !restarted {
+			return nil
+		}
 	}
-	return nil
+}
+
+// serve serves srv on l, listening on its address if l is nil.
+func (s *httpServer) serve(srv *http.Server, l net.Listener, useTLS bool) error {
+	if l == nil {
+		addr := srv.Addr
+		if addr == "" {
+			addr = ":http"
+			if useTLS {
+				addr = ":https"
+			}
+		}
+		var err error
+		if l, err = net.Listen("tcp", addr); err != nil {
+			return err
+		}
+	}
+	if useTLS {
+		l = tls.NewListener(l, &tls.Config{
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return s.tlsConfig.Load(), nil
+			},
+		})
+	}
+	return srv.Serve(l)
+}
+
+// restart serves on addr with tlsConfig. If the address changes, the new one
+// is bound first, then the old server stops accepting connections and drains
+// in-flight requests for up to grace. Otherwise, the TLS configuration is
+// swapped in place for new connections.
+func (s *httpServer) restart(addr string, tlsConfig *tls.Config, grace time.Duration) error {
+	s.mu.Lock()
+	if !s.running || s.terminated {
+		s.mu.Unlock()
+		return errors.New("not running")
+	}
+	useTLS := hasCertificate(tlsConfig)
+	if addr == "" || addr == s.addr {
+		defer s.mu.Unlock()
+		if useTLS != s.useTLS {
+			return errors.New("enabling or disabling TLS requires a new address")
+		}
+		if useTLS {
+			s.tlsConfig.Store(serverTLSConfig(tlsConfig))
+			s.logger.Info("Reloaded TLS configuration", zap.String("address", s.addr))
+		}
+		return nil
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old := s.httpServer
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           old.Handler,
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       old.ReadTimeout,
+		ReadHeaderTimeout: old.ReadHeaderTimeout,
+		WriteTimeout:      old.WriteTimeout,
+		IdleTimeout:       old.IdleTimeout,
+		MaxHeaderBytes:    old.MaxHeaderBytes,
+		ConnState:         old.ConnState,
+		ErrorLog:          old.ErrorLog,
+		BaseContext:       old.BaseContext,
+		ConnContext:       old.ConnContext,
+	}
+	s.logger.Info("Restarting HTTP server", zap.String("address", addr), zap.String("previous_address", s.addr))
+	s.addr, s.listener, s.useTLS = addr, l, useTLS
+	if useTLS {
+		s.tlsConfig.Store(serverTLSConfig(tlsConfig))
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	return old.Shutdown(ctx)
 }
 
 // Terminate implements the Worker interface.
 func (s *httpServer) Terminate() error {
-	return s.httpServer.Shutdown(context.Background())
+	s.mu.Lock()
+	s.terminated = true
+	srv := s.httpServer
+	s.mu.Unlock()
+
+	return srv.Shutdown(context.Background())
+}
+
+// hasCertificate reports whether the TLS configuration can serve, thus whether
+// TLS is enabled.
+func hasCertificate(cfg *tls.Config) bool {
+	return cfg != nil && (len(cfg.Certificates) > 0 || cfg.GetCertificate != nil)
+}
+
+// serverTLSConfig returns a copy of cfg negotiating HTTP/2 and HTTP/1.1 by
+// default, as http.Server.ServeTLS does.
+func serverTLSConfig(cfg *tls.Config) *tls.Config {
+	cfg = cfg.Clone()
+	if len(cfg.NextProtos) == 0 {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return cfg
+}
+
+// RestartHTTPServer moves the named HTTP server worker, i.e. the internal HTTP
+// server or one added with HTTPServerWorker, to addr and tlsConfig without
+// restarting the process, e.g. after a configuration reload. The new address
+// is bound before the old listener is closed, and the old server drains
+// in-flight requests within the termination grace period. With an empty or
+// unchanged addr, the TLS material, e.g. a renewed certificate, is swapped for
+// new connections only; enabling or disabling TLS requires a new address.
+func (s *SVC) RestartHTTPServer(name, addr string, tlsConfig *tls.Config) error {
+	hs, ok := unwrapWorker(s.workers[name]).(*httpServer)
+	if !ok {
+		return fmt.Errorf("worker %s is not an HTTP server", name)
+	}
+	_, grace := s.terminationPeriods()
+	if err := hs.restart(addr, tlsConfig, grace); err != nil {
+		return fmt.Errorf("restart HTTP server %s: %w", name, err)
+	}
+	return nil
+}
+
+// router returns the handler serving the router's routes, falling back to the
+// handler set with WithHandler, if any, for requests matching none.
+func (s *SVC) router() http.Handler {
+	if s.appHandler == nil {
+		return s.Router
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := s.Router.Handler(r); pattern == "" {
+			s.appHandler.ServeHTTP(w, r)
+			return
+		}
+		s.Router.ServeHTTP(w, r)
+	})
+}
+
+// serveHTTP serves the router wrapped by the middlewares. The chain is built on
+// the first request, thus after all options have been applied.
+func (s *SVC) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handlerOnce.Do(func() {
+		// Built once, thus no middleware can be added from then on, see Use.
+		s.lifecycleMu.Lock()
+		defer s.lifecycleMu.Unlock()
+		h := s.maintenanceMiddleware(s.router())
+		for i := len(s.middlewares) - 1; i >= 0; i-- {
+			h = s.middlewares[i](h)
+		}
+		s.handler = h
+	})
+	s.handler.ServeHTTP(w, r)
 }
@@ -0,0 +1,136 @@
+package svc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPServerWorker(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		}),
+	}
+	w := HTTPServerWorker(srv)
+	require.NoError(t, w.Init(zap.NewNop()))
+	assert.NotNil(t, srv.ErrorLog)
+	require.Implements(t, (*Healther)(nil), w)
+
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "hello", string(body))
+
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-done)
+}
+
+func TestRestartHTTPServer(t *testing.T) {
+	freeAddr := func() string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+		return l.Addr().String()
+	}
+	cert1, cert2 := issueCert(t, nil, ""), issueCert(t, nil, "")
+	pool := x509.NewCertPool()
+	pool.AddCert(cert1.Leaf)
+	pool.AddCert(cert2.Leaf)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		DisableKeepAlives: true,
+	}}
+
+	block, blocked := make(chan struct{}), make(chan struct{})
+	addr := freeAddr()
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/block" {
+				close(blocked)
+				<-block
+			}
+			_, _ = w.Write([]byte("hello"))
+		}),
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert1}, MinVersion: tls.VersionTLS12},
+	}
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	w := HTTPServerWorker(srv)
+	s.AddWorker("api", w)
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	require.EqualError(t, s.RestartHTTPServer("api", "", nil), "restart HTTP server api: not running")
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+
+	servedCert := func(addr string) *x509.Certificate {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = client.Get("https://" + addr)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		_ = resp.Body.Close()
+		return resp.TLS.PeerCertificates[0]
+	}
+	assert.Equal(t, cert1.Leaf, servedCert(addr))
+
+	// New TLS material on the same address.
+	require.NoError(t, s.RestartHTTPServer("api", "", &tls.Config{Certificates: []tls.Certificate{cert2}}))
+	assert.Equal(t, cert2.Leaf, servedCert(addr))
+	require.EqualError(t, s.RestartHTTPServer("api", addr, nil),
+		"restart HTTP server api: enabling or disabling TLS requires a new address")
+
+	// New address, draining the request in flight on the old one.
+	inFlight := make(chan error)
+	go func() {
+		resp, err := client.Get("https://" + addr + "/block")
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+		}
+		inFlight <- err
+	}()
+	<-blocked
+	newAddr := freeAddr()
+	restarted := make(chan error)
+	go func() { restarted <- s.RestartHTTPServer("api", newAddr, nil) }()
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + newAddr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_ = resp.Body.Close()
+	close(block)
+	require.NoError(t, <-inFlight)
+	require.NoError(t, <-restarted)
+	_, err = client.Get("https://" + addr)
+	require.Error(t, err)
+
+	require.EqualError(t, s.RestartHTTPServer("unknown", newAddr, nil), "worker unknown is not an HTTP server")
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-done)
+}
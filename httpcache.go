@@ -0,0 +1,145 @@
+package svc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cache-Control presets.
+const (
+	CacheControlNoStore = "no-store"
+	CacheControlNoCache = "no-cache"
+)
+
+// CacheControlPublic returns a Cache-Control value allowing any cache to store
+// the response for maxAge.
+func CacheControlPublic(maxAge time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// CacheControlPrivate returns a Cache-Control value allowing only the client to
+// store the response for maxAge.
+func CacheControlPrivate(maxAge time.Duration) string {
+	return fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+}
+
+// CacheControlImmutable returns a Cache-Control value for responses that never
+// change, e.g. content-addressed assets.
+func CacheControlImmutable(maxAge time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d, immutable", int(maxAge.Seconds()))
+}
+
+// ETag returns a strong entity tag for the given content, or a weak one for
+// semantically equivalent but not byte-identical representations.
+func ETag(content []byte, weak bool) string {
+	sum := sha256.Sum256(content)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// CheckNotModified sets the ETag and Last-Modified response headers, if given,
+// and evaluates the request's conditional headers. It reports whether the
+// response was answered with 304 Not Modified, in which case the handler must
+// not write a body.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etag != "" && etagMatch(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		notModified = err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	if !notModified {
+		return false
+	}
+
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatch reports whether the If-None-Match header matches the entity tag,
+// using the weak comparison.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ETagHandler wraps a handler to add a strong ETag to its successful GET and
+// HEAD responses that have none, and to answer matching conditional requests
+// with 304 Not Modified. Responses are buffered to compute the tag.
+func ETagHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &etagRecorder{header: http.Header{}, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		if rec.code == http.StatusOK {
+			etag := rec.header.Get("ETag")
+			if etag == "" {
+				etag = ETag(rec.body.Bytes(), false)
+			}
+			if CheckNotModified(w, r, etag, time.Time{}) {
+				return
+			}
+		}
+		w.WriteHeader(rec.code)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+// etagRecorder buffers a response.
+type etagRecorder struct {
+	header      http.Header
+	code        int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *etagRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *etagRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+}
+
+func (r *etagRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
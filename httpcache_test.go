@@ -0,0 +1,97 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	lastModified := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := ETag([]byte("content"), false)
+
+	tests := []struct {
+		name         string
+		method       string
+		header       map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "should serve without conditional headers",
+			method:       "GET",
+			expectedCode: 200,
+		},
+		{
+			name:         "should not modify on matching etag",
+			method:       "GET",
+			header:       map[string]string{"If-None-Match": `"other", W/` + etag},
+			expectedCode: 304,
+		},
+		{
+			name:         "should serve on other etag even if not modified since",
+			method:       "GET",
+			header:       map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			expectedCode: 200,
+		},
+		{
+			name:         "should not modify when not modified since",
+			method:       "HEAD",
+			header:       map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			expectedCode: 304,
+		},
+		{
+			name:         "should serve when modified since",
+			method:       "GET",
+			header:       map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedCode: 200,
+		},
+		{
+			name:         "should ignore unsafe methods",
+			method:       "POST",
+			header:       map[string]string{"If-None-Match": "*"},
+			expectedCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			if !CheckNotModified(rec, req, etag, lastModified) {
+				rec.WriteHeader(http.StatusOK)
+			}
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, etag, rec.Header().Get("ETag"))
+			assert.Equal(t, "Sat, 02 Jan 2021 03:04:05 GMT", rec.Header().Get("Last-Modified"))
+		})
+	}
+}
+
+func TestETagHandler(t *testing.T) {
+	h := ETagHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Cache-Control", CacheControlPublic(time.Minute))
+		_, _ = w.Write([]byte("content"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "content", rec.Body.String())
+	assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, ETag([]byte("content"), false), etag)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
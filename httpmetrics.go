@@ -0,0 +1,195 @@
+package svc
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHTTPMetricsMaxRoutes = 100
+	// httpMetricsOther is the label value of routes beyond the cardinality cap
+	// and of non-standard methods.
+	httpMetricsOther = "other"
+	// httpMetricsUnmatched is the route label value of requests matching no
+	// route.
+	httpMetricsUnmatched = "unmatched"
+)
+
+var httpMetricsMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// HTTPMetricsOption defines WithHTTPMetrics' option type.
+type HTTPMetricsOption func(*httpMetrics)
+
+// HTTPMetricsRouteFunc sets the function returning the route label of a
+// request. It must return a route template, e.g. "/users/{id}", never the raw
+// path. Defaults to the pattern of the Router's route matching the request.
+func HTTPMetricsRouteFunc(fn func(r *http.Request) string) HTTPMetricsOption {
+	return func(m *httpMetrics) {
+		m.routeFunc = fn
+	}
+}
+
+// HTTPMetricsMaxRoutes caps the number of distinct route labels. Requests to
+// further routes are counted under the "other" route. Defaults to 100.
+func HTTPMetricsMaxRoutes(n int) HTTPMetricsOption {
+	return func(m *httpMetrics) {
+		m.maxRoutes = n
+	}
+}
+
+// WithHTTPMetrics is an option that instruments the internal HTTP server with
+// the svc_http_requests_total and svc_http_request_duration_seconds metrics,
+// labeled by method, route template, and status code. Label cardinality is
+// capped, see HTTPMetricsMaxRoutes. Paths excluded from instrumentation are not
+// counted, see WithInstrumentationExclusions.
+func WithHTTPMetrics(opts ...HTTPMetricsOption) Option {
+	return func(s *SVC) error {
+		m := &httpMetrics{
+			logger:       s.logger,
+			instrumented: s.instrumented,
+			routeFunc: func(r *http.Request) string {
+				_, pattern := s.Router.Handler(r)
+				return pattern
+			},
+			maxRoutes: defaultHTTPMetricsMaxRoutes,
+			routes:    map[string]bool{},
+			requests: prometheus.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: "svc_http_requests_total",
+					Help: "Number of HTTP requests served by the internal HTTP server.",
+				},
+				[]string{"method", "route", "code"},
+			),
+			duration: prometheus.NewHistogramVec(
+				prometheus.HistogramOpts{
+					Name:    "svc_http_request_duration_seconds",
+					Help:    "Duration of HTTP requests served by the internal HTTP server.",
+					Buckets: prometheus.DefBuckets,
+				},
+				[]string{"method", "route"},
+			),
+		}
+		for _, o := range opts {
+			o(m)
+		}
+		for _, c := range []prometheus.Collector{m.requests, m.duration} {
+			if err := s.internalRegister.Register(c); err != nil {
+				return err
+			}
+		}
+		s.middlewares = append(s.middlewares, m.middleware)
+
+		return nil
+	}
+}
+
+type httpMetrics struct {
+	logger       *zap.Logger
+	instrumented func(*http.Request) bool
+	routeFunc    func(*http.Request) string
+	maxRoutes    int
+
+	mu       sync.Mutex
+	routes   map[string]bool
+	capped   bool
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func (m *httpMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.instrumented(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		method := r.Method
+		if !httpMetricsMethods[method] {
+			method = httpMetricsOther
+		}
+		route := m.route(r)
+		m.requests.WithLabelValues(method, route, strconv.Itoa(rec.code)).Inc()
+		m.duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// route returns the route label of the request, capping the number of
+// distinct routes.
+func (m *httpMetrics) route(r *http.Request) string {
+	route := m.routeFunc(r)
+	if route == "" {
+		return httpMetricsUnmatched
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.routes[route] {
+		return route
+	}
+	if len(m.routes) >= m.maxRoutes {
+		if !m.capped {
+			m.capped = true
+			m.logger.Warn("HTTP metrics route cardinality cap reached, counting further routes as other",
+				zap.Int("max_routes", m.maxRoutes))
+		}
+		return httpMetricsOther
+	}
+	m.routes[route] = true
+	return route
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
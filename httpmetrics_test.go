@@ -0,0 +1,100 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTPMetrics(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHTTPMetrics(HTTPMetricsMaxRoutes(1)))
+	require.NoError(t, err)
+	s.HandleFunc("/users/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	s.HandleFunc("/orders/", func(http.ResponseWriter, *http.Request) {})
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest("GET", "/users/1", nil),
+		httptest.NewRequest("GET", "/users/2", nil),
+		httptest.NewRequest("FOO", "/users/3", nil),
+		httptest.NewRequest("GET", "/orders/1", nil),
+		httptest.NewRequest("GET", "/unknown", nil),
+	} {
+		s.serveHTTP(httptest.NewRecorder(), r)
+	}
+
+	requests := `
+		# HELP svc_http_requests_total Number of HTTP requests served by the internal HTTP server.
+		# TYPE svc_http_requests_total counter
+		svc_http_requests_total{code="200",method="GET",route="other"} 1
+		svc_http_requests_total{code="201",method="GET",route="/users/"} 2
+		svc_http_requests_total{code="201",method="other",route="/users/"} 1
+		svc_http_requests_total{code="404",method="GET",route="unmatched"} 1
+	`
+	require.NoError(t, testutil.GatherAndCompare(s.internalRegister, strings.NewReader(requests), "svc_http_requests_total"))
+
+	mfs, err := s.internalRegister.Gather()
+	require.NoError(t, err)
+	series := 0
+	for _, mf := range mfs {
+		if mf.GetName() == "svc_http_request_duration_seconds" {
+			series = len(mf.GetMetric())
+		}
+	}
+	assert.Equal(t, 4, series)
+}
+
+func TestHTTPMetricsExclusions(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenOptions  []Option
+		expectedCount float64
+	}{
+		{
+			name:          "should not count probes by default",
+			givenOptions:  []Option{WithHealthz(), WithHTTPMetrics()},
+			expectedCount: 2,
+		},
+		{
+			name:          "should replace the default exclusions",
+			givenOptions:  []Option{WithHealthz(), WithHTTPMetrics(), WithInstrumentationExclusions("/users/")},
+			expectedCount: 3,
+		},
+		{
+			name:          "should count every request without exclusions",
+			givenOptions:  []Option{WithHealthz(), WithHTTPMetrics(), WithInstrumentationExclusions()},
+			expectedCount: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", tc.givenOptions...)
+			require.NoError(t, err)
+			s.HandleFunc("/users/", func(http.ResponseWriter, *http.Request) {})
+
+			for _, path := range []string{"/live", "/ready", "/users/1", "/orders"} {
+				s.serveHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+			}
+
+			mfs, err := s.internalRegister.Gather()
+			require.NoError(t, err)
+			count := 0.0
+			for _, mf := range mfs {
+				if mf.GetName() == "svc_http_requests_total" {
+					for _, m := range mf.GetMetric() {
+						count += m.GetCounter().GetValue()
+					}
+				}
+			}
+			assert.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
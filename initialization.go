@@ -0,0 +1,86 @@
+package svc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// WithParallelInit is an option that initializes the workers concurrently
+// rather than one at a time in added order, e.g. for services dialing many
+// backends. Workers declaring dependencies with AddWorkerWithDeps are still
+// initialized after their dependencies. Once a worker failed to initialize,
+// the workers not initializing yet are skipped.
+func WithParallelInit() Option {
+	return func(s *SVC) error {
+		s.parallelInit = true
+
+		return nil
+	}
+}
+
+// WithInitTimeout is an option that bounds each worker's initialization,
+// including its retries, so that a hung Init fails the start rather than
+// blocking it forever. A worker timing out is not terminated, as its Init may
+// still be running.
+func WithInitTimeout(d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return errors.New("init timeout must be positive")
+		}
+		s.initTimeout = d
+
+		return nil
+	}
+}
+
+// initWorkersConcurrently initializes the workers concurrently, except that
+// workers declaring dependencies are initialized after them.
+func (s *SVC) initWorkersConcurrently() error {
+	done := map[string]chan struct{}{}
+	for _, name := range s.workersAdded {
+		done[name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	initialized := map[string]bool{}
+	var failed string
+	var failedErr error
+	var wg sync.WaitGroup
+	for _, name := range s.workersAdded {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer close(done[name])
+			for _, dep := range s.workerDeps[name] {
+				<-done[dep]
+			}
+			mu.Lock()
+			skip := failedErr != nil
+			for _, dep := range s.workerDeps[name] {
+				skip = skip || !initialized[dep]
+			}
+			mu.Unlock()
+			if skip {
+				return
+			}
+
+			err := s.initWorker(name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if failedErr == nil {
+					failed, failedErr = name, err
+				}
+				return
+			}
+			initialized[name] = true
+		}(name)
+	}
+	wg.Wait()
+
+	if failedErr != nil {
+		return s.initError(failed, failedErr)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package svc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithParallelInit(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithParallelInit())
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	initAt := map[string]time.Time{}
+	worker := func(name string) Worker {
+		return &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				defer mu.Unlock()
+				initAt[name] = time.Now()
+				return nil
+			},
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { return nil },
+		}
+	}
+	for _, name := range []string{"w1", "w2", "w3", "w4"} {
+		s.AddWorker(name, worker(name))
+	}
+	s.AddWorkerWithDeps("consumer", worker("consumer"), "w1")
+
+	start := time.Now()
+	require.NoError(t, s.RunE())
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "independent workers should initialize concurrently")
+	assert.Len(t, initAt, 5)
+	assert.True(t, initAt["consumer"].After(initAt["w1"]), "dependency should be initialized first")
+}
+
+func TestWithParallelInitFailure(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithParallelInit())
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var terminated []string
+	cacheInit := make(chan struct{})
+	worker := func(name string, initErr error) Worker {
+		return &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				if name == "cache" {
+					close(cacheInit)
+				} else if initErr != nil {
+					// Fail once the independent worker is initializing.
+					<-cacheInit
+				}
+				return initErr
+			},
+			RunFunc: func() error {
+				require.FailNow(t, "Worker should not run")
+				return nil
+			},
+			TerminateFunc: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				terminated = append(terminated, name)
+				return nil
+			},
+		}
+	}
+	s.AddWorker("db", worker("db", errors.New("dummy error")))
+	s.AddWorker("cache", worker("cache", nil))
+	s.AddWorkerWithDeps("consumer", worker("consumer", nil), "db")
+
+	err = s.RunE()
+	var initErr *InitError
+	require.ErrorAs(t, err, &initErr)
+	assert.Equal(t, "db", initErr.Worker)
+	assert.Equal(t, []string{"cache"}, initErr.Initialized)
+	assert.Equal(t, []string{"consumer"}, initErr.NotInitialized)
+	assert.Equal(t, []string{"cache"}, terminated)
+}
+
+func TestWithInitTimeout(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithInitTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+
+	hung := make(chan struct{})
+	defer close(hung)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			<-hung
+			return nil
+		},
+	})
+
+	err = s.RunE()
+	var initErr *InitError
+	require.ErrorAs(t, err, &initErr)
+	assert.EqualError(t, initErr.Err, "timed out after 20ms")
+
+	_, err = New("dummy-service", "v0.0.0", WithInitTimeout(0))
+	require.EqualError(t, err, "init timeout must be positive")
+}
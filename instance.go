@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// crockford is the Crockford's base32 alphabet ULIDs are encoded with.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// IDGenerator defines a function generating unique IDs.
+type IDGenerator func() string
+
+// NewULID returns a new ULID, a lexicographically sortable unique ID made of
+// a millisecond timestamp and 80 random bits, e.g.
+// "01ARZ3NDEKTSV4RRFFQ69G5FAV". It is the default instance ID generator.
+func NewULID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	// crypto/rand never fails on supported platforms.
+	_, _ = rand.Read(b[6:])
+
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var id [26]byte
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id[:])
+}
+
+// WithIDGenerator is an option that generates the instance ID with gen rather
+// than NewULID, e.g. to use the pod's name. Options using the instance ID,
+// e.g. WithMetrics, must come after it.
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(s *SVC) error {
+		id := gen()
+		if id == "" {
+			return errors.New("instance ID must not be empty")
+		}
+		s.instanceID = id
+
+		return nil
+	}
+}
+
+// InstanceID returns the ID identifying this instance of the service, i.e.
+// replica, generated at New. It is included in the logs, the svc_up metric,
+// and the health and status responses, to correlate a replica's telemetry,
+// e.g. to register in service discovery from an OnStart hook.
+func (s *SVC) InstanceID() string {
+	return s.instanceID
+}
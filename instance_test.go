@@ -0,0 +1,60 @@
+package svc
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewULID(t *testing.T) {
+	first := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	second := NewULID()
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`), first)
+	assert.Less(t, first, second, "ULIDs should sort by time")
+}
+
+func TestInstanceID(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		expectedID    string
+		expectedError bool
+	}{
+		{
+			name: "generated ULID",
+		},
+		{
+			name:       "custom generator",
+			opts:       []Option{WithIDGenerator(func() string { return "pod-1" })},
+			expectedID: "pod-1",
+		},
+		{
+			name:          "empty ID",
+			opts:          []Option{WithIDGenerator(func() string { return "" })},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", tc.opts...)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectedID == "" {
+				assert.Len(t, s.InstanceID(), 26)
+			} else {
+				assert.Equal(t, tc.expectedID, s.InstanceID())
+			}
+			assert.Equal(t, s.InstanceID(), s.expvars().InstanceID)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package svc
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultInstrumentationExclusions are the paths excluded from request
+// instrumentation by default: the probes, the metrics scrape, and profiling.
+var DefaultInstrumentationExclusions = []string{
+	"/live",
+	"/startup",
+	"/ready",
+	"/ready/details",
+	"/metrics",
+	"/debug/pprof/",
+	"/debug/vars",
+}
+
+// WithInstrumentationExclusions is an option that replaces the paths excluded
+// from request instrumentation, i.e. request metrics, access logs, and
+// tracing, which default to DefaultInstrumentationExclusions. A path ending in
+// a slash excludes the whole subtree, like http.ServeMux patterns. Pass no
+// paths to instrument every request.
+func WithInstrumentationExclusions(paths ...string) Option {
+	return func(s *SVC) error {
+		s.instrumentationExclusions = paths
+		return nil
+	}
+}
+
+// instrumented returns whether the request is to be instrumented, i.e. its path
+// is not excluded.
+func (s *SVC) instrumented(r *http.Request) bool {
+	for _, p := range s.instrumentationExclusions {
+		if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+			return false
+		}
+	}
+	return true
+}
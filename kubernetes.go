@@ -0,0 +1,296 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	kubeServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubeEventsBufferSize  = 64
+	kubeEventsPostTimeout = 5 * time.Second
+	// kubeEventsFlushTimeout bounds posting the queued events on termination.
+	kubeEventsFlushTimeout = 2 * time.Second
+)
+
+// kubeEventTypes maps the lifecycle events posted as Kubernetes Events to their
+// Kubernetes Event type.
+var kubeEventTypes = map[EventType]string{
+	EventWorkerInitFailed:  "Warning",
+	EventWorkerFailed:      "Warning",
+	EventWorkerDrainFailed: "Warning",
+	EventWorkerTermFailed:  "Warning",
+	EventWorkerUnhealthy:   "Warning",
+	EventWorkerNotAlive:    "Warning",
+	EventWorkerHealthy:     "Normal",
+	EventWorkerAlive:       "Normal",
+}
+
+type kubeEventsConfig struct {
+	PodName      string `env:"POD_NAME" validate:"required"`
+	PodNamespace string `env:"POD_NAMESPACE"`
+	PodUID       string `env:"POD_UID"`
+	Host         string `env:"KUBERNETES_SERVICE_HOST" validate:"required"`
+	Port         string `env:"KUBERNETES_SERVICE_PORT" envDefault:"443"`
+}
+
+// WithKubernetesEvents is an option that posts Kubernetes Events on the pod when
+// workers fail or their health changes, so they show up in
+// `kubectl describe pod`. The pod is referenced via the downward API
+// environment variables POD_NAME, POD_NAMESPACE, and optionally POD_UID; the
+// pod's service account needs permission to create events. Events are posted
+// from the workers' initialization on, and those still queued on termination
+// are flushed. Dropped events are counted in the
+// svc_kubernetes_events_dropped_total metric.
+func WithKubernetesEvents() Option {
+	return func(s *SVC) error {
+		var cfg kubeEventsConfig
+		if err := LoadFromEnv(&cfg); err != nil {
+			return fmt.Errorf("kubernetes events: %w", err)
+		}
+		k, err := newKubeEvents(cfg, kubeServiceAccountDir, s.Name, s.metrics)
+		if err != nil {
+			return fmt.Errorf("kubernetes events: %w", err)
+		}
+		k.logger = s.logger
+		s.events.subscribe(k.enqueue)
+		s.AddWorker("internal-kubernetes-events", k)
+
+		return nil
+	}
+}
+
+var _ Worker = (*kubeEvents)(nil)
+
+// kubeEvents defines the internal worker posting Kubernetes Events.
+type kubeEvents struct {
+	logger    *zap.Logger
+	client    *http.Client
+	url       string
+	tokenPath string
+	cfg       kubeEventsConfig
+	component string
+
+	metrics *metrics
+
+	mu       sync.Mutex
+	closed   bool
+	queue    chan Event
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newKubeEvents(cfg kubeEventsConfig, saDir, component string, m *metrics) (*kubeEvents, error) {
+	// Read on each post as projected tokens rotate, thus only checked here.
+	if _, err := os.Stat(saDir + "/token"); err != nil {
+		return nil, err
+	}
+	if cfg.PodNamespace == "" {
+		ns, err := os.ReadFile(saDir + "/namespace")
+		if err != nil {
+			return nil, err
+		}
+		cfg.PodNamespace = strings.TrimSpace(string(ns))
+	}
+	ca, err := os.ReadFile(saDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no CA certificate found in %s/ca.crt", saDir)
+	}
+
+	return &kubeEvents{
+		client: &http.Client{
+			Timeout:   kubeEventsPostTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+		url: fmt.Sprintf("https://%s/api/v1/namespaces/%s/events",
+			net.JoinHostPort(cfg.Host, cfg.Port), cfg.PodNamespace),
+		tokenPath: saDir + "/token",
+		cfg:       cfg,
+		component: component,
+		metrics:   m,
+		queue:     make(chan Event, kubeEventsBufferSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Init implements the Worker interface. It starts posting the events, for
+// those raised while the other workers initialize, e.g. their failures, to be
+// posted.
+func (k *kubeEvents) Init(logger *zap.Logger) error {
+	k.logger = logger
+	go k.postQueued()
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (k *kubeEvents) Run() error {
+	<-k.done
+
+	return nil
+}
+
+// Terminate implements the Worker interface. It stops queueing events and
+// flushes the queue, bounded by kubeEventsFlushTimeout.
+func (k *kubeEvents) Terminate() error {
+	k.stopOnce.Do(func() {
+		k.mu.Lock()
+		k.closed = true
+		k.mu.Unlock()
+		close(k.stop)
+	})
+	select {
+	case <-k.done:
+	case <-time.After(kubeEventsFlushTimeout):
+		k.logger.Warn("Kubernetes events not flushed in time")
+	}
+
+	return nil
+}
+
+// postQueued posts the queued events until stopped, then flushes the queue.
+func (k *kubeEvents) postQueued() {
+	defer close(k.done)
+	for {
+		select {
+		case e := <-k.queue:
+			k.postWithTimeout(context.Background(), e)
+		case <-k.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), kubeEventsFlushTimeout)
+			defer cancel()
+			for {
+				select {
+				case e := <-k.queue:
+					if ctx.Err() != nil {
+						k.drop(e, "flush_timeout")
+						continue
+					}
+					k.postWithTimeout(ctx, e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// enqueue queues the event to be posted, dropping it if the queue is full to
+// never block the service, or once terminated.
+func (k *kubeEvents) enqueue(e Event) {
+	if _, ok := kubeEventTypes[e.Type]; !ok {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.closed {
+		k.drop(e, "terminated")
+		return
+	}
+	select {
+	case k.queue <- e:
+	default:
+		k.drop(e, "queue_full")
+	}
+}
+
+func (k *kubeEvents) drop(e Event, reason string) {
+	k.metrics.kubeEventsDropped.WithLabelValues(reason).Inc()
+	k.logger.Warn("Dropped Kubernetes event", zap.String("event", string(e.Type)),
+		zap.String("worker", e.Worker), zap.String("reason", reason))
+}
+
+// postWithTimeout posts the event within kubeEventsPostTimeout, or ctx,
+// logging failures.
+func (k *kubeEvents) postWithTimeout(ctx context.Context, e Event) {
+	ctx, cancel := context.WithTimeout(ctx, kubeEventsPostTimeout)
+	defer cancel()
+	if err := k.post(ctx, e); err != nil {
+		k.logger.Warn("Could not post Kubernetes event", zap.String("event", string(e.Type)), zap.Error(err))
+	}
+}
+
+func (k *kubeEvents) post(ctx context.Context, e Event) error {
+	message := fmt.Sprintf("worker %s: %s", e.Worker, e.Type)
+	if e.Message != "" {
+		message = fmt.Sprintf("worker %s: %s", e.Worker, e.Message)
+	}
+	ts := e.Time.UTC().Format(time.RFC3339)
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]interface{}{
+			"generateName": k.cfg.PodName + ".",
+			"namespace":    k.cfg.PodNamespace,
+		},
+		"involvedObject": map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"name":       k.cfg.PodName,
+			"namespace":  k.cfg.PodNamespace,
+			"uid":        k.cfg.PodUID,
+		},
+		"reason":             kubeEventReason(e.Type),
+		"message":            message,
+		"type":               kubeEventTypes[e.Type],
+		"source":             map[string]string{"component": k.component},
+		"reportingComponent": k.component,
+		"reportingInstance":  k.cfg.PodName,
+		"firstTimestamp":     ts,
+		"lastTimestamp":      ts,
+		"count":              1,
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := os.ReadFile(k.tokenPath)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// kubeEventReason converts an event type to a CamelCase Kubernetes reason,
+// e.g. worker_init_failed to WorkerInitFailed.
+func kubeEventReason(t EventType) string {
+	parts := strings.Split(string(t), "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,101 @@
+package svc
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestKubeEvents returns a poster to a test API server, sending the posted
+// events with their token, and the service account directory.
+func newTestKubeEvents(t *testing.T) (*kubeEvents, <-chan map[string]interface{}, string) {
+	posted := make(chan map[string]interface{}, 4)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/namespaces/dummy-ns/events", r.URL.Path)
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		body["token"] = r.Header.Get("Authorization")
+		posted <- body
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("dummy-token\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespace"), []byte("dummy-ns"), 0o600))
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	m, err := newMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	k, err := newKubeEvents(kubeEventsConfig{PodName: "dummy-pod", Host: host, Port: port}, dir, "dummy-service", m)
+	require.NoError(t, err)
+	require.NoError(t, k.Init(zap.NewNop()))
+	return k, posted, dir
+}
+
+func TestKubeEvents(t *testing.T) {
+	k, posted, _ := newTestKubeEvents(t)
+	go func() { _ = k.Run() }()
+	defer func() { _ = k.Terminate() }()
+
+	k.enqueue(Event{Type: EventWorkerStarted, Worker: "dummy-worker"})
+	k.enqueue(Event{Type: EventWorkerFailed, Worker: "dummy-worker", Message: "boom", Time: time.Now()})
+
+	select {
+	case body := <-posted:
+		assert.Equal(t, "WorkerFailed", body["reason"])
+		assert.Equal(t, "Warning", body["type"])
+		assert.Equal(t, "worker dummy-worker: boom", body["message"])
+		assert.Equal(t, "dummy-pod", body["involvedObject"].(map[string]interface{})["name"])
+		assert.Equal(t, "Bearer dummy-token", body["token"])
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Event has not been posted")
+	}
+}
+
+func TestKubeEventsLifecycle(t *testing.T) {
+	k, posted, dir := newTestKubeEvents(t)
+
+	// Posted once initialized, e.g. another worker's init failure.
+	k.enqueue(Event{Type: EventWorkerInitFailed, Worker: "dummy-worker", Message: "boom", Time: time.Now()})
+	select {
+	case body := <-posted:
+		assert.Equal(t, "WorkerInitFailed", body["reason"])
+		assert.Equal(t, "Bearer dummy-token", body["token"])
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Event has not been posted")
+	}
+
+	// The rotated token is used, and the queue flushed on termination.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("rotated-token"), 0o600))
+	k.enqueue(Event{Type: EventWorkerTermFailed, Worker: "dummy-worker", Message: "boom", Time: time.Now()})
+	require.NoError(t, k.Terminate())
+	require.Len(t, posted, 1)
+	body := <-posted
+	assert.Equal(t, "Bearer rotated-token", body["token"])
+
+	k.enqueue(Event{Type: EventWorkerTermFailed, Worker: "dummy-worker", Message: "boom", Time: time.Now()})
+	assert.Equal(t, 1.0, testutil.ToFloat64(k.metrics.kubeEventsDropped.WithLabelValues("terminated")))
+}
+
+func TestWithKubernetesEventsRequiresPod(t *testing.T) {
+	t.Setenv("POD_NAME", "")
+	_, err := New("dummy-service", "v0.0.0", WithKubernetesEvents())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "kubernetes events: ")
+}
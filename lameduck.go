@@ -0,0 +1,142 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLameDuckInterval  = 10 * time.Second
+	defaultLameDuckThreshold = 3
+	defaultLameDuckTimeout   = 5 * time.Second
+)
+
+// Dependency defines a named check of a dependency, e.g. pinging a database.
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// LameDuckOption defines WithLameDuck's option type.
+type LameDuckOption func(*lameDuck)
+
+// LameDuckInterval sets how often the dependency is checked. Defaults to 10s.
+func LameDuckInterval(d time.Duration) LameDuckOption {
+	return func(l *lameDuck) {
+		l.interval = d
+	}
+}
+
+// LameDuckThreshold sets the number of consecutive failed checks marking the
+// service not ready. Defaults to 3.
+func LameDuckThreshold(n int) LameDuckOption {
+	return func(l *lameDuck) {
+		l.threshold = n
+	}
+}
+
+// LameDuckTimeout bounds each check. Defaults to 5s.
+func LameDuckTimeout(d time.Duration) LameDuckOption {
+	return func(l *lameDuck) {
+		l.timeout = d
+	}
+}
+
+// WithLameDuck is an option that periodically checks a critical dependency and
+// marks the service not ready once the check failed for a number of
+// consecutive intervals, and ready again after the first successful check. The
+// dependency's state is exported as the svc_dependency_up metric.
+func WithLameDuck(dep Dependency, opts ...LameDuckOption) Option {
+	return func(s *SVC) error {
+		l := &lameDuck{
+			s:         s,
+			dep:       dep,
+			interval:  defaultLameDuckInterval,
+			threshold: defaultLameDuckThreshold,
+			timeout:   defaultLameDuckTimeout,
+			done:      make(chan struct{}),
+		}
+		for _, o := range opts {
+			o(l)
+		}
+		if l.interval <= 0 || l.threshold <= 0 {
+			return fmt.Errorf("lame duck %s: interval and threshold must be positive", dep.Name)
+		}
+		s.AddWorker("internal-lame-duck-"+dep.Name, l)
+
+		return nil
+	}
+}
+
+var _ Worker = (*lameDuck)(nil)
+
+// lameDuck defines the internal worker checking a critical dependency.
+type lameDuck struct {
+	s         *SVC
+	logger    *zap.Logger
+	dep       Dependency
+	interval  time.Duration
+	threshold int
+	timeout   time.Duration
+	done      chan struct{}
+
+	failures int
+}
+
+// Init implements the Worker interface.
+func (l *lameDuck) Init(logger *zap.Logger) error {
+	l.logger = logger
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (l *lameDuck) Run() error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.check()
+		case <-l.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (l *lameDuck) Terminate() error {
+	close(l.done)
+
+	return nil
+}
+
+func (l *lameDuck) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	err := l.dep.Check(ctx)
+
+	key := "dependency/" + l.dep.Name
+	if err == nil {
+		if l.failures >= l.threshold {
+			l.logger.Info("Dependency recovered, ready again", zap.String("dependency", l.dep.Name))
+			l.s.clearNotReady(key)
+		}
+		l.failures = 0
+		l.s.metrics.dependencyUp.WithLabelValues(l.dep.Name).Set(1)
+		return
+	}
+
+	l.failures++
+	l.s.metrics.dependencyUp.WithLabelValues(l.dep.Name).Set(0)
+	if l.failures == l.threshold {
+		l.logger.Warn("Dependency failing, marking not ready",
+			zap.String("dependency", l.dep.Name), zap.Int("failures", l.failures), zap.Error(err))
+	}
+	if l.failures >= l.threshold {
+		l.s.setNotReady(key, fmt.Sprintf("failed %d consecutive checks: %s", l.failures, err))
+	}
+}
@@ -0,0 +1,44 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLameDuck(t *testing.T) {
+	var depErr error
+	s, err := New("dummy-service", "v0.0.0", WithLameDuck(Dependency{
+		Name:  "db",
+		Check: func(context.Context) error { return depErr },
+	}, LameDuckThreshold(2)))
+	require.NoError(t, err)
+	l := s.workers["internal-lame-duck-db"].(*lameDuck)
+	require.NoError(t, l.Init(zap.NewNop()))
+	up := s.metrics.dependencyUp.WithLabelValues("db")
+
+	depErr = errors.New("connection refused")
+	l.check()
+	assert.Equal(t, HealthOK, s.self.CheckHealth().Status)
+	assert.Equal(t, float64(0), testutil.ToFloat64(up))
+
+	l.check()
+	res := s.self.CheckHealth()
+	assert.Equal(t, HealthCritical, res.Status)
+	assert.Equal(t, "dependency/db: failed 2 consecutive checks: connection refused", res.Detail)
+
+	depErr = nil
+	l.check()
+	assert.Equal(t, HealthOK, s.self.CheckHealth().Status)
+	assert.Equal(t, float64(1), testutil.ToFloat64(up))
+}
+
+func TestWithLameDuckInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithLameDuck(Dependency{Name: "db"}, LameDuckThreshold(0)))
+	require.EqualError(t, err, "lame duck db: interval and threshold must be positive")
+}
@@ -1,6 +1,7 @@
 package svc
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -10,19 +11,41 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func (s *SVC) newLogger(level zapcore.Level, encoder zapcore.Encoder) (*zap.Logger, zap.AtomicLevel) {
+func (s *SVC) newLogger(level zapcore.Level, config zapcore.EncoderConfig, newEncoder func(zapcore.EncoderConfig) zapcore.Encoder) (*zap.Logger, zap.AtomicLevel) {
+	return s.newSampledLogger(level, config, newEncoder, true)
+}
+
+func (s *SVC) newSampledLogger(level zapcore.Level, config zapcore.EncoderConfig, newEncoder func(zapcore.EncoderConfig) zapcore.Encoder, sampled bool) (*zap.Logger, zap.AtomicLevel) {
+	for _, fn := range s.encoderConfigFuncs {
+		fn(&config)
+	}
+	encoder := newEncoder(config)
+
 	atom := zap.NewAtomicLevel()
 	atom.SetLevel(level)
 
 	s.zapOpts = append(s.zapOpts, zap.ErrorOutput(zapcore.Lock(os.Stderr)), zap.AddCaller())
 
-	logger := zap.New(zapcore.NewSamplerWithOptions(zapcore.NewCore(
+	out := zapcore.Lock(os.Stdout)
+	if s.logBuffer != nil {
+		buf := &zapcore.BufferedWriteSyncer{
+			WS:            zapcore.AddSync(os.Stdout),
+			Size:          s.logBuffer.size,
+			FlushInterval: s.logBuffer.flushInterval,
+		}
+		s.logBuffers = append(s.logBuffers, buf)
+		out = buf
+	}
+
+	core := zapcore.NewCore(
 		encoder,
-		zapcore.Lock(os.Stdout),
+		out,
 		atom,
-	), time.Second, 10, 10),
-		s.zapOpts...,
 	)
+	if sampled {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, 10, 10)
+	}
+	logger := zap.New(core, s.zapOpts...)
 
 	return logger, atom
 }
@@ -55,9 +78,49 @@ func WithZapMetrics() Option {
 	}
 }
 
-// WithLogger is an option that allows you to provide your own customized logger.
+// WithLoggerEncoderConfig is an option that customizes the encoder configuration
+// of the logger options, e.g. field names, time format, caller and stacktrace
+// encoding. This option must be passed before the logger option it applies to;
+// pass WithDevelopmentLogger after it to customize the default logger.
+func WithLoggerEncoderConfig(fn func(*zapcore.EncoderConfig)) Option {
+	return func(s *SVC) error {
+		s.encoderConfigFuncs = append(s.encoderConfigFuncs, fn)
+		return nil
+	}
+}
+
+type logBufferConfig struct {
+	size          int
+	flushInterval time.Duration
+}
+
+// WithBufferedLogOutput is an option that buffers the logger's output up to
+// size bytes, flushed when full, every flushInterval, on entries above the
+// error level, e.g. Fatal, and on shutdown. Zero values default to 256kB and
+// 30s. This option must be passed before the logger option it applies to.
+func WithBufferedLogOutput(size int, flushInterval time.Duration) Option {
+	return func(s *SVC) error {
+		s.logBuffer = &logBufferConfig{size: size, flushInterval: flushInterval}
+		return nil
+	}
+}
+
+// flushLogs flushes the buffered log entries, including those queued for log
+// sinks, and stops the periodic flushing.
+func (s *SVC) flushLogs() {
+	_ = s.logger.Sync()
+	for _, buf := range s.logBuffers {
+		_ = buf.Stop()
+	}
+}
+
+// WithLogger is an option that allows you to provide your own customized logger,
+// e.g. with its own writers, hooks, field names or sampler, instead of the
+// built-in formats. atom controls its level, e.g. through
+// WithLogLevelHandlers.
 func WithLogger(logger *zap.Logger, atom zap.AtomicLevel) Option {
 	return func(s *SVC) error {
+		s.logLevelSet = true
 		return assignLogger(s, logger, atom)
 	}
 }
@@ -69,7 +132,8 @@ func WithDevelopmentLogger(opts ...zap.Option) Option {
 		s.zapOpts = append(s.zapOpts, opts...)
 		logger, atom := s.newLogger(
 			zapcore.DebugLevel,
-			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zap.NewProductionEncoderConfig(),
+			zapcore.NewJSONEncoder,
 		)
 		logger = logger.With(zap.String("app", s.Name), zap.String("version", s.Version))
 		return assignLogger(s, logger, atom)
@@ -83,7 +147,8 @@ func WithProductionLogger(opts ...zap.Option) Option {
 		s.zapOpts = append(s.zapOpts, opts...)
 		logger, atom := s.newLogger(
 			zapcore.InfoLevel,
-			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zap.NewProductionEncoderConfig(),
+			zapcore.NewJSONEncoder,
 		)
 		logger = logger.With(zap.String("app", s.Name), zap.String("version", s.Version))
 		return assignLogger(s, logger, atom)
@@ -100,8 +165,10 @@ func WithConsoleLogger(level zapcore.Level, opts ...zap.Option) Option {
 
 		logger, atom := s.newLogger(
 			level,
-			zapcore.NewConsoleEncoder(config),
+			config,
+			zapcore.NewConsoleEncoder,
 		)
+		s.logLevelSet = true
 		return assignLogger(s, logger, atom)
 	}
 }
@@ -113,9 +180,11 @@ func WithStackdriverLogger(level zapcore.Level, opts ...zap.Option) Option {
 		s.zapOpts = append(s.zapOpts, opts...)
 		logger, atom := s.newLogger(
 			level,
-			zapcore.NewJSONEncoder(zapdriver.NewProductionEncoderConfig()),
+			zapdriver.NewProductionEncoderConfig(),
+			zapcore.NewJSONEncoder,
 		)
 		logger = logger.With(zapdriver.ServiceContext(s.Name), zapdriver.Label("version", s.Version))
+		s.logLevelSet = true
 		return assignLogger(s, logger, atom)
 	}
 }
@@ -137,3 +206,112 @@ func assignLogger(s *SVC, logger *zap.Logger, atom zap.AtomicLevel) error {
 
 	return nil
 }
+
+// LoggingProfile defines a logger preset.
+type LoggingProfile string
+
+// Logging profiles.
+const (
+	// LoggingProfileProduction logs sampled JSON from the info level.
+	LoggingProfileProduction LoggingProfile = "production"
+	// LoggingProfileDevelopment logs unsampled console output from the debug
+	// level.
+	LoggingProfileDevelopment LoggingProfile = "development"
+	// LoggingProfileSilent logs sampled JSON from the error level only, e.g.
+	// for test runs.
+	LoggingProfileSilent LoggingProfile = "silent"
+)
+
+type loggingProfileConfig struct {
+	Profile LoggingProfile `env:"SVC_LOGGING_PROFILE" validate:"omitempty,oneof=production development silent"`
+}
+
+// WithLoggingProfile is an option that uses a logger preset. The
+// SVC_LOGGING_PROFILE environment variable, if set, takes precedence, e.g. to
+// silence test runs.
+func WithLoggingProfile(profile LoggingProfile, opts ...zap.Option) Option {
+	return func(s *SVC) error {
+		var cfg loggingProfileConfig
+		if err := LoadFromEnv(&cfg); err != nil {
+			return err
+		}
+		if cfg.Profile != "" {
+			profile = cfg.Profile
+		}
+
+		s.zapOpts = append(s.zapOpts, opts...)
+		var logger *zap.Logger
+		var atom zap.AtomicLevel
+		switch profile {
+		case LoggingProfileProduction:
+			logger, atom = s.newLogger(zapcore.InfoLevel, zap.NewProductionEncoderConfig(), zapcore.NewJSONEncoder)
+		case LoggingProfileDevelopment:
+			config := zap.NewDevelopmentEncoderConfig()
+			config.EncodeTime = zapcore.RFC3339TimeEncoder
+			logger, atom = s.newSampledLogger(zapcore.DebugLevel, config, zapcore.NewConsoleEncoder, false)
+		case LoggingProfileSilent:
+			logger, atom = s.newLogger(zapcore.ErrorLevel, zap.NewProductionEncoderConfig(), zapcore.NewJSONEncoder)
+		default:
+			return fmt.Errorf("unknown logging profile %q", profile)
+		}
+		logger = logger.With(zap.String("app", s.Name), zap.String("version", s.Version))
+		s.logLevelSet = true
+		return assignLogger(s, logger, atom)
+	}
+}
+
+// WithWorkerLogLevel is an option that sets the minimum level of the named
+// worker's logger, e.g. to quiet a chatty worker without raising the service's
+// level. The service's level still applies, thus the worker's level can only
+// make it quieter. It can be changed at runtime with SetWorkerLogLevel.
+func WithWorkerLogLevel(name string, level zapcore.Level) Option {
+	return func(s *SVC) error {
+		s.workerLogLevels[name] = zap.NewAtomicLevelAt(level)
+
+		return nil
+	}
+}
+
+// SetWorkerLogLevel changes the level set by WithWorkerLogLevel of the named
+// worker at runtime.
+func (s *SVC) SetWorkerLogLevel(name string, level zapcore.Level) error {
+	atom, ok := s.workerLogLevels[name]
+	if !ok {
+		return fmt.Errorf("worker %s has no log level set", name)
+	}
+	atom.SetLevel(level)
+	s.logger.Info("Worker log level changed", zap.String("worker", name), zap.Stringer("level", level))
+	return nil
+}
+
+// workerLogger returns the logger passed to the named worker.
+func (s *SVC) workerLogger(name string) *zap.Logger {
+	logger := s.logger.Named(name)
+	if atom, ok := s.workerLogLevels[name]; ok {
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &levelCore{Core: core, level: atom}
+		}))
+	}
+	return logger
+}
+
+// levelCore filters the entries of a core by an additional level.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -1,7 +1,10 @@
 package svc
 
 import (
+	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -59,3 +62,102 @@ func TestNewLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestWithLoggerEncoderConfig(t *testing.T) {
+	var keys []string
+	_, err := New("dummy-name", "dummy-version",
+		WithLoggerEncoderConfig(func(config *zapcore.EncoderConfig) {
+			config.MessageKey = "message"
+		}),
+		WithLoggerEncoderConfig(func(config *zapcore.EncoderConfig) {
+			keys = append(keys, config.MessageKey)
+		}),
+		WithProductionLogger(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"message"}, keys)
+}
+
+func TestWithLoggingProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       LoggingProfile
+		env           string
+		expectedLevel zapcore.Level
+		expectedError string
+	}{
+		{name: "production", profile: LoggingProfileProduction, expectedLevel: zapcore.InfoLevel},
+		{name: "development", profile: LoggingProfileDevelopment, expectedLevel: zapcore.DebugLevel},
+		{name: "silent", profile: LoggingProfileSilent, expectedLevel: zapcore.ErrorLevel},
+		{name: "env override", profile: LoggingProfileDevelopment, env: "silent", expectedLevel: zapcore.ErrorLevel},
+		{name: "unknown profile", profile: "verbose", expectedError: `unknown logging profile "verbose"`},
+		{name: "unknown env profile", profile: LoggingProfileSilent, env: "verbose", expectedError: "invalid configuration: Profile: failed on the 'oneof=production development silent' validation"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.env != "" {
+				t.Setenv("SVC_LOGGING_PROFILE", tc.env)
+			}
+			s, err := New("dummy-service", "v0.0.0", WithLoggingProfile(tc.profile))
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLevel, s.atom.Level())
+		})
+	}
+}
+
+func TestWithBufferedLogOutput(t *testing.T) {
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	s, err := New("dummy-service", "v0.0.0",
+		WithBufferedLogOutput(0, time.Hour),
+		WithProductionLogger(),
+	)
+	require.NoError(t, err)
+	s.logger.Info("buffered entry")
+
+	logged, err := os.ReadFile(out.Name())
+	require.NoError(t, err)
+	require.NotContains(t, string(logged), "buffered entry")
+
+	s.flushLogs()
+	logged, err = os.ReadFile(out.Name())
+	require.NoError(t, err)
+	require.Contains(t, string(logged), "buffered entry")
+}
+
+func TestWithWorkerLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	atom := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), atom))
+	s, err := New("dummy-service", "v0.0.0", WithLogger(logger, atom), WithWorkerLogLevel("noisy", zapcore.WarnLevel))
+	require.NoError(t, err)
+
+	noisy, other := s.workerLogger("noisy").With(zap.Int("n", 1)), s.workerLogger("other")
+	noisy.Info("noisy info")
+	noisy.Warn("noisy warn")
+	other.Debug("other debug")
+	require.NotContains(t, buf.String(), "noisy info")
+	require.Contains(t, buf.String(), "noisy warn")
+	require.Contains(t, buf.String(), "other debug")
+
+	require.NoError(t, s.SetWorkerLogLevel("noisy", zapcore.InfoLevel))
+	noisy.Info("noisy info")
+	require.Contains(t, buf.String(), "noisy info")
+
+	// The service's level still applies.
+	s.SetLogLevel(zapcore.ErrorLevel)
+	noisy.Warn("quiet warn")
+	require.NotContains(t, buf.String(), "quiet warn")
+
+	require.EqualError(t, s.SetWorkerLogLevel("other", zapcore.InfoLevel), "worker other has no log level set")
+}
@@ -0,0 +1,157 @@
+package svc
+
+import (
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const defaultLogSinkBufferSize = 1024
+
+// LogSinkOption defines WithLogSink's option type.
+type LogSinkOption func(*logSink)
+
+// LogSinkBufferSize sets the number of log entries buffered for the sink.
+// Entries logged while the buffer is full are dropped. Defaults to 1024.
+func LogSinkBufferSize(n int) LogSinkOption {
+	return func(l *logSink) {
+		l.queue = make(chan []byte, n)
+	}
+}
+
+// LogSinkLevel sets the minimum level of the entries written to the sink.
+// Defaults to info.
+func LogSinkLevel(level zapcore.Level) LogSinkOption {
+	return func(l *logSink) {
+		l.level = level
+	}
+}
+
+// WithLogSink is an option that tees the service's logs to a secondary sink,
+// e.g. a Kafka topic or syslog, for consumers such as audit teams. The sink
+// receives one JSON-encoded entry per Write from a worker named
+// "internal-log-sink-" followed by the name, so slow sinks never block logging:
+// entries are dropped when the buffer is full or the write fails, and counted in
+// the svc_log_sink_dropped_total metric. The sink is closed on shutdown if it
+// implements io.Closer.
+//
+// This option must be passed after the logger option it applies to.
+func WithLogSink(name string, sink io.Writer, opts ...LogSinkOption) Option {
+	return func(s *SVC) error {
+		l := &logSink{
+			name:    name,
+			sink:    sink,
+			level:   zapcore.InfoLevel,
+			queue:   make(chan []byte, defaultLogSinkBufferSize),
+			done:    make(chan struct{}),
+			metrics: s.metrics,
+		}
+		for _, o := range opts {
+			o(l)
+		}
+
+		config := zap.NewProductionEncoderConfig()
+		for _, fn := range s.encoderConfigFuncs {
+			fn(&config)
+		}
+		core := zapcore.NewCore(zapcore.NewJSONEncoder(config), zapcore.AddSync(l), l.level).
+			With([]zap.Field{zap.String("app", s.Name), zap.String("version", s.Version)})
+		tee := zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(c, core)
+		})
+		if err := assignLogger(s, s.logger.WithOptions(tee), s.atom); err != nil {
+			return err
+		}
+
+		s.AddWorker("internal-log-sink-"+name, l)
+
+		return nil
+	}
+}
+
+var _ Worker = (*logSink)(nil)
+
+// logSink defines the internal worker delivering log entries to a sink.
+type logSink struct {
+	name    string
+	sink    io.Writer
+	level   zapcore.Level
+	queue   chan []byte
+	done    chan struct{}
+	metrics *metrics
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Write implements the io.Writer interface, queuing the entry without
+// blocking.
+func (l *logSink) Write(p []byte) (int, error) {
+	entry := append([]byte{}, p...)
+	select {
+	case l.queue <- entry:
+	default:
+		l.metrics.logSinkDropped.WithLabelValues(l.name, "buffer_full").Inc()
+	}
+	return len(p), nil
+}
+
+// Init implements the Worker interface.
+func (l *logSink) Init(*zap.Logger) error {
+	return nil
+}
+
+// Run implements the Worker interface.
+func (l *logSink) Run() error {
+	for {
+		select {
+		case entry := <-l.queue:
+			l.write(entry)
+		case <-l.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface. It delivers the buffered entries
+// before closing the sink.
+func (l *logSink) Terminate() error {
+	close(l.done)
+	_ = l.Sync()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if c, ok := l.sink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Sync implements the zapcore.WriteSyncer interface, delivering the queued
+// entries, so they are not lost when the process exits right after, e.g. on
+// Fatal.
+func (l *logSink) Sync() error {
+	for {
+		select {
+		case entry := <-l.queue:
+			l.write(entry)
+		default:
+			return nil
+		}
+	}
+}
+
+func (l *logSink) write(entry []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	// Errors are not logged as the entry would be teed to the failing sink.
+	if _, err := l.sink.Write(entry); err != nil {
+		l.metrics.logSinkDropped.WithLabelValues(l.name, "write_error").Inc()
+	}
+}
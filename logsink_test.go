@@ -0,0 +1,82 @@
+package svc
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type dummySink struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	err    error
+	closed bool
+}
+
+func (s *dummySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.buf.Write(p)
+}
+
+func (s *dummySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestWithLogSink(t *testing.T) {
+	sink := &dummySink{}
+	s, err := New("dummy-service", "v0.0.0", WithLogSink("audit", sink))
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(logger *zap.Logger) error {
+			logger.Info("audit entry")
+			logger.Debug("debug entry")
+			return nil
+		},
+		RunFunc:       func() error { s.Shutdown(); return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	s.Run()
+
+	assert.True(t, sink.closed)
+	assert.Contains(t, sink.buf.String(), `"msg":"audit entry"`)
+	assert.Contains(t, sink.buf.String(), `"app":"dummy-service"`)
+	assert.NotContains(t, sink.buf.String(), "debug entry")
+}
+
+func TestLogSinkDrops(t *testing.T) {
+	sink := &dummySink{err: errors.New("dummy error")}
+	s, err := New("dummy-service", "v0.0.0", WithLogSink("audit", sink, LogSinkBufferSize(1)))
+	require.NoError(t, err)
+
+	// The buffer is already filled by the entries logged while adding the worker.
+	dropped := s.metrics.logSinkDropped.WithLabelValues("audit", "buffer_full")
+	before := testutil.ToFloat64(dropped)
+	s.logger.Info("dropped")
+	assert.Equal(t, before+1, testutil.ToFloat64(dropped))
+
+	require.NoError(t, s.workers["internal-log-sink-audit"].Terminate())
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.logSinkDropped.WithLabelValues("audit", "write_error")))
+}
+
+func TestLogSinkSyncOnFatal(t *testing.T) {
+	sink := &dummySink{}
+	s, err := New("dummy-service", "v0.0.0", WithLogSink("audit", sink))
+	require.NoError(t, err)
+
+	// Entries above the error level sync the cores, as Fatal does before
+	// exiting, while the sink's worker isn't running.
+	s.logger.DPanic("last words")
+
+	assert.Contains(t, sink.buf.String(), `"msg":"last words"`)
+}
@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maintenance defines the state of the maintenance mode.
+type maintenance struct {
+	mu         sync.Mutex
+	until      time.Time
+	reason     string
+	timer      *time.Timer
+	exclusions []string
+}
+
+// WithMaintenanceExclusions is an option that keeps serving the given user
+// routes in maintenance mode, e.g. read-only ones. A path ending in a slash
+// excludes the whole subtree, like http.ServeMux patterns.
+func WithMaintenanceExclusions(paths ...string) Option {
+	return func(s *SVC) error {
+		s.maintenance.exclusions = paths
+
+		return nil
+	}
+}
+
+// EnterMaintenance puts the service in maintenance mode for d, e.g. while
+// reindexing: it is not ready, and user routes respond 503 Service Unavailable
+// with a Retry-After header until the window ends, see
+// WithMaintenanceExclusions. The routes registered by options, such as the
+// probes and metrics, are still served. Entering maintenance again replaces
+// the window.
+func (s *SVC) EnterMaintenance(d time.Duration, reason string) error {
+	if d <= 0 {
+		return errors.New("maintenance window must be positive")
+	}
+
+	m := &s.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.until, m.reason = time.Now().Add(d), reason
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		// Replaced by a later window.
+		if m.timer == timer {
+			s.exitMaintenance()
+		}
+	})
+	m.timer = timer
+	s.setNotReady("maintenance", reason)
+	s.logger.Warn("Entered maintenance", zap.Duration("window", d), zap.String("reason", reason))
+	return nil
+}
+
+// ExitMaintenance ends the maintenance mode before the window ends.
+func (s *SVC) ExitMaintenance() {
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+
+	if s.maintenance.timer != nil {
+		s.maintenance.timer.Stop()
+		s.exitMaintenance()
+	}
+}
+
+// exitMaintenance ends the maintenance mode. The lock must be held.
+func (s *SVC) exitMaintenance() {
+	s.maintenance.timer = nil
+	s.clearNotReady("maintenance")
+	s.logger.Info("Exited maintenance", zap.String("reason", s.maintenance.reason))
+}
+
+// maintenanceMiddleware responds 503 to the user routes in maintenance mode.
+func (s *SVC) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := &s.maintenance
+		m.mu.Lock()
+		active, until, reason := m.timer != nil, m.until, m.reason
+		m.mu.Unlock()
+		if !active || !s.maintained(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retryAfter := math.Ceil(time.Until(until).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(retryAfter, 1))))
+		http.Error(w, "in maintenance: "+reason, http.StatusServiceUnavailable)
+	})
+}
+
+// maintained returns whether the request is to a user route not excluded from
+// the maintenance mode.
+func (s *SVC) maintained(r *http.Request) bool {
+	if s.isOptionRoute(r) {
+		return false
+	}
+	for _, p := range s.maintenance.exclusions {
+		if r.URL.Path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(r.URL.Path, p)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnterMaintenance(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithMaintenanceExclusions("/api/status"))
+	require.NoError(t, err)
+	s.HandleFunc("/api/", func(http.ResponseWriter, *http.Request) {})
+	s.self.setInitialized()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.serveHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	require.EqualError(t, s.EnterMaintenance(0, "reindexing"), "maintenance window must be positive")
+	require.NoError(t, s.EnterMaintenance(time.Hour, "reindexing"))
+	rec := serve("/api/users")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "3600", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "in maintenance: reindexing")
+	assert.Equal(t, http.StatusOK, serve("/api/status").Code)
+	assert.Equal(t, http.StatusOK, serve("/live").Code)
+	assert.Equal(t, "maintenance: reindexing", s.self.CheckHealth().Detail)
+
+	s.ExitMaintenance()
+	assert.Equal(t, http.StatusOK, serve("/api/users").Code)
+	assert.Equal(t, HealthOK, s.self.CheckHealth().Status, s.self.CheckHealth().Detail)
+
+	// The window ends automatically.
+	require.NoError(t, s.EnterMaintenance(20*time.Millisecond, "reindexing"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/api/users").Code)
+	require.Eventually(t, func() bool {
+		return serve("/api/users").Code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,40 @@
+package svc
+
+import (
+	"math"
+	"os"
+	"runtime"
+
+	"go.uber.org/zap"
+)
+
+// WithAutoMaxProcs is an option that sets GOMAXPROCS to the CPU quota of the
+// process' cgroup, rounded down to at least 1, as the Go runtime defaults to the
+// number of CPUs of the host and containers get throttled. An explicit
+// GOMAXPROCS environment variable takes precedence.
+func WithAutoMaxProcs() Option {
+	return func(s *SVC) error {
+		if v, ok := os.LookupEnv("GOMAXPROCS"); ok {
+			s.logger.Info("GOMAXPROCS set by environment", zap.String("gomaxprocs", v))
+			return nil
+		}
+		quota, ok := cgroupCPUQuota()
+		if !ok {
+			s.logger.Info("No CPU quota detected, leaving GOMAXPROCS unchanged",
+				zap.Int("gomaxprocs", runtime.GOMAXPROCS(0)))
+			return nil
+		}
+
+		procs := maxProcs(quota)
+		prev := runtime.GOMAXPROCS(procs)
+		s.logger.Info("Set GOMAXPROCS from CPU quota",
+			zap.Float64("cpu_quota", quota), zap.Int("gomaxprocs", procs), zap.Int("previous", prev))
+
+		return nil
+	}
+}
+
+// maxProcs returns the GOMAXPROCS value for the CPU quota.
+func maxProcs(quota float64) int {
+	return int(math.Max(1, math.Floor(quota)))
+}
@@ -0,0 +1,32 @@
+package svc
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxProcs(t *testing.T) {
+	assert.Equal(t, 1, maxProcs(0.5))
+	assert.Equal(t, 1, maxProcs(1.5))
+	assert.Equal(t, 4, maxProcs(4))
+}
+
+func TestWithAutoMaxProcs(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	root := cgroupRoot
+	defer func() { cgroupRoot = root }()
+	cgroupRoot = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("250000 100000\n"), 0o600))
+
+	if _, ok := os.LookupEnv("GOMAXPROCS"); ok {
+		t.Skip("GOMAXPROCS set by environment")
+	}
+	_, err := New("dummy-service", "v0.0.0", WithAutoMaxProcs())
+	require.NoError(t, err)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+}
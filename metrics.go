@@ -0,0 +1,241 @@
+package svc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics holds the metrics of the framework itself.
+type metrics struct {
+	deadLetters       *prometheus.CounterVec
+	workerHealth      *prometheus.GaugeVec
+	singleflightCalls *prometheus.CounterVec
+	grpcClientDials   *prometheus.CounterVec
+	logSinkDropped    *prometheus.CounterVec
+	kubeEventsDropped *prometheus.CounterVec
+	dependencyUp      *prometheus.GaugeVec
+	shadowComparisons *prometheus.CounterVec
+	workerInitSeconds *prometheus.GaugeVec
+	probeFailures     *prometheus.CounterVec
+	heapDumps         *prometheus.CounterVec
+	workerRestarts    *prometheus.CounterVec
+	clientAttempts    *prometheus.CounterVec
+	clientHedgeWins   *prometheus.CounterVec
+	outboxEvents      *prometheus.CounterVec
+	outboxLag         *prometheus.GaugeVec
+	workerPanics      *prometheus.CounterVec
+	recentCrashes     prometheus.Gauge
+	deadlineExhausted prometheus.Counter
+	httpRateLimited   prometheus.Counter
+	httpPanics        prometheus.Counter
+	featureDegraded   *prometheus.GaugeVec
+
+	shutdownDuration            prometheus.Gauge
+	shutdownWorkersExceeded     prometheus.Gauge
+	shutdownGracePeriodExceeded prometheus.Gauge
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		deadLetters: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_dead_letters_total",
+				Help: "Number of messages handed to the dead-letter sink.",
+			},
+			[]string{"source", "result"},
+		),
+		workerHealth: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "svc_worker_health_status",
+				Help: "Last health check status per worker: 0 ok, 1 warn, 2 critical.",
+			},
+			[]string{"worker"},
+		),
+		singleflightCalls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_singleflight_calls_total",
+				Help: "Number of single-flight calls, either executing (leader) or sharing (shared) the result.",
+			},
+			[]string{"group", "result"},
+		),
+		grpcClientDials: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_grpc_client_dials_total",
+				Help: "Number of gRPC client connections dialed, by target and result.",
+			},
+			[]string{"target", "result"},
+		),
+		logSinkDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_log_sink_dropped_total",
+				Help: "Number of log entries dropped by a log sink, by reason.",
+			},
+			[]string{"sink", "reason"},
+		),
+		kubeEventsDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_kubernetes_events_dropped_total",
+				Help: "Number of Kubernetes events not posted, by reason.",
+			},
+			[]string{"reason"},
+		),
+		dependencyUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "svc_dependency_up",
+				Help: "Whether the last check of a critical dependency succeeded.",
+			},
+			[]string{"dependency"},
+		),
+		shadowComparisons: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_shadow_comparisons_total",
+				Help: "Number of outputs of primary and shadow workers compared, by result: match, diverged, or unmatched.",
+			},
+			[]string{"shadow", "result"},
+		),
+		workerInitSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "svc_worker_init_duration_seconds",
+				Help: "Duration of the last initialization of a worker, including retries.",
+			},
+			[]string{"worker"},
+		),
+		probeFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_probe_failures_total",
+				Help: "Number of failed liveness (live) and readiness (ready) checks, by worker.",
+			},
+			[]string{"probe", "worker"},
+		),
+		heapDumps: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_heap_dumps_total",
+				Help: "Number of heap profiles dumped on high memory usage, by result.",
+			},
+			[]string{"result"},
+		),
+		workerRestarts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_worker_restarts_total",
+				Help: "Number of times a worker was restarted according to its restart policy.",
+			},
+			[]string{"worker"},
+		),
+		clientAttempts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_client_attempts_total",
+				Help: "Number of call attempts made by a caller, by kind: first, retry or hedge.",
+			},
+			[]string{"client", "kind"},
+		),
+		clientHedgeWins: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_client_hedge_wins_total",
+				Help: "Number of calls won by a hedged attempt.",
+			},
+			[]string{"client"},
+		),
+		outboxEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_outbox_events_total",
+				Help: "Number of outbox events relayed, by result: published or error.",
+			},
+			[]string{"outbox", "result"},
+		),
+		outboxLag: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "svc_outbox_lag_seconds",
+				Help: "Age of the oldest pending outbox event, as of the last poll.",
+			},
+			[]string{"outbox"},
+		),
+		workerPanics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "svc_worker_panics_total",
+				Help: "Number of panics recovered from workers.",
+			},
+			[]string{"worker"},
+		),
+		recentCrashes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "svc_recent_crashes",
+			Help: "Number of crashes within the crash marker's window, as of startup.",
+		}),
+		deadlineExhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_http_deadline_exhausted_total",
+			Help: "Number of requests rejected because their deadline budget was exhausted.",
+		}),
+		httpRateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_http_rate_limited_total",
+			Help: "Number of requests rejected by the rate limit.",
+		}),
+		httpPanics: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "svc_http_panics_total",
+			Help: "Number of panics recovered from HTTP handlers.",
+		}),
+		featureDegraded: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "svc_feature_degraded",
+				Help: "Whether the feature registered with Degrade is degraded.",
+			},
+			[]string{"feature"},
+		),
+		shutdownDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "svc_shutdown_duration_seconds",
+			Help: "Duration of the previous run's workers termination, recorded by WithCrashMarker.",
+		}),
+		shutdownWorkersExceeded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "svc_shutdown_workers_exceeding_deadline",
+			Help: "Number of workers not terminated within the grace period during the previous run's termination, recorded by WithCrashMarker.",
+		}),
+		shutdownGracePeriodExceeded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "svc_shutdown_grace_period_exceeded",
+			Help: "Whether the previous run's workers termination hit the grace period, recorded by WithCrashMarker.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.deadLetters,
+		m.workerHealth,
+		m.singleflightCalls,
+		m.grpcClientDials,
+		m.logSinkDropped,
+		m.kubeEventsDropped,
+		m.dependencyUp,
+		m.shadowComparisons,
+		m.workerInitSeconds,
+		m.probeFailures,
+		m.heapDumps,
+		m.workerRestarts,
+		m.clientAttempts,
+		m.clientHedgeWins,
+		m.outboxEvents,
+		m.outboxLag,
+		m.workerPanics,
+		m.deadlineExhausted,
+		m.httpRateLimited,
+		m.httpPanics,
+		m.featureDegraded,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// registerCrashMarker registers the metrics recorded by WithCrashMarker only,
+// not to export constant zeroes without it.
+func (m *metrics) registerCrashMarker(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.recentCrashes,
+		m.shutdownDuration,
+		m.shutdownWorkersExceeded,
+		m.shutdownGracePeriodExceeded,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
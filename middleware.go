@@ -0,0 +1,153 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header carrying a request's ID, see
+// WithHTTPRequestID.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// Use adds the middlewares to the internal HTTP server: they apply to every
+// request it serves, including the routes registered by options, in added
+// order, i.e. the first added is the outermost. They must be added before the
+// service runs: once it started, or served a request, the call logs
+// ErrServiceStarted and the middlewares are ignored.
+func (s *SVC) Use(mws ...func(http.Handler) http.Handler) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.started || s.handler != nil {
+		s.logger.Error("Middlewares added once the service started, ignoring them",
+			zap.Int("middlewares", len(mws)), zap.Error(ErrServiceStarted), zap.Stack("stacktrace"))
+		return
+	}
+	s.middlewares = append(s.middlewares, mws...)
+}
+
+// WithHTTPMiddleware is an option that adds the middlewares to the internal
+// HTTP server, see Use.
+func WithHTTPMiddleware(mws ...func(http.Handler) http.Handler) Option {
+	return func(s *SVC) error {
+		s.Use(mws...)
+
+		return nil
+	}
+}
+
+// WithHTTPRequestID is an option that identifies each request by its
+// X-Request-Id header, or else a generated ULID. The ID is set on the
+// response, and on the request's context, see RequestIDFromContext.
+func WithHTTPRequestID() Option {
+	return func(s *SVC) error {
+		s.Use(requestIDMiddleware)
+
+		return nil
+	}
+}
+
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = NewULID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the ID of the request the context belongs to,
+// if identified with WithHTTPRequestID, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithHTTPRequestLogging is an option that logs each request served by the
+// internal HTTP server, with its status, duration, and ID, if identified with
+// WithHTTPRequestID. Server errors are logged as warnings. Paths excluded from
+// instrumentation are not logged, see WithInstrumentationExclusions.
+func WithHTTPRequestLogging() Option {
+	return func(s *SVC) error {
+		s.Use(s.requestLoggingMiddleware)
+
+		return nil
+	}
+}
+
+func (s *SVC) requestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.instrumented(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", rec.code),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+		}
+		// The request ID is set on the response even if identified by an
+		// inner middleware.
+		if id := rec.Header().Get(RequestIDHeader); id != "" {
+			fields = append(fields, zap.String("request_id", id))
+		}
+		if rec.code >= http.StatusInternalServerError {
+			s.logger.Warn("HTTP request served", fields...)
+			return
+		}
+		s.logger.Info("HTTP request served", fields...)
+	})
+}
+
+// WithHTTPPanicRecovery is an option that recovers from panics in the internal
+// HTTP server's handlers, responding 500 Internal Server Error rather than
+// dropping the connection. Panics are logged with their stack and counted in
+// the svc_http_panics_total metric. Add it after the middlewares that should
+// see the 500 response, e.g. WithHTTPRequestLogging.
+func WithHTTPPanicRecovery() Option {
+	return func(s *SVC) error {
+		s.Use(s.panicRecoveryMiddleware)
+
+		return nil
+	}
+}
+
+func (s *SVC) panicRecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				// Aborting the response on purpose.
+				panic(p)
+			}
+			s.metrics.httpPanics.Inc()
+			s.logger.Error("HTTP handler panicked",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Any("panic", p),
+				zap.ByteString("stack", debug.Stack()))
+			if !rec.wroteHeader {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
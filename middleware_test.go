@@ -0,0 +1,159 @@
+package svc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestUse(t *testing.T) {
+	var calls []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHTTPMiddleware(mw("option")))
+	require.NoError(t, err)
+	s.Use(mw("first"), mw("second"))
+	s.self.setInitialized()
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(rec, httptest.NewRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"option", "first", "second"}, calls)
+
+	// Ignored once serving.
+	calls = nil
+	s.Use(mw("late"))
+	s.serveHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, []string{"option", "first", "second"}, calls)
+}
+
+func TestUseStarted(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	require.NoError(t, s.RunE())
+
+	s.Use(func(next http.Handler) http.Handler { return next })
+	assert.Empty(t, s.middlewares)
+}
+
+func TestWithHTTPRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		givenID    string
+		expectedID string
+	}{
+		{
+			name:       "propagated",
+			givenID:    "dummy-request",
+			expectedID: "dummy-request",
+		},
+		{
+			name: "generated",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithHTTPRequestID())
+			require.NoError(t, err)
+			s.self.setInitialized()
+			var fromContext string
+			s.HandleFunc("/data", func(_ http.ResponseWriter, r *http.Request) {
+				fromContext = RequestIDFromContext(r.Context())
+			})
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/data", nil)
+			req.Header.Set(RequestIDHeader, tc.givenID)
+			s.serveHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, id)
+			} else {
+				assert.Len(t, id, 26)
+			}
+			assert.Equal(t, id, fromContext)
+		})
+	}
+}
+
+func TestWithHTTPRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	atom := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), atom))
+	s, err := New("dummy-service", "v0.0.0",
+		WithLogger(logger, atom),
+		WithHealthz(),
+		WithInstrumentationExclusions("/live"),
+		WithHTTPRequestLogging(),
+		WithHTTPRequestID())
+	require.NoError(t, err)
+	s.self.setInitialized()
+	s.HandleFunc("/fail", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	for _, path := range []string{"/live", "/fail"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(RequestIDHeader, "dummy-request")
+		s.serveHTTP(httptest.NewRecorder(), req)
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] == "HTTP request served" {
+			entries = append(entries, entry)
+		}
+	}
+	require.Len(t, entries, 1)
+	assert.Equal(t, "warn", entries[0]["level"])
+	assert.Equal(t, "/fail", entries[0]["path"])
+	assert.Equal(t, float64(http.StatusBadGateway), entries[0]["status"])
+	assert.Equal(t, "dummy-request", entries[0]["request_id"])
+}
+
+func TestWithHTTPPanicRecovery(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHTTPPanicRecovery())
+	require.NoError(t, err)
+	s.self.setInitialized()
+	s.HandleFunc("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("dummy panic")
+	})
+	s.HandleFunc("/abort", func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.httpPanics))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		s.serveHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.httpPanics))
+}
@@ -0,0 +1,112 @@
+package svc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// MTLSPolicy defines how the service's servers authenticate themselves and
+// their clients with mutual TLS.
+type MTLSPolicy struct {
+	// Certificates are the servers' certificates. Ignored if GetCertificate is
+	// set, e.g. to serve rotated certificates.
+	Certificates   []tls.Certificate
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// ClientAuth defaults to tls.RequireAndVerifyClientCert. Use
+	// tls.VerifyClientCertIfGiven to keep serving clients that cannot present
+	// a certificate, e.g. the kubelet's probes.
+	ClientAuth tls.ClientAuthType
+	// VerifySPIFFEID authorizes verified clients by their SPIFFE ID, i.e. the
+	// spiffe:// URI SAN of their certificate, which is empty if they have
+	// none. See AllowSPIFFEIDs.
+	VerifySPIFFEID func(id string) error
+}
+
+// AllowSPIFFEIDs returns an MTLSPolicy.VerifySPIFFEID hook authorizing only
+// the given SPIFFE IDs, e.g. "spiffe://example.org/ns/platform/sa/tooling".
+func AllowSPIFFEIDs(ids ...string) func(id string) error {
+	allowed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return func(id string) error {
+		if !allowed[id] {
+			return fmt.Errorf("SPIFFE ID %q is not allowed", id)
+		}
+		return nil
+	}
+}
+
+// SPIFFEID returns the SPIFFE ID of the certificate, i.e. its spiffe:// URI
+// SAN, if any.
+func SPIFFEID(cert *x509.Certificate) (string, bool) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String(), true
+		}
+	}
+	return "", false
+}
+
+// WithMutualTLS is an option that serves the internal HTTP or multiplexed
+// server over TLS, verifying clients' certificates against caPool as set by
+// policy. The configuration is also available to other servers, e.g. a gRPC
+// server, via MutualTLSConfig.
+func WithMutualTLS(caPool *x509.CertPool, policy MTLSPolicy) Option {
+	return func(s *SVC) error {
+		if caPool == nil {
+			return errors.New("mutual TLS requires a CA pool")
+		}
+		if len(policy.Certificates) == 0 && policy.GetCertificate == nil {
+			return errors.New("mutual TLS requires a server certificate")
+		}
+		s.useMutualTLS(newMutualTLSConfig(caPool, policy))
+
+		return nil
+	}
+}
+
+// newMutualTLSConfig returns the server TLS configuration of the policy.
+func newMutualTLSConfig(caPool *x509.CertPool, policy MTLSPolicy) *tls.Config {
+	clientAuth := policy.ClientAuth
+	if clientAuth == tls.NoClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		Certificates:   policy.Certificates,
+		GetCertificate: policy.GetCertificate,
+		ClientCAs:      caPool,
+		ClientAuth:     clientAuth,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if policy.VerifySPIFFEID == nil || len(cs.PeerCertificates) == 0 {
+				return nil
+			}
+			id, _ := SPIFFEID(cs.PeerCertificates[0])
+			return policy.VerifySPIFFEID(id)
+		},
+	}
+}
+
+// useMutualTLS sets the servers' TLS configuration, including the internal
+// HTTP or multiplexed server's if it was already added.
+func (s *SVC) useMutualTLS(cfg *tls.Config) {
+	s.tlsConfig = cfg
+	if hs, ok := s.workers[internalHTTPServerName].(*httpServer); ok {
+		hs.httpServer.TLSConfig = s.MutualTLSConfig()
+	}
+	if ms, ok := s.workers[internalMultiplexedServerName].(*multiplexServer); ok {
+		ms.httpServer.TLSConfig = s.MutualTLSConfig()
+	}
+}
+
+// MutualTLSConfig returns a copy of the server TLS configuration set by
+// WithMutualTLS, WithSPIFFE or WithHTTPServerTLS, or nil.
+func (s *SVC) MutualTLSConfig() *tls.Config {
+	if s.tlsConfig == nil {
+		return nil
+	}
+	return s.tlsConfig.Clone()
+}
@@ -0,0 +1,120 @@
+package svc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueCert issues a certificate signed by parent, or self-signed if parent is
+// nil.
+func issueCert(t *testing.T, parent *tls.Certificate, spiffeID string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if spiffeID != "" {
+		uri, err := url.Parse(spiffeID)
+		require.NoError(t, err)
+		tmpl.URIs = []*url.URL{uri}
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestWithMutualTLS(t *testing.T) {
+	ca := issueCert(t, nil, "")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	s, err := New("dummy-service", "v0.0.0", WithHTTPServer("0"), WithMutualTLS(pool, MTLSPolicy{
+		Certificates:   []tls.Certificate{issueCert(t, &ca, "")},
+		VerifySPIFFEID: AllowSPIFFEIDs("spiffe://example.org/tooling"),
+	}))
+	require.NoError(t, err)
+	assert.NotNil(t, s.workers[internalHTTPServerName].(*httpServer).httpServer.TLSConfig)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = s.MutualTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		givenSPIFFEID string
+		givenNoCert   bool
+		expectedError bool
+	}{
+		{
+			name:          "should accept allowed SPIFFE IDs",
+			givenSPIFFEID: "spiffe://example.org/tooling",
+		},
+		{
+			name:          "should reject other SPIFFE IDs",
+			givenSPIFFEID: "spiffe://example.org/other",
+			expectedError: true,
+		},
+		{
+			name:          "should reject clients without certificate",
+			givenNoCert:   true,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			clientTLS := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+			if !tc.givenNoCert {
+				clientTLS.Certificates = []tls.Certificate{issueCert(t, &ca, tc.givenSPIFFEID)}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			res, err := client.Get(srv.URL)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_ = res.Body.Close()
+		})
+	}
+}
+
+func TestWithMutualTLSErrors(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithMutualTLS(nil, MTLSPolicy{}))
+	require.EqualError(t, err, "mutual TLS requires a CA pool")
+	_, err = New("dummy-service", "v0.0.0", WithMutualTLS(x509.NewCertPool(), MTLSPolicy{}))
+	require.EqualError(t, err, "mutual TLS requires a server certificate")
+}
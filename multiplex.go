@@ -0,0 +1,237 @@
+package svc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// http2Preface is the connection preface HTTP/2 clients with prior knowledge,
+// such as gRPC clients, start with.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+const sniffTimeout = 5 * time.Second
+
+// internalMultiplexedServerName is the worker name of the internal multiplexed
+// server.
+const internalMultiplexedServerName = "internal-multiplexed-server"
+
+var _ Worker = (*multiplexServer)(nil)
+
+// multiplexServer defines the internal worker serving HTTP/1 and gRPC on the
+// same port, dispatching connections by sniffing the HTTP/2 preface.
+type multiplexServer struct {
+	logger     *zap.Logger
+	addr       string
+	httpServer *http.Server
+	grpcServe  func(net.Listener) error
+	grpcStop   func()
+
+	mu         sync.Mutex
+	terminated bool
+	listener   net.Listener
+}
+
+func newMultiplexServer(port string, handler http.Handler, logger *log.Logger, grpcServe func(net.Listener) error, grpcStop func(), opts ...HTTPOption) *multiplexServer {
+	addr := net.JoinHostPort("", port)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ErrorLog:          logger,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	for _, o := range opts {
+		o(srv)
+	}
+	return &multiplexServer{
+		addr:       addr,
+		httpServer: srv,
+		grpcServe:  grpcServe,
+		grpcStop:   grpcStop,
+	}
+}
+
+// Init implements the Worker interface.
+func (s *multiplexServer) Init(logger *zap.Logger) error {
+	s.logger = logger
+
+	return nil
+}
+
+// Healthy implements the Healther interface.
+func (s *multiplexServer) Healthy() error {
+	return nil
+}
+
+// Run implements the Worker interface.
+func (s *multiplexServer) Run() error {
+	s.mu.Lock()
+	terminated := s.terminated
+	s.mu.Unlock()
+	if terminated {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Error("Failed to listen", zap.Error(err))
+		return err
+	}
+	return s.serve(l)
+}
+
+// Terminate implements the Worker interface.
+func (s *multiplexServer) Terminate() error {
+	s.mu.Lock()
+	s.terminated = true
+	l := s.listener
+	s.mu.Unlock()
+
+	s.grpcStop()
+	err := s.httpServer.Shutdown(context.Background())
+	if l != nil {
+		_ = l.Close()
+	}
+	return err
+}
+
+// serve serves on l, unless terminated meanwhile, over TLS if the HTTP server
+// has a certificate: connections are then dispatched once decrypted.
+func (s *multiplexServer) serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.terminated {
+		s.mu.Unlock()
+		_ = l.Close()
+		return nil
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	if cfg := s.httpServer.TLSConfig; hasCertificate(cfg) {
+		l = tls.NewListener(l, serverTLSConfig(cfg))
+	}
+
+	httpL := newChanListener(l.Addr())
+	grpcL := newChanListener(l.Addr())
+
+	errs := make(chan error, 3)
+	go func() {
+		if err := s.httpServer.Serve(httpL); err != nil && err != http.ErrServerClosed {
+			errs <- err
+		}
+	}()
+	go func() {
+		if err := s.grpcServe(grpcL); err != nil {
+			errs <- err
+		}
+	}()
+
+	s.logger.Info("Listening and serving HTTP and gRPC", zap.String("address", l.Addr().String()))
+	go func() {
+		defer httpL.Close()
+		defer grpcL.Close()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					err = nil
+				}
+				errs <- err
+				return
+			}
+			go dispatchConn(conn, httpL, grpcL)
+		}
+	}()
+
+	if err := <-errs; err != nil {
+		s.logger.Error("Failed to serve HTTP and gRPC", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// dispatchConn hands the connection to the gRPC listener if it starts with the
+// HTTP/2 preface and to the HTTP listener otherwise.
+func dispatchConn(conn net.Conn, httpL, grpcL *chanListener) {
+	_ = conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	r := bufio.NewReader(conn)
+	isHTTP2 := true
+	for i := 1; i <= len(http2Preface); i++ {
+		b, err := r.Peek(i)
+		if err != nil || b[i-1] != http2Preface[i-1] {
+			isHTTP2 = false
+			break
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	c := &peekedConn{Conn: conn, r: r}
+	if isHTTP2 {
+		grpcL.deliver(c)
+	} else {
+		httpL.deliver(c)
+	}
+}
+
+// peekedConn is a connection whose first bytes were buffered while sniffing.
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// chanListener is a net.Listener accepting connections handed to it.
+type chanListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newChanListener(addr net.Addr) *chanListener {
+	return &chanListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *chanListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+// Accept implements the net.Listener interface.
+func (l *chanListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements the net.Listener interface.
+func (l *chanListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr implements the net.Listener interface.
+func (l *chanListener) Addr() net.Addr {
+	return l.addr
+}
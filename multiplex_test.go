@@ -0,0 +1,156 @@
+package svc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMultiplexServer(t *testing.T) {
+	router := http.NewServeMux()
+	router.HandleFunc("/hello", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("world"))
+	})
+
+	grpcConns := make(chan []byte, 1)
+	grpcL := make(chan net.Listener, 1)
+	grpcServe := func(l net.Listener) error {
+		grpcL <- l
+		conn, err := l.Accept()
+		if err != nil {
+			return nil
+		}
+		b := make([]byte, len(http2Preface))
+		_, _ = io.ReadFull(conn, b)
+		grpcConns <- b
+		return nil
+	}
+	grpcStop := func() { _ = (<-grpcL).Close() }
+
+	server := newMultiplexServer("0", router, nil, grpcServe, grpcStop)
+	require.NoError(t, server.Init(zap.NewNop()))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- server.serve(l) }()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/hello")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "world", string(body))
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(http2Preface)
+	require.NoError(t, err)
+	select {
+	case b := <-grpcConns:
+		assert.Equal(t, http2Preface, b)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "gRPC connection was not dispatched")
+	}
+
+	require.NoError(t, server.Terminate())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Server has not been shut down")
+	}
+}
+
+func TestMultiplexServerTLS(t *testing.T) {
+	ca := issueCert(t, nil, "")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	grpcConns := make(chan []byte, 1)
+	stopped := make(chan struct{})
+	grpcServe := func(l net.Listener) error {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil
+		}
+		b := make([]byte, len(http2Preface))
+		_, _ = io.ReadFull(conn, b)
+		grpcConns <- b
+		<-stopped
+		return nil
+	}
+	s, err := New("dummy-service", "v0.0.0",
+		WithMultiplexedServer("0", grpcServe, func() { close(stopped) }, HTTPWriteTimeout(time.Second)),
+		WithMutualTLS(pool, MTLSPolicy{Certificates: []tls.Certificate{issueCert(t, &ca, "")}}),
+		WithHealthz(),
+	)
+	require.NoError(t, err)
+	server := s.workers[internalMultiplexedServerName].(*multiplexServer)
+	assert.Equal(t, time.Second, server.httpServer.WriteTimeout)
+	require.NotNil(t, server.httpServer.TLSConfig)
+	require.NoError(t, server.Init(zap.NewNop()))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- server.serve(l) }()
+
+	clientTLS := &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{issueCert(t, &ca, "")},
+		MinVersion:   tls.VersionTLS12,
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	resp, err := client.Get("https://" + l.Addr().String() + "/live")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = http.Get("http://" + l.Addr().String() + "/live")
+	require.Error(t, err, "plaintext should not be served")
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), clientTLS)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(http2Preface)
+	require.NoError(t, err)
+	select {
+	case b := <-grpcConns:
+		assert.Equal(t, http2Preface, b)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "gRPC connection was not dispatched")
+	}
+
+	require.NoError(t, server.Terminate())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Server has not been shut down")
+	}
+}
+
+func TestMultiplexServerTerminatedBeforeRun(t *testing.T) {
+	server := newMultiplexServer("0", http.NewServeMux(), nil,
+		func(net.Listener) error { return nil }, func() {})
+	require.NoError(t, server.Init(zap.NewNop()))
+	require.NoError(t, server.Terminate())
+	require.NoError(t, server.Run())
+
+	// Serving once terminated closes the listener rather than leaking it.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, server.serve(l))
+	_, err = l.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
@@ -1,10 +1,12 @@
 package svc
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,57 +40,185 @@ func WithTerminationGracePeriod(d time.Duration) Option {
 func WithRouter(router *http.ServeMux) Option {
 	return func(s *SVC) error {
 		s.Router = router
+		s.routes = map[string]string{}
+		return nil
+	}
+}
+
+// WithHandler is an option that serves the requests not matching any route of
+// the HTTP router with the given handler, e.g. a chi, gin, or echo router, while
+// the routes registered by options, e.g. the probes, pprof, and metrics, keep
+// being served. Label its routes in metrics with HTTPMetricsRouteFunc.
+func WithHandler(h http.Handler) Option {
+	return func(s *SVC) error {
+		if h == nil {
+			return errors.New("handler must not be nil")
+		}
+		s.appHandler = h
+		return nil
+	}
+}
+
+// WithWorkersDisabled is an option that disables the named workers: they are
+// neither initialized nor run. Workers are disabled when Run is called, thus
+// the workers do not need to be added yet.
+func WithWorkersDisabled(names ...string) Option {
+	return func(s *SVC) error {
+		for _, name := range names {
+			s.workersDisabled[name] = true
+		}
+
+		return nil
+	}
+}
+
+// WithWorkersDisabledFromEnv is an option that disables the workers listed in
+// the comma-separated WORKERS_DISABLED environment variable, read when Run is
+// called.
+func WithWorkersDisabledFromEnv() Option {
+	return func(s *SVC) error {
+		s.workersDisabledEnv = true
+
 		return nil
 	}
 }
 
 // WithLogLevelHandlers is an option that sets up HTTP routes to read write the
-// log level. This option must be passed after other options that manipulate the
-// logger to have any effect on that logger option.
+// log level of the service's logger, see SetLogLevel.
 func WithLogLevelHandlers() Option {
 	return func(s *SVC) error {
-		s.Router.Handle("/loglevel", s.atom)
+		// Resolve the level on each request, the logger might be replaced by
+		// a later option.
+		s.handle("WithLogLevelHandlers", "/loglevel", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.atom.ServeHTTP(w, r)
+		}))
 
 		return nil
 	}
 }
 
-// WithHTTPServer is an option that adds an internal HTTP server exposing
-// observability routes.
-func WithHTTPServer(port string) Option {
+// WithHTTPServer is an option that adds an internal HTTP server serving
+// s.Router, with the observability routes, on addr, either an address such as
+// "127.0.0.1:8080" or a port such as "8080". Its timeouts can be set with
+// options, e.g. HTTPWriteTimeout.
+func WithHTTPServer(addr string, opts ...HTTPOption) Option {
 	return func(s *SVC) error {
-		httpServer := newHTTPServer(port, s.Router, s.stdLogger)
-		s.AddWorker("internal-http-server", httpServer)
+		httpServer := newHTTPServer(addr, http.HandlerFunc(s.serveHTTP), s.stdLogger, opts...)
+		httpServer.httpServer.TLSConfig = s.MutualTLSConfig()
+		s.AddWorker(internalHTTPServerName, httpServer)
 
 		return nil
 	}
 }
 
-// WithMetrics is an option that exports metrics via prometheus.
+// WithMultiplexedServer is an option that adds an internal server serving both
+// the observability routes over HTTP and gRPC on the same port, for platforms
+// allocating only one port per container. Connections starting with the HTTP/2
+// preface are handed to grpcServe, e.g. a grpc.Server's Serve method, and
+// grpcStop, e.g. its GracefulStop method, is called on termination. Its HTTP
+// timeouts can be set with options, e.g. HTTPWriteTimeout. With WithMutualTLS
+// or WithHTTPServerTLS, TLS is terminated before dispatching, thus the gRPC
+// server must not be configured with TLS credentials.
+func WithMultiplexedServer(port string, grpcServe func(net.Listener) error, grpcStop func(), opts ...HTTPOption) Option {
+	return func(s *SVC) error {
+		server := newMultiplexServer(port, http.HandlerFunc(s.serveHTTP), s.stdLogger, grpcServe, grpcStop, opts...)
+		server.httpServer.TLSConfig = s.MutualTLSConfig()
+		s.AddWorker(internalMultiplexedServerName, server)
+
+		return nil
+	}
+}
+
+// WithMetrics is an option that exports metrics via prometheus. Applying it
+// again is a no-op.
 func WithMetrics() Option {
 	return func(s *SVC) error {
-		m := prometheus.NewGauge(
+		if s.upMetric {
+			return nil
+		}
+		// Registered once running, for its labels not to depend on the
+		// options order.
+		s.upMetric = true
+
+		build := prometheus.NewGauge(
 			prometheus.GaugeOpts{
-				Name:        "svc_up",
-				Help:        "Is the service in this pod up.",
-				ConstLabels: prometheus.Labels{"version": s.Version, "name": s.Name},
+				Name: "svc_build_info",
+				Help: "Build metadata of the service, always 1.",
+				ConstLabels: prometheus.Labels{
+					"name":       s.Name,
+					"version":    s.Version,
+					"module":     s.build.Module,
+					"revision":   s.build.Revision,
+					"dirty":      strconv.FormatBool(s.build.Dirty),
+					"go_version": s.build.GoVersion,
+				},
 			},
 		)
-		m.Set(1)
+		build.Set(1)
+		if err := s.internalRegister.Register(build); err != nil {
+			s.logger.Error("svc_build_info could not register", zap.Error(err))
+		}
 
-		if err := s.internalRegister.Register(m); err != nil {
-			s.logger.Error("svc_up could not register", zap.Error(err))
+		started := prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "svc_start_time_seconds",
+				Help: "Start time of the service since unix epoch in seconds.",
+			},
+		)
+		started.Set(float64(s.startedAt.UnixNano()) / 1e9)
+		if err := s.internalRegister.Register(started); err != nil {
+			s.logger.Error("svc_start_time_seconds could not register", zap.Error(err))
 		}
 
 		return nil
 	}
 }
 
+// registerUpMetric registers the svc_up metric, if enabled with WithMetrics.
+func (s *SVC) registerUpMetric() {
+	if !s.upMetric {
+		return
+	}
+	labels := prometheus.Labels{"version": s.Version, "name": s.Name, "instance_id": s.instanceID}
+	if s.role != "" {
+		labels["role"] = s.role
+	}
+	m := prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "svc_up",
+			Help:        "Is the service in this pod up.",
+			ConstLabels: labels,
+		},
+	)
+	m.Set(1)
+
+	if err := s.internalRegister.Register(m); err != nil {
+		s.logger.Error("svc_up could not register", zap.Error(err))
+	}
+}
+
+// WithPrometheus is an option that serves the metrics of reg, along with the
+// framework's own metrics, on the `/metrics` route. It is a shortcut for
+// WithMetrics and WithMetricsHandler, which it may be combined with.
+func WithPrometheus(reg *prometheus.Registry) Option {
+	return func(s *SVC) error {
+		s.AddGatherer(reg)
+		if err := WithMetrics()(s); err != nil {
+			return err
+		}
+		return WithMetricsHandler()(s)
+	}
+}
+
 // WithMetricsHandler is an option that exposes Prometheus metrics for a
-// Prometheus scraper.
+// Prometheus scraper. Applying it again is a no-op.
 func WithMetricsHandler() Option {
 	return func(s *SVC) error {
-		s.Router.Handle("/metrics",
+		if s.metricsRoute {
+			return nil
+		}
+		s.metricsRoute = true
+		s.handle("WithMetricsHandler", "/metrics",
 			promhttp.InstrumentMetricHandler(
 				s.internalRegister, /* Register */
 				http.HandlerFunc(s.metricsHandler)))
@@ -98,22 +228,38 @@ func WithMetricsHandler() Option {
 }
 
 // WithPProfHandlers is an option that exposes Go's Performance Profiler via
-// HTTP routes.
-func WithPProfHandlers() Option {
+// HTTP routes. By default, profiles are limited to 60 seconds and one capture
+// at a time; see the PProfOption knobs to change these limits.
+func WithPProfHandlers(opts ...PProfOption) Option {
 	return func(s *SVC) error {
+		cfg := pprofConfig{
+			maxDuration:   defaultPProfMaxDuration,
+			maxConcurrent: defaultPProfMaxConcurrent,
+		}
+		for _, o := range opts {
+			o(&cfg)
+		}
+		if cfg.blockProfileRate != nil {
+			runtime.SetBlockProfileRate(*cfg.blockProfileRate)
+		}
+		if cfg.mutexProfileFraction != nil {
+			runtime.SetMutexProfileFraction(*cfg.mutexProfileFraction)
+		}
+		g := newPProfGuard(cfg)
+
 		// See https://github.com/golang/go/blob/master/src/net/http/pprof/pprof.go#L72-L77
-		s.Router.HandleFunc("/debug/pprof/", pprof.Index)
-		s.Router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		s.Router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		s.Router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		s.Router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		s.handle("WithPProfHandlers", "/debug/pprof/", http.HandlerFunc(pprof.Index))
+		s.handle("WithPProfHandlers", "/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+		s.handle("WithPProfHandlers", "/debug/pprof/profile", g.capture(http.HandlerFunc(pprof.Profile), pprofProfileDefaultDuration))
+		s.handle("WithPProfHandlers", "/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+		s.handle("WithPProfHandlers", "/debug/pprof/trace", g.capture(http.HandlerFunc(pprof.Trace), pprofTraceDefaultDuration))
 		// See https://github.com/golang/go/blob/master/src/net/http/pprof/pprof.go#L248-L258
-		s.Router.Handle("/debug/pprof/allocs", pprof.Handler("allocs"))
-		s.Router.Handle("/debug/pprof/block", pprof.Handler("block"))
-		s.Router.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-		s.Router.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-		s.Router.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
-		s.Router.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+		s.handle("WithPProfHandlers", "/debug/pprof/allocs", g.snapshot(pprof.Handler("allocs")))
+		s.handle("WithPProfHandlers", "/debug/pprof/block", g.snapshot(pprof.Handler("block")))
+		s.handle("WithPProfHandlers", "/debug/pprof/goroutine", g.snapshot(pprof.Handler("goroutine")))
+		s.handle("WithPProfHandlers", "/debug/pprof/heap", g.snapshot(pprof.Handler("heap")))
+		s.handle("WithPProfHandlers", "/debug/pprof/mutex", g.snapshot(pprof.Handler("mutex")))
+		s.handle("WithPProfHandlers", "/debug/pprof/threadcreate", g.snapshot(pprof.Handler("threadcreate")))
 
 		return nil
 	}
@@ -124,54 +270,17 @@ func WithPProfHandlers() Option {
 func WithHealthz() Option {
 	return func(s *SVC) error {
 		// Register live probe handler
-		s.Router.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
-			var errs []error
-			for n, w := range s.workers {
-				if hw, ok := w.(Aliver); ok {
-					if err := hw.Alive(); err != nil {
-						errs = append(errs, fmt.Errorf("worker %s: %s", n, err))
-					}
-				}
-			}
-			if len(errs) == 0 {
-				w.Header().Set("Content-Type", "application/json")
-				_, _ = w.Write([]byte(`{"status": "Still Alive!"}`))
-				return
-			}
-
-			s.logger.Warn("liveliness probe failed", zap.Errors("errors", errs))
-			b, err := json.Marshal(map[string]interface{}{"errors": errs})
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = w.Write(b)
-		})
+		s.handle("WithHealthz", "/live", http.HandlerFunc(s.liveHandler))
+
+		// Register startup probe handler
+		s.handle("WithHealthz", "/startup", http.HandlerFunc(s.startupHandler))
 
 		// Register ready probe handler
-		s.Router.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-			var errs []error
-			for n, w := range s.workers {
-				if hw, ok := w.(Healther); ok {
-					if err := hw.Healthy(); err != nil {
-						errs = append(errs, fmt.Errorf("worker %s: %s", n, err))
-					}
-				}
-			}
-			if len(errs) > 0 {
-				s.logger.Warn("Ready check failed", zap.Errors("errors", errs))
-				b, err := json.Marshal(map[string]interface{}{"errors": errs})
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				w.WriteHeader(http.StatusServiceUnavailable)
-				w.Header().Set("Content-Type", "application/json")
-				_, _ = w.Write(b)
-			}
-		})
+		s.handle("WithHealthz", "/ready", http.HandlerFunc(s.readyHandler))
+
+		s.handle("WithHealthz", "/ready/details", http.HandlerFunc(s.readyDetailsHandler))
+
+		s.handle("WithHealthz", "/healthz/workers", http.HandlerFunc(s.workersHandler))
 
 		return nil
 	}
@@ -1,13 +1,19 @@
 package svc
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // nolint: dupl
@@ -121,3 +127,135 @@ func TestHealthy(t *testing.T) {
 		})
 	}
 }
+
+func TestWithPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "dummy_total", Help: "Dummy counter."}))
+	s, err := New("dummy-service", "v0.0.0", WithPrometheus(reg), WithHealthz())
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { s.Shutdown(); return nil },
+		TerminateFunc: func() error { return nil },
+		HealthyFunc:   func() error { return errors.New("dummy error") },
+	})
+	require.NoError(t, s.RunE())
+
+	s.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ready", nil))
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "dummy_total 0")
+	assert.Contains(t, body, `svc_up{instance_id="`+s.InstanceID()+`",name="dummy-service",version="v0.0.0"} 1`)
+	assert.Contains(t, body, `svc_worker_init_duration_seconds{worker="dummy-worker"}`)
+	assert.Contains(t, body, `svc_probe_failures_total{probe="ready",worker="dummy-worker"} 1`)
+}
+
+func TestWithPrometheusCombined(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "dummy_total", Help: "Dummy counter."}))
+	s, err := New("dummy-service", "v0.0.0", WithMetrics(), WithMetricsHandler(), WithPrometheus(reg))
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { s.Shutdown(); return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	require.NoError(t, s.RunE())
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "dummy_total 0")
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "\nsvc_build_info{"))
+}
+
+func TestWithLogLevelHandlers(t *testing.T) {
+	// The handlers apply to the logger set by a later option.
+	s, err := New("dummy-service", "v0.0.0", WithLogLevelHandlers(), WithProductionLogger())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, zapcore.DebugLevel, s.LogLevel())
+
+	s.SetLogLevel(zapcore.WarnLevel)
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	assert.JSONEq(t, `{"level":"warn"}`, rec.Body.String())
+}
+
+func TestWithHTTPServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		addr         string
+		opts         []HTTPOption
+		expectedAddr string
+		expected     func(t *testing.T, srv *http.Server)
+	}{
+		{
+			name:         "port",
+			addr:         "8080",
+			expectedAddr: ":8080",
+			expected: func(t *testing.T, srv *http.Server) {
+				assert.Equal(t, 5*time.Second, srv.ReadHeaderTimeout)
+			},
+		},
+		{
+			name:         "address with timeouts",
+			addr:         "127.0.0.1:8080",
+			opts:         []HTTPOption{HTTPReadTimeout(time.Second), HTTPReadHeaderTimeout(2 * time.Second), HTTPWriteTimeout(3 * time.Second), HTTPIdleTimeout(4 * time.Second)},
+			expectedAddr: "127.0.0.1:8080",
+			expected: func(t *testing.T, srv *http.Server) {
+				assert.Equal(t, time.Second, srv.ReadTimeout)
+				assert.Equal(t, 2*time.Second, srv.ReadHeaderTimeout)
+				assert.Equal(t, 3*time.Second, srv.WriteTimeout)
+				assert.Equal(t, 4*time.Second, srv.IdleTimeout)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithHTTPServer(tc.addr, tc.opts...))
+			require.NoError(t, err)
+
+			srv := s.workers[internalHTTPServerName].(*httpServer).httpServer
+			assert.Equal(t, tc.expectedAddr, srv.Addr)
+			tc.expected(t, srv)
+		})
+	}
+}
+
+func TestWithHandler(t *testing.T) {
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("app " + r.URL.Path))
+	})
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHandler(app))
+	require.NoError(t, err)
+	s.self.setInitialized()
+
+	tests := []struct {
+		path         string
+		expectedBody string
+	}{
+		{path: "/users/42", expectedBody: "app /users/42"},
+		{path: "/", expectedBody: "app /"},
+		{path: "/live", expectedBody: "Still Alive!"},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.serveHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+
+	_, err = New("dummy-service", "v0.0.0", WithHandler(nil))
+	require.Error(t, err)
+}
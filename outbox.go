@@ -0,0 +1,241 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultOutboxBatchSize    = 100
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxMaxBackoff   = time.Minute
+)
+
+// OutboxEvent describes an event written to the outbox in the same
+// transaction as the state change it announces.
+type OutboxEvent struct {
+	// ID identifies the event in the store. It doubles as the idempotency key
+	// consumers deduplicate on, as an event is published again if marking it
+	// published fails.
+	ID        string
+	Key       []byte
+	Payload   []byte
+	Headers   map[string]string
+	CreatedAt time.Time
+}
+
+// OutboxStore defines the storage of the outbox, e.g. a table in the
+// service's database.
+type OutboxStore interface {
+	// Pending returns up to limit events not published yet, oldest first.
+	Pending(ctx context.Context, limit int) ([]OutboxEvent, error)
+	// MarkPublished marks the events with the given IDs as published, e.g.
+	// deleting them.
+	MarkPublished(ctx context.Context, ids []string) error
+}
+
+// OutboxProducer defines where outbox events are published to, e.g. a Kafka
+// topic.
+type OutboxProducer interface {
+	Publish(ctx context.Context, events []OutboxEvent) error
+}
+
+// OutboxProducerFunc is an adapter to allow the use of ordinary functions as
+// OutboxProducer.
+type OutboxProducerFunc func(ctx context.Context, events []OutboxEvent) error
+
+// Publish implements the OutboxProducer interface.
+func (f OutboxProducerFunc) Publish(ctx context.Context, events []OutboxEvent) error {
+	return f(ctx, events)
+}
+
+// OutboxOption defines AddOutboxRelay's option type.
+type OutboxOption func(*outboxRelay)
+
+// OutboxBatchSize sets the maximum number of events published at once.
+// Defaults to 100.
+func OutboxBatchSize(n int) OutboxOption {
+	return func(r *outboxRelay) {
+		r.batchSize = n
+	}
+}
+
+// OutboxPollInterval sets how often the store is polled once the outbox is
+// drained. Defaults to 1s.
+func OutboxPollInterval(d time.Duration) OutboxOption {
+	return func(r *outboxRelay) {
+		r.pollInterval = d
+	}
+}
+
+// OutboxMaxBackoff caps the delay between attempts while failing, which
+// doubles from the poll interval on each consecutive failure. Defaults to 1m.
+func OutboxMaxBackoff(d time.Duration) OutboxOption {
+	return func(r *outboxRelay) {
+		r.maxBackoff = d
+	}
+}
+
+// AddOutboxRelay adds a named worker relaying the events of a transactional
+// outbox: it polls store for pending events, publishes them in batches with
+// producer, and marks them published. Events are delivered at least once, in
+// order, see OutboxEvent.ID. Failures are retried with backoff and reported
+// as a health warning. Events are counted in the svc_outbox_events_total
+// metric, and the age of the oldest pending event in svc_outbox_lag_seconds.
+func (s *SVC) AddOutboxRelay(name string, store OutboxStore, producer OutboxProducer, opts ...OutboxOption) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &outboxRelay{
+		s:            s,
+		name:         name,
+		store:        store,
+		producer:     producer,
+		batchSize:    defaultOutboxBatchSize,
+		pollInterval: defaultOutboxPollInterval,
+		maxBackoff:   defaultOutboxMaxBackoff,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	s.AddWorker(name, r)
+}
+
+var (
+	_ Worker        = (*outboxRelay)(nil)
+	_ HealthChecker = (*outboxRelay)(nil)
+)
+
+// outboxRelay defines the worker relaying outbox events.
+type outboxRelay struct {
+	s            *SVC
+	logger       *zap.Logger
+	name         string
+	store        OutboxStore
+	producer     OutboxProducer
+	batchSize    int
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+
+	mu       sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// Init implements the Worker interface.
+func (r *outboxRelay) Init(logger *zap.Logger) error {
+	r.logger = logger
+	if r.batchSize <= 0 || r.pollInterval <= 0 {
+		return errors.New("outbox batch size and poll interval must be positive")
+	}
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (r *outboxRelay) Run() error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			return nil
+		}
+
+		n, err := r.relay()
+		if err != nil && r.ctx.Err() != nil {
+			return nil
+		}
+		timer.Reset(r.next(n, err))
+	}
+}
+
+// Terminate implements the Worker interface. It cancels the batch in flight,
+// whose events are published again on the next start.
+func (r *outboxRelay) Terminate() error {
+	r.cancel()
+
+	return nil
+}
+
+// CheckHealth implements the HealthChecker interface.
+func (r *outboxRelay) CheckHealth() HealthResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures == 0 {
+		return HealthResult{Status: HealthOK, CheckedAt: time.Now()}
+	}
+	return HealthResult{
+		Status:    HealthWarn,
+		Detail:    fmt.Sprintf("failed %d consecutive times: %s", r.failures, r.lastErr),
+		CheckedAt: time.Now(),
+	}
+}
+
+// relay publishes a batch of pending events and returns its size.
+func (r *outboxRelay) relay() (int, error) {
+	events, err := r.store.Pending(r.ctx, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("fetch pending events: %w", err)
+	}
+	lag := 0.0
+	if len(events) > 0 && !events[0].CreatedAt.IsZero() {
+		lag = time.Since(events[0].CreatedAt).Seconds()
+	}
+	r.s.metrics.outboxLag.WithLabelValues(r.name).Set(lag)
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := r.producer.Publish(r.ctx, events); err != nil {
+		r.s.metrics.outboxEvents.WithLabelValues(r.name, "error").Add(float64(len(events)))
+		return 0, fmt.Errorf("publish events: %w", err)
+	}
+	r.s.metrics.outboxEvents.WithLabelValues(r.name, "published").Add(float64(len(events)))
+
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if err := r.store.MarkPublished(r.ctx, ids); err != nil {
+		return 0, fmt.Errorf("mark events published: %w", err)
+	}
+	return len(events), nil
+}
+
+// next records the outcome of a relay and returns the delay until the next:
+// none while the outbox is backlogged, the backoff while failing, and the poll
+// interval otherwise.
+func (r *outboxRelay) next(n int, err error) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.failures, r.lastErr = 0, nil
+		if n == r.batchSize {
+			return 0
+		}
+		return r.pollInterval
+	}
+
+	r.failures++
+	r.lastErr = err
+	r.logger.Warn("Could not relay outbox events", zap.Int("failures", r.failures), zap.Error(err))
+	backoff := r.pollInterval
+	for i := 1; i < r.failures && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,97 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type outboxStoreMock struct {
+	mu      sync.Mutex
+	pending []OutboxEvent
+}
+
+func (m *outboxStoreMock) Pending(_ context.Context, limit int) ([]OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > len(m.pending) {
+		limit = len(m.pending)
+	}
+	return append([]OutboxEvent{}, m.pending[:limit]...), nil
+}
+
+func (m *outboxStoreMock) MarkPublished(_ context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	published := map[string]bool{}
+	for _, id := range ids {
+		published[id] = true
+	}
+	pending := m.pending[:0]
+	for _, e := range m.pending {
+		if !published[e.ID] {
+			pending = append(pending, e)
+		}
+	}
+	m.pending = pending
+	return nil
+}
+
+func (m *outboxStoreMock) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pending)
+}
+
+func TestAddOutboxRelay(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	store := &outboxStoreMock{}
+	for i := 0; i < 5; i++ {
+		store.pending = append(store.pending, OutboxEvent{ID: fmt.Sprint(i), CreatedAt: time.Now()})
+	}
+
+	var mu sync.Mutex
+	var published []string
+	unavailable := true
+	producer := OutboxProducerFunc(func(_ context.Context, events []OutboxEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if unavailable {
+			return errors.New("broker unavailable")
+		}
+		for _, e := range events {
+			published = append(published, e.ID)
+		}
+		return nil
+	})
+	s.AddOutboxRelay("orders-outbox", store, producer,
+		OutboxBatchSize(2), OutboxPollInterval(time.Millisecond), OutboxMaxBackoff(5*time.Millisecond))
+	r := s.workers["orders-outbox"].(*outboxRelay)
+	require.NoError(t, r.Init(zap.NewNop()))
+	done := make(chan error)
+	go func() { done <- r.Run() }()
+
+	require.Eventually(t, func() bool { return r.CheckHealth().Status == HealthWarn }, time.Second, time.Millisecond)
+	assert.Contains(t, r.CheckHealth().Detail, "publish events: broker unavailable")
+
+	mu.Lock()
+	unavailable = false
+	mu.Unlock()
+	require.Eventually(t, func() bool { return store.len() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, r.Terminate())
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, published)
+	assert.Equal(t, HealthOK, r.CheckHealth().Status)
+	assert.Equal(t, float64(5), testutil.ToFloat64(s.metrics.outboxEvents.WithLabelValues("orders-outbox", "published")))
+	assert.Less(t, float64(0), testutil.ToFloat64(s.metrics.outboxEvents.WithLabelValues("orders-outbox", "error")))
+}
@@ -0,0 +1,136 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultPanicReportTimeout = 10 * time.Second
+
+// Panic describes a recovered worker panic.
+type Panic struct {
+	Worker string
+	Value  interface{}
+	// Stack is the stack of the panicking goroutine.
+	Stack []byte
+	// Goroutines is the dump of all goroutines, when enabled with
+	// PanicGoroutineDump, and Truncated reports whether it exceeded the size
+	// limit.
+	Goroutines []byte
+	Truncated  bool
+	At         time.Time
+}
+
+// PanicReporter defines an error-reporter integration worker panics are
+// reported to, e.g. Sentry.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, p Panic) error
+}
+
+// PanicReporterFunc is an adapter to allow the use of ordinary functions as
+// PanicReporter.
+type PanicReporterFunc func(ctx context.Context, p Panic) error
+
+// ReportPanic implements the PanicReporter interface.
+func (f PanicReporterFunc) ReportPanic(ctx context.Context, p Panic) error {
+	return f(ctx, p)
+}
+
+// PanicReportDir returns a PanicReporter writing each panic, with its stack
+// and goroutine dump, to a file in dir.
+func PanicReportDir(dir string) PanicReporter {
+	return PanicReporterFunc(func(_ context.Context, p Panic) error {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("panic-%s-%s.txt", filepath.Base(p.Worker), p.At.UTC().Format("20060102T150405.000000000Z"))
+		report := fmt.Sprintf("worker: %s\npanic: %v\n\n%s", p.Worker, p.Value, p.Stack)
+		if len(p.Goroutines) > 0 {
+			report += "\ngoroutines:\n" + string(p.Goroutines)
+			if p.Truncated {
+				report += "\n[truncated]\n"
+			}
+		}
+		return os.WriteFile(filepath.Join(dir, name), []byte(report), 0o640)
+	})
+}
+
+// PanicReportOption defines WithPanicReporter's option type.
+type PanicReportOption func(*panicReporting)
+
+// PanicGoroutineDump attaches a dump of all goroutines to the reports, up to
+// maxBytes, which helps diagnosing panics caused by other goroutines' state.
+func PanicGoroutineDump(maxBytes int) PanicReportOption {
+	return func(p *panicReporting) {
+		p.dumpMaxBytes = maxBytes
+	}
+}
+
+type panicReporting struct {
+	reporter     PanicReporter
+	dumpMaxBytes int
+}
+
+// WithPanicReporter is an option that reports worker panics to reporter,
+// besides failing the worker as usual. Panics are counted in the
+// svc_worker_panics_total metric.
+func WithPanicReporter(reporter PanicReporter, opts ...PanicReportOption) Option {
+	return func(s *SVC) error {
+		p := &panicReporting{reporter: reporter}
+		for _, o := range opts {
+			o(p)
+		}
+		s.panics = p
+
+		return nil
+	}
+}
+
+// reportPanic reports a panic recovered from the worker. It must be called
+// from the deferred function that recovered, for the stack to be the
+// panicking goroutine's.
+func (s *SVC) reportPanic(name string, value interface{}) {
+	s.metrics.workerPanics.WithLabelValues(name).Inc()
+	if s.panics == nil {
+		return
+	}
+
+	p := Panic{Worker: name, Value: value, Stack: debug.Stack(), At: time.Now()}
+	if s.panics.dumpMaxBytes > 0 {
+		p.Goroutines, p.Truncated = goroutineDump(s.panics.dumpMaxBytes)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultPanicReportTimeout)
+	defer cancel()
+	if err := s.panics.reporter.ReportPanic(ctx, p); err != nil {
+		s.logger.Error("Could not report panic", zap.String("worker", name), zap.Error(err))
+	}
+}
+
+// goroutineDump returns the stacks of all goroutines, truncated to maxBytes,
+// and whether they were.
+func goroutineDump(maxBytes int) ([]byte, bool) {
+	// Grow the buffer until the dump fits, up to one byte beyond the limit to
+	// detect truncation.
+	size := 64 << 10
+	for {
+		if size > maxBytes+1 {
+			size = maxBytes + 1
+		}
+		buf := make([]byte, size)
+		n := runtime.Stack(buf, true)
+		if n < size {
+			return buf[:n], false
+		}
+		if size == maxBytes+1 {
+			return buf[:maxBytes], true
+		}
+		size *= 2
+	}
+}
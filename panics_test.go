@@ -0,0 +1,59 @@
+package svc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithPanicReporter(t *testing.T) {
+	var reported []Panic
+	reporter := PanicReporterFunc(func(_ context.Context, p Panic) error {
+		reported = append(reported, p)
+		return nil
+	})
+	s, err := New("dummy-service", "v0.0.0", WithPanicReporter(reporter, PanicGoroutineDump(512)))
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { panic("boom") },
+		TerminateFunc: func() error { return nil },
+	})
+
+	require.EqualError(t, s.RunE(), "boom")
+	require.Len(t, reported, 1)
+	p := reported[0]
+	assert.Equal(t, "dummy-worker", p.Worker)
+	assert.Equal(t, "boom", p.Value)
+	assert.Contains(t, string(p.Stack), "TestWithPanicReporter")
+	assert.Len(t, p.Goroutines, 512)
+	assert.True(t, p.Truncated)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.workerPanics.WithLabelValues("dummy-worker")))
+}
+
+func TestPanicReportDir(t *testing.T) {
+	dir := t.TempDir()
+	goroutines, truncated := goroutineDump(1 << 20)
+	require.False(t, truncated)
+
+	err := PanicReportDir(dir).ReportPanic(context.Background(), Panic{
+		Worker:     "dummy-worker",
+		Value:      "boom",
+		Stack:      []byte("stack"),
+		Goroutines: goroutines,
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "panic-dummy-worker-*.txt"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	report, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(report), "worker: dummy-worker\npanic: boom\n\nstack\ngoroutines:\ngoroutine ")
+}
@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPProfMaxDuration   = 60 * time.Second
+	defaultPProfMaxConcurrent = 1
+	// Durations captured by net/http/pprof without a positive `seconds`
+	// parameter.
+	pprofProfileDefaultDuration = 30 * time.Second
+	pprofTraceDefaultDuration   = time.Second
+)
+
+// PProfOption defines WithPProfHandlers' option type.
+type PProfOption func(*pprofConfig)
+
+type pprofConfig struct {
+	blockProfileRate     *int
+	mutexProfileFraction *int
+	maxDuration          time.Duration
+	maxConcurrent        int
+}
+
+// PProfBlockProfileRate enables block profiling, sampling on average one
+// blocking event per rate nanoseconds spent blocked. See
+// runtime.SetBlockProfileRate.
+func PProfBlockProfileRate(rate int) PProfOption {
+	return func(c *pprofConfig) {
+		c.blockProfileRate = &rate
+	}
+}
+
+// PProfMutexProfileFraction enables mutex profiling, reporting on average 1/rate
+// of the mutex contention events. See runtime.SetMutexProfileFraction.
+func PProfMutexProfileFraction(rate int) PProfOption {
+	return func(c *pprofConfig) {
+		c.mutexProfileFraction = &rate
+	}
+}
+
+// PProfMaxDuration limits the duration (`seconds` parameter) of profile
+// captures. Requests exceeding it are rejected, including those capturing for
+// the default duration without `seconds`, i.e. 30s for the CPU profile and 1s
+// for the trace. Zero disables the limit.
+func PProfMaxDuration(d time.Duration) PProfOption {
+	return func(c *pprofConfig) {
+		c.maxDuration = d
+	}
+}
+
+// PProfMaxConcurrent limits the number of concurrent profile captures. Requests
+// exceeding it are rejected. Zero disables the limit.
+func PProfMaxConcurrent(n int) PProfOption {
+	return func(c *pprofConfig) {
+		c.maxConcurrent = n
+	}
+}
+
+// pprofGuard enforces the profile duration and concurrency limits.
+type pprofGuard struct {
+	maxDuration time.Duration
+	slots       chan struct{}
+}
+
+func newPProfGuard(cfg pprofConfig) *pprofGuard {
+	g := &pprofGuard{maxDuration: cfg.maxDuration}
+	if cfg.maxConcurrent > 0 {
+		g.slots = make(chan struct{}, cfg.maxConcurrent)
+	}
+	return g
+}
+
+// capture guards handlers always capturing over a duration, such as the CPU
+// profile and the execution trace, capturing over def without a positive
+// `seconds` parameter.
+func (g *pprofGuard) capture(next http.Handler, def time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.allowDuration(w, r, def) {
+			return
+		}
+		g.limit(next).ServeHTTP(w, r)
+	})
+}
+
+// snapshot guards handlers that capture over a duration only when asked to,
+// i.e. delta profiles.
+func (g *pprofGuard) snapshot(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("seconds") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		g.capture(next, 0).ServeHTTP(w, r)
+	})
+}
+
+func (g *pprofGuard) allowDuration(w http.ResponseWriter, r *http.Request, def time.Duration) bool {
+	if g.maxDuration <= 0 {
+		return true
+	}
+	d := def
+	if v := r.FormValue("seconds"); v != "" {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid seconds parameter: %s", err), http.StatusBadRequest)
+			return false
+		}
+		if sec > 0 {
+			d = time.Duration(sec) * time.Second
+		}
+	}
+	if d > g.maxDuration {
+		http.Error(w, fmt.Sprintf("profile duration exceeds %s", g.maxDuration), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (g *pprofGuard) limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.slots == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case g.slots <- struct{}{}:
+			defer func() { <-g.slots }()
+			next.ServeHTTP(w, r)
+		default:
+			http.Error(w, "too many concurrent profile captures", http.StatusTooManyRequests)
+		}
+	})
+}
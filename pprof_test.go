@@ -0,0 +1,96 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPProfMaxDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []PProfOption
+		target       string
+		expectedCode int
+	}{
+		{
+			name:         "should reject profile exceeding default max duration",
+			target:       "/debug/pprof/profile?seconds=300",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should reject invalid duration",
+			target:       "/debug/pprof/trace?seconds=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should reject profile without seconds exceeding max duration",
+			opts:         []PProfOption{PProfMaxDuration(10 * time.Second)},
+			target:       "/debug/pprof/profile",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should reject profile with zero seconds exceeding max duration",
+			opts:         []PProfOption{PProfMaxDuration(10 * time.Second)},
+			target:       "/debug/pprof/profile?seconds=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should serve trace without seconds within max duration",
+			opts:         []PProfOption{PProfMaxDuration(10 * time.Second)},
+			target:       "/debug/pprof/trace",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "should reject delta profile exceeding max duration",
+			opts:         []PProfOption{PProfMaxDuration(time.Second)},
+			target:       "/debug/pprof/heap?seconds=2",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should serve snapshot profile",
+			opts:         []PProfOption{PProfMaxDuration(time.Second)},
+			target:       "/debug/pprof/heap",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", WithPProfHandlers(tc.opts...))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestPProfMaxConcurrent(t *testing.T) {
+	g := newPProfGuard(pprofConfig{maxConcurrent: 1})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := g.capture(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	}), time.Second)
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/debug/pprof/profile", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pprof/profile", nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	rec = httptest.NewRecorder()
+	g.snapshot(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/pprof/heap", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	close(release)
+}
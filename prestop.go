@@ -0,0 +1,68 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WithPreStopHandler is an option that sets up the `/internal/prestop` HTTP route
+// for the pod's preStop hook. When hit, the service reports not ready so load
+// balancers stop sending traffic, the request blocks for the termination wait
+// period while they drain the instance, and the shutdown starts. The request
+// then blocks until the workers implementing Drainer are drained and the
+// tracked tasks done, bounded by the termination grace period, responding 503
+// Service Unavailable if not drained in time or canceled. The wait period is
+// not waited again when the kubelet sends SIGTERM.
+func WithPreStopHandler() Option {
+	return func(s *SVC) error {
+		s.handle("WithPreStopHandler", "/internal/prestop", http.HandlerFunc(s.preStopHandler))
+
+		return nil
+	}
+}
+
+func (s *SVC) preStopHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s.drain(ctx)
+	_, grace := s.terminationPeriods()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-s.drained:
+		_, _ = w.Write([]byte(`{"status": "drained"}`))
+	case <-timer.C:
+		s.logger.Warn("Pre-stop hook timed out before drained", zap.Duration("termination_grace_period", grace))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status": "timeout"}`))
+	case <-ctx.Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status": "canceled"}`))
+	}
+}
+
+// drain reports the service not ready, waits the termination wait period, or
+// until ctx is done, and starts the shutdown without waiting again.
+func (s *SVC) drain(ctx context.Context) {
+	s.setNotReady("prestop", "pre-stop hook called, draining")
+	wait, _ := s.terminationPeriods()
+	s.logger.Info("Pre-stop hook called, draining", zap.Duration("termination_wait_period", wait))
+
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		s.logger.Warn("Pre-stop hook canceled while draining")
+	}
+	s.skipTerminationWait()
+
+	// Never block on an already full signals channel, e.g. if hit repeatedly.
+	select {
+	case s.signals <- syscall.SIGTERM:
+	default:
+	}
+}
@@ -0,0 +1,102 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPreStopHandler(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0",
+		WithHealthz(),
+		WithPreStopHandler(),
+		WithTerminationWaitPeriod(20*time.Millisecond),
+		WithTerminationGracePeriod(20*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	// Not running, thus never drained.
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/internal/prestop", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status": "timeout"}`, rec.Body.String())
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	rec = httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready/details", nil))
+	assert.Contains(t, rec.Body.String(), "prestop: pre-stop hook called, draining")
+
+	select {
+	case sig := <-s.signals:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	default:
+		require.FailNow(t, "Shutdown has not been started")
+	}
+	wait, _ := s.beginTermination()
+	assert.Zero(t, wait)
+}
+
+func TestPreStopHandlerDrained(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithPreStopHandler())
+	require.NoError(t, err)
+
+	running, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	var drainedAt time.Time
+	s.AddWorker("consumer", drainerMock{
+		WorkerMock: &WorkerMock{
+			InitFunc:      func(*zap.Logger) error { return nil },
+			RunFunc:       func() error { close(running); <-done; return nil },
+			TerminateFunc: func() error { close(done); return nil },
+		},
+		drainFunc: func(context.Context) error {
+			<-release
+			drainedAt = time.Now()
+			return nil
+		},
+	})
+	errs := make(chan error, 1)
+	go func() { errs <- s.RunE() }()
+	<-running
+
+	responded := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/internal/prestop", nil))
+		responded <- rec
+	}()
+	select {
+	case <-responded:
+		require.FailNow(t, "Responded before drained")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	rec := <-responded
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "drained"}`, rec.Body.String())
+	assert.False(t, drainedAt.IsZero())
+	require.NoError(t, <-errs)
+}
+
+func TestPreStopHandlerCanceled(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithPreStopHandler())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/internal/prestop", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status": "canceled"}`, rec.Body.String())
+}
@@ -0,0 +1,334 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultRateLimitSweepInterval = time.Minute
+
+// RateLimitKeyFunc returns the key a request is rate limited by, e.g. the
+// client's IP address, API key, or tenant. Requests with an empty key are not
+// rate limited.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP rate limits requests by the client's IP address, as seen by
+// the server, i.e. the proxy's behind one.
+func RateLimitByIP() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// RateLimitByHeader rate limits requests by the value of the header, e.g.
+// "X-API-Key" or "X-Tenant-ID". Requests without the header are not rate
+// limited.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimit defines a token bucket allowing bursts of Requests, refilled at
+// Requests per Per, e.g. 100 requests per minute.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// rate returns the tokens refilled per second.
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimits defines the limit of each key: Keys' limits override the
+// Default one, e.g. for tenants on a premium plan.
+type RateLimits struct {
+	Default RateLimit
+	Keys    map[string]RateLimit
+}
+
+func (l RateLimits) forKey(key string) RateLimit {
+	if limit, ok := l.Keys[key]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+// RateLimitResult defines the outcome of taking a token from a key's bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until a token is available, if not allowed.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore defines where the token buckets are kept, in memory by
+// default, or e.g. in Redis to share the limits among the replicas.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitOption defines WithHTTPRateLimit's option type.
+type RateLimitOption func(*rateLimiter)
+
+// RateLimitWithStore sets the store of the token buckets. Defaults to a
+// MemoryRateLimitStore.
+func RateLimitWithStore(store RateLimitStore) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.store = store
+	}
+}
+
+// WithHTTPRateLimit is an option that rate limits the requests to the user
+// routes of the internal HTTP server with a token bucket per key, as returned
+// by keyFunc. Requests over the limit are rejected with 429 Too Many Requests
+// and a Retry-After header, and counted in the svc_http_rate_limited_total
+// metric. All responses carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers. Requests are let through if the store fails. The
+// routes registered by options, such as the probes, are not rate limited.
+func WithHTTPRateLimit(keyFunc RateLimitKeyFunc, limits RateLimits, opts ...RateLimitOption) Option {
+	return func(s *SVC) error {
+		if keyFunc == nil {
+			return errors.New("rate limit requires a key function")
+		}
+		for key, limit := range limits.Keys {
+			if limit.Requests <= 0 || limit.Per <= 0 {
+				return fmt.Errorf("rate limit of key %s must be positive", key)
+			}
+		}
+		if limits.Default.Requests <= 0 || limits.Default.Per <= 0 {
+			return errors.New("default rate limit must be positive")
+		}
+
+		l := &rateLimiter{s: s, keyFunc: keyFunc, limits: limits}
+		for _, o := range opts {
+			o(l)
+		}
+		if l.store == nil {
+			l.store = NewMemoryRateLimitStore()
+		}
+		s.middlewares = append(s.middlewares, l.middleware)
+
+		return nil
+	}
+}
+
+// rateLimiter defines the rate limiting middleware.
+type rateLimiter struct {
+	s       *SVC
+	keyFunc RateLimitKeyFunc
+	limits  RateLimits
+	store   RateLimitStore
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.keyFunc(r)
+		if key == "" || l.s.isOptionRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := l.limits.forKey(key)
+		res, err := l.store.Take(r.Context(), key, limit)
+		if err != nil {
+			l.s.logger.Warn("Could not rate limit request", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			l.s.metrics.httpRateLimited.Inc()
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds formats d in whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// bucketResult returns the result of taking a token from a bucket holding
+// tokens, after the take if allowed.
+func bucketResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	rate := limit.rate()
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// MemoryRateLimitStore keeps the token buckets in memory, shared by all the
+// requests of the process. Full buckets are swept periodically.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}, lastSweep: time.Now(), now: time.Now}
+}
+
+// Take implements the RateLimitStore interface.
+func (m *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= defaultRateLimitSweepInterval {
+		m.sweep(now)
+	}
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &tokenBucket{tokens: float64(limit.Requests), last: now, limit: limit}
+		m.buckets[key] = b
+	}
+	b.refill(now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(allowed, b.tokens, limit), nil
+}
+
+// sweep removes the full buckets, equivalent to missing ones. The lock must be
+// held.
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Requests) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed*b.limit.rate())
+	}
+	b.last = now
+}
+
+// RedisEvaler defines a Redis client evaluating Lua scripts. Adapt e.g. a
+// go-redis client with RedisEvalFunc:
+//
+//	svc.RedisEvalFunc(func(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return rdb.Eval(ctx, script, keys, args...).Result()
+//	})
+type RedisEvaler interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// RedisEvalFunc adapts a function to the RedisEvaler interface.
+type RedisEvalFunc func(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+
+// Eval implements the RedisEvaler interface.
+func (f RedisEvalFunc) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return f(ctx, script, keys, args...)
+}
+
+// redisTokenBucketScript takes a token from the bucket at KEYS[1] holding up
+// to ARGV[1] tokens refilled at ARGV[2] per second, on Redis' clock so the
+// replicas' clocks do not matter. It returns whether the token was taken, and
+// the tokens left as a string, as Lua numbers are truncated to integers.
+const redisTokenBucketScript = `
+redis.replicate_commands()
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+var _ RateLimitStore = (*RedisRateLimitStore)(nil)
+
+// RedisRateLimitStore keeps the token buckets in Redis, under keys prefixed
+// with prefix, so the limits are shared among the replicas of the service.
+// Buckets expire once full.
+type RedisRateLimitStore struct {
+	client RedisEvaler
+	prefix string
+}
+
+// NewRedisRateLimitStore returns a RedisRateLimitStore, e.g. with the prefix
+// "ratelimit:my-service:".
+func NewRedisRateLimitStore(client RedisEvaler, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+// Take implements the RateLimitStore interface.
+func (r *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	reply, err := r.client.Eval(ctx, redisTokenBucketScript, []string{r.prefix + key},
+		limit.Requests, strconv.FormatFloat(limit.rate(), 'f', -1, 64))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	s, ok := values[1].(string)
+	if !ok {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	return bucketResult(allowed == 1, tokens, limit), nil
+}
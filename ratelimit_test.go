@@ -0,0 +1,181 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTPRateLimit(t *testing.T) {
+	limits := RateLimits{
+		Default: RateLimit{Requests: 2, Per: time.Minute},
+		Keys:    map[string]RateLimit{"premium": {Requests: 3, Per: time.Minute}},
+	}
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(),
+		WithHTTPRateLimit(RateLimitByHeader("X-Tenant-ID"), limits))
+	require.NoError(t, err)
+	s.self.setInitialized()
+	s.HandleFunc("/data", func(w http.ResponseWriter, _ *http.Request) {})
+
+	serve := func(path, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		rec := httptest.NewRecorder()
+		s.serveHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name              string
+		path              string
+		tenant            string
+		expectedCode      int
+		expectedRemaining string
+	}{
+		{name: "first request", path: "/data", tenant: "basic", expectedCode: http.StatusOK, expectedRemaining: "1"},
+		{name: "second request", path: "/data", tenant: "basic", expectedCode: http.StatusOK, expectedRemaining: "0"},
+		{name: "over the limit", path: "/data", tenant: "basic", expectedCode: http.StatusTooManyRequests, expectedRemaining: "0"},
+		{name: "other tenant", path: "/data", tenant: "premium", expectedCode: http.StatusOK, expectedRemaining: "2"},
+		{name: "no key", path: "/data", expectedCode: http.StatusOK},
+		{name: "option route", path: "/live", tenant: "basic", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.path, tc.tenant)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Equal(t, tc.expectedRemaining, rec.Header().Get("RateLimit-Remaining"))
+			if tc.expectedRemaining != "" {
+				assert.NotEmpty(t, rec.Header().Get("RateLimit-Limit"))
+				assert.NotEmpty(t, rec.Header().Get("RateLimit-Reset"))
+			}
+			if tc.expectedCode == http.StatusTooManyRequests {
+				assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.httpRateLimited))
+
+	for _, opt := range []Option{
+		WithHTTPRateLimit(nil, limits),
+		WithHTTPRateLimit(RateLimitByIP(), RateLimits{}),
+		WithHTTPRateLimit(RateLimitByIP(), RateLimits{Default: limits.Default, Keys: map[string]RateLimit{"x": {}}}),
+	} {
+		_, err := New("dummy-service", "v0.0.0", opt)
+		require.Error(t, err)
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimit) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("dummy error")
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHTTPRateLimit(RateLimitByIP(),
+		RateLimits{Default: RateLimit{Requests: 1, Per: time.Second}},
+		RateLimitWithStore(failingRateLimitStore{})))
+	require.NoError(t, err)
+	s.self.setInitialized()
+	s.HandleFunc("/data", func(w http.ResponseWriter, _ *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(rec, httptest.NewRequest("GET", "/data", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Now()
+	m := NewMemoryRateLimitStore()
+	m.now = func() time.Time { return now }
+	limit := RateLimit{Requests: 2, Per: 2 * time.Second}
+
+	take := func() RateLimitResult {
+		res, err := m.Take(context.Background(), "key", limit)
+		require.NoError(t, err)
+		return res
+	}
+
+	assert.Equal(t, RateLimitResult{Allowed: true, Remaining: 1, Reset: time.Second}, take())
+	assert.Equal(t, RateLimitResult{Allowed: true, Remaining: 0, Reset: 2 * time.Second}, take())
+	assert.Equal(t, RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: time.Second, Reset: 2 * time.Second}, take())
+
+	now = now.Add(500 * time.Millisecond)
+	res := take()
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, take().Allowed)
+
+	// Full buckets are swept.
+	now = now.Add(defaultRateLimitSweepInterval)
+	_, err := m.Take(context.Background(), "other", limit)
+	require.NoError(t, err)
+	assert.Len(t, m.buckets, 1)
+}
+
+func TestRedisRateLimitStore(t *testing.T) {
+	tests := []struct {
+		name           string
+		reply          interface{}
+		err            error
+		expectedResult RateLimitResult
+		expectedError  bool
+	}{
+		{
+			name:           "allowed",
+			reply:          []interface{}{int64(1), "1.5"},
+			expectedResult: RateLimitResult{Allowed: true, Remaining: 1, Reset: 25 * time.Second},
+		},
+		{
+			name:           "not allowed",
+			reply:          []interface{}{int64(0), "0.5"},
+			expectedResult: RateLimitResult{Remaining: 0, RetryAfter: 5 * time.Second, Reset: 35 * time.Second},
+		},
+		{
+			name:          "redis error",
+			err:           errors.New("dummy error"),
+			expectedError: true,
+		},
+		{
+			name:          "unexpected reply",
+			reply:         int64(1),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var keys []string
+			var args []interface{}
+			store := NewRedisRateLimitStore(RedisEvalFunc(func(_ context.Context, script string, k []string, a ...interface{}) (interface{}, error) {
+				assert.Equal(t, redisTokenBucketScript, script)
+				keys, args = k, a
+				return tc.reply, tc.err
+			}), "ratelimit:")
+
+			res, err := store.Take(context.Background(), "tenant", RateLimit{Requests: 4, Per: 40 * time.Second})
+			assert.Equal(t, []string{"ratelimit:tenant"}, keys)
+			assert.Equal(t, []interface{}{4, "0.1"}, args)
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResult, res)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package svc
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	readinessDelayKey           = "readiness_delay"
+	maxReadinessDelayPollPeriod = time.Second
+)
+
+// WithReadinessDelay is an option that holds the service not ready until all
+// workers report healthy and then for the quiet period d, e.g. for JIT
+// warm-up or connection pools to fill, reducing latency spikes of the first
+// requests after deploys. The period restarts if a worker turns unhealthy
+// meanwhile.
+func WithReadinessDelay(d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return errors.New("readiness delay must be positive")
+		}
+		poll := d
+		if poll > maxReadinessDelayPollPeriod {
+			poll = maxReadinessDelayPollPeriod
+		}
+		s.AddWorker("internal-readiness-delay", &readinessDelay{
+			s:     s,
+			delay: d,
+			poll:  poll,
+			done:  make(chan struct{}),
+		})
+
+		return nil
+	}
+}
+
+var _ Worker = (*readinessDelay)(nil)
+
+// readinessDelay defines the internal worker holding the service not ready
+// during the quiet period.
+type readinessDelay struct {
+	s      *SVC
+	logger *zap.Logger
+	delay  time.Duration
+	poll   time.Duration
+	done   chan struct{}
+}
+
+// Init implements the Worker interface.
+func (r *readinessDelay) Init(logger *zap.Logger) error {
+	r.logger = logger
+	r.s.setNotReady(readinessDelayKey, "waiting for workers to be healthy")
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (r *readinessDelay) Run() error {
+	ticker := time.NewTicker(r.poll)
+	defer ticker.Stop()
+	var healthySince time.Time
+	for {
+		switch {
+		case !r.workersHealthy():
+			if !healthySince.IsZero() {
+				r.s.setNotReady(readinessDelayKey, "waiting for workers to be healthy")
+			}
+			healthySince = time.Time{}
+		case healthySince.IsZero():
+			healthySince = time.Now()
+			r.s.setNotReady(readinessDelayKey, "quiet period after workers turned healthy")
+		case time.Since(healthySince) >= r.delay:
+			r.s.clearNotReady(readinessDelayKey)
+			r.logger.Info("Readiness delay elapsed", zap.Duration("delay", r.delay))
+			<-r.done
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (r *readinessDelay) Terminate() error {
+	close(r.done)
+
+	return nil
+}
+
+// workersHealthy reports whether the ready checks of all workers pass,
+// ignoring the framework's own, which the delay holds failing.
+func (r *readinessDelay) workersHealthy() bool {
+	for _, c := range r.s.readyChecks() {
+		if c.Worker != SelfHealthName && c.Status == HealthCritical {
+			return false
+		}
+	}
+	return true
+}
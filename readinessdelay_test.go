@@ -0,0 +1,49 @@
+package svc
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithReadinessDelay(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithReadinessDelay(50*time.Millisecond))
+	require.NoError(t, err)
+	var healthy int32
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+		HealthyFunc: func() error {
+			if atomic.LoadInt32(&healthy) == 0 {
+				return errors.New("warming up")
+			}
+			return nil
+		},
+	})
+	r := s.workers["internal-readiness-delay"].(*readinessDelay)
+	require.NoError(t, r.Init(zap.NewNop()))
+	go func() { _ = r.Run() }()
+	defer func() { _ = r.Terminate() }()
+
+	res := s.self.CheckHealth()
+	assert.Equal(t, HealthCritical, res.Status)
+	assert.Equal(t, "readiness_delay: waiting for workers to be healthy", res.Detail)
+
+	atomic.StoreInt32(&healthy, 1)
+	start := time.Now()
+	require.Eventually(t, func() bool {
+		return s.self.CheckHealth().Status == HealthOK
+	}, time.Second, 5*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestWithReadinessDelayInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithReadinessDelay(0))
+	require.EqualError(t, err, "readiness delay must be positive")
+}
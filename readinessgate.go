@@ -0,0 +1,27 @@
+package svc
+
+import "go.uber.org/zap"
+
+const readinessGateKey = "readiness_gate"
+
+// WithReadinessGate is an option that holds the service not ready until the
+// application calls SetReady(true), e.g. once caches are primed or migrations
+// completed.
+func WithReadinessGate() Option {
+	return func(s *SVC) error {
+		s.setNotReady(readinessGateKey, "waiting for SetReady")
+
+		return nil
+	}
+}
+
+// SetReady holds the service not ready, e.g. while draining, or releases it,
+// independently of the workers' health checks, which still apply.
+func (s *SVC) SetReady(ready bool) {
+	if ready {
+		s.clearNotReady(readinessGateKey)
+	} else {
+		s.setNotReady(readinessGateKey, "held not ready by SetReady")
+	}
+	s.logger.Info("Readiness gate changed", zap.Bool("ready", ready))
+}
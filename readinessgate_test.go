@@ -0,0 +1,32 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReadinessGate(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithReadinessGate())
+	require.NoError(t, err)
+	s.self.setInitialized()
+
+	ready := func() int {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+	assert.Equal(t, "readiness_gate: waiting for SetReady", s.self.CheckHealth().Detail)
+
+	s.SetReady(true)
+	assert.Equal(t, http.StatusOK, ready())
+
+	s.SetReady(false)
+	assert.Equal(t, http.StatusServiceUnavailable, ready())
+	assert.Equal(t, "readiness_gate: held not ready by SetReady", s.self.CheckHealth().Detail)
+}
@@ -0,0 +1,115 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Reloader defines a worker that can reload its configuration while running,
+// e.g. on SIGHUP, see WithReloadOnSIGHUP.
+type Reloader interface {
+	Reload() error
+}
+
+// configSource defines a named configuration source re-read on reload.
+type configSource struct {
+	name string
+	load func() error
+}
+
+// WithConfigSource is an option that registers a configuration source, e.g. a
+// file, re-read by load on every reload, before the workers are reloaded.
+// load must synchronize with the readers of the configuration.
+func WithConfigSource(name string, load func() error) Option {
+	return func(s *SVC) error {
+		if load == nil {
+			return fmt.Errorf("config source %s has no load function", name)
+		}
+		s.configSources = append(s.configSources, configSource{name: name, load: load})
+
+		return nil
+	}
+}
+
+// WithReloadOnSIGHUP is an option that reloads the service on SIGHUP, see
+// Reload, instead of shutting it down.
+func WithReloadOnSIGHUP() Option {
+	return func(s *SVC) error {
+		s.shutdownSignals = removeSignal(s.shutdownSignals, syscall.SIGHUP)
+
+		return WithSignalHandler(syscall.SIGHUP, func() {
+			_ = s.Reload()
+		})(s)
+	}
+}
+
+// WithShutdownSignals is an option that sets the signals shutting the service
+// down. Defaults to SIGINT, SIGTERM and SIGHUP.
+func WithShutdownSignals(sigs ...os.Signal) Option {
+	return func(s *SVC) error {
+		if len(sigs) == 0 {
+			return errors.New("at least one shutdown signal is required")
+		}
+		for _, sig := range sigs {
+			if _, handled := s.signalHandlers[sig]; handled {
+				return fmt.Errorf("signal %s is already handled", sig)
+			}
+		}
+		s.shutdownSignals = sigs
+
+		return nil
+	}
+}
+
+// Reload re-reads the configuration sources, in registration order, then
+// reloads the initialized workers implementing Reloader, in added order,
+// while the service keeps running. A failure does not stop the others from
+// reloading; all errors are returned. Reloads are serialized.
+func (s *SVC) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.logger.Info("Reloading service")
+	var errs []error
+	for _, src := range s.configSources {
+		if err := src.load(); err != nil {
+			s.logger.Error("Could not reload config source", zap.String("source", src.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("config source %s: %w", src.name, err))
+		}
+	}
+
+	s.initMu.Lock()
+	initialized := append([]string{}, s.workersInitialized...)
+	s.initMu.Unlock()
+	for _, name := range initialized {
+		r, ok := unwrapWorker(s.workers[name]).(Reloader)
+		if !ok {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			s.logger.Error("Could not reload worker", zap.String("worker", name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("worker %s: %w", name, err))
+		}
+	}
+
+	err := errors.Join(errs...)
+	s.recordEvent(EventServiceReloaded, "", err)
+	if err == nil {
+		s.logger.Info("Service reloaded")
+	}
+	return err
+}
+
+func removeSignal(sigs []os.Signal, sig os.Signal) []os.Signal {
+	kept := make([]os.Signal, 0, len(sigs))
+	for _, s := range sigs {
+		if s != sig {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
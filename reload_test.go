@@ -0,0 +1,122 @@
+package svc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type reloaderWorker struct {
+	*WorkerMock
+	ReloadFunc func() error
+}
+
+func (w reloaderWorker) Reload() error {
+	return w.ReloadFunc()
+}
+
+func TestReload(t *testing.T) {
+	var calls []string
+	s, err := New("dummy-service", "v0.0.0",
+		WithConfigSource("file", func() error {
+			calls = append(calls, "file")
+			return nil
+		}),
+		WithConfigSource("vault", func() error {
+			calls = append(calls, "vault")
+			return errors.New("dummy error")
+		}),
+	)
+	require.NoError(t, err)
+	for _, name := range []string{"db", "cache", "plain", "uninitialized"} {
+		name := name
+		var w Worker = &WorkerMock{InitFunc: func(*zap.Logger) error { return nil }}
+		if name != "plain" {
+			w = reloaderWorker{WorkerMock: w.(*WorkerMock), ReloadFunc: func() error {
+				calls = append(calls, name)
+				if name == "cache" {
+					return errors.New("dummy error")
+				}
+				return nil
+			}}
+		}
+		s.AddWorker(name, w)
+		if name != "uninitialized" {
+			require.NoError(t, s.initWorker(name))
+		}
+	}
+
+	err = s.Reload()
+	require.EqualError(t, err, "config source vault: dummy error\nworker cache: dummy error")
+	assert.Equal(t, []string{"file", "vault", "db", "cache"}, calls)
+	events := s.Events()
+	last := events[len(events)-1]
+	assert.Equal(t, EventServiceReloaded, last.Type)
+	assert.Equal(t, err.Error(), last.Message)
+
+	_, err = New("dummy-service", "v0.0.0", WithConfigSource("file", nil))
+	require.Error(t, err)
+}
+
+func TestWithReloadOnSIGHUP(t *testing.T) {
+	reloaded := make(chan struct{}, 1)
+	s, err := New("dummy-service", "v0.0.0", WithReloadOnSIGHUP())
+	require.NoError(t, err)
+	assert.NotContains(t, s.shutdownSignals, syscall.SIGHUP)
+
+	running := make(chan struct{})
+	stop := make(chan struct{})
+	s.AddWorker("dummy-worker", reloaderWorker{
+		WorkerMock: &WorkerMock{
+			InitFunc:      func(*zap.Logger) error { return nil },
+			RunFunc:       func() error { close(running); <-stop; return nil },
+			TerminateFunc: func() error { close(stop); return nil },
+		},
+		ReloadFunc: func() error {
+			reloaded <- struct{}{}
+			return nil
+		},
+	})
+	done := make(chan error)
+	go func() { done <- s.RunE() }()
+	<-running
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	select {
+	case <-reloaded:
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Worker has not been reloaded")
+	}
+	select {
+	case <-done:
+		require.FailNow(t, "Service should keep running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Shutdown()
+	require.NoError(t, <-done)
+}
+
+func TestWithShutdownSignals(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0",
+		WithShutdownSignals(syscall.SIGTERM),
+		WithSignalHandler(syscall.SIGINT, func() {}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []os.Signal{syscall.SIGTERM}, s.shutdownSignals)
+
+	_, err = New("dummy-service", "v0.0.0",
+		WithSignalHandler(syscall.SIGUSR1, func() {}),
+		WithShutdownSignals(syscall.SIGUSR1),
+	)
+	require.EqualError(t, err, "signal user defined signal 1 is already handled")
+
+	_, err = New("dummy-service", "v0.0.0", WithShutdownSignals())
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package svc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultResourceCloseTimeout = 5 * time.Second
+
+// resource defines an outbound resource closed on shutdown.
+type resource struct {
+	name    string
+	close   func(ctx context.Context) error
+	timeout time.Duration
+}
+
+// RegisterResource registers an outbound resource, such as a connection pool or
+// a producer, to be closed once all workers are terminated, i.e. after all
+// ingress is drained. Resources are closed in parallel, each bounded by the
+// given timeout (5s if zero). Workers typically register their resources in
+// Init.
+func (s *SVC) RegisterResource(name string, closeFn func(ctx context.Context) error, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultResourceCloseTimeout
+	}
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	s.resources = append(s.resources, resource{name: name, close: closeFn, timeout: timeout})
+}
+
+// RegisterCloser registers an io.Closer as an outbound resource. See
+// RegisterResource.
+func (s *SVC) RegisterCloser(name string, c io.Closer, timeout time.Duration) {
+	s.RegisterResource(name, func(context.Context) error { return c.Close() }, timeout)
+}
+
+func (s *SVC) closeResources() {
+	s.resourcesMu.Lock()
+	resources := s.resources
+	s.resources = nil
+	s.resourcesMu.Unlock()
+	if len(resources) == 0 {
+		return
+	}
+
+	s.logger.Info("Closing resources", zap.Int("resources", len(resources)))
+	wg := sync.WaitGroup{}
+	for _, r := range resources {
+		wg.Add(1)
+		go func(r resource) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+			defer cancel()
+
+			errs := make(chan error, 1)
+			go func() { errs <- r.close(ctx) }()
+			select {
+			case err := <-errs:
+				if err != nil {
+					s.logger.Error("Resource closed with error", zap.String("resource", r.name), zap.Error(err))
+					return
+				}
+				s.logger.Info("Resource closed", zap.String("resource", r.name))
+			case <-ctx.Done():
+				s.logger.Error("Resource close timed out", zap.String("resource", r.name), zap.Duration("timeout", r.timeout))
+			}
+		}(r)
+	}
+	wg.Wait()
+}
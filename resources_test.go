@@ -0,0 +1,53 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type closeFunc func() error
+
+func (f closeFunc) Close() error { return f() }
+
+func TestCloseResources(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var seq []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seq = append(seq, name)
+	}
+
+	hang := make(chan struct{})
+	defer close(hang)
+
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			s.RegisterCloser("pool", closeFunc(func() error { record("pool"); return nil }), 0)
+			s.RegisterResource("hanging", func(context.Context) error {
+				<-hang
+				return nil
+			}, 10*time.Millisecond)
+			return nil
+		},
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { record("worker"); return nil },
+	})
+
+	start := time.Now()
+	s.Run()
+
+	assert.Less(t, time.Since(start), time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"worker", "pool"}, seq)
+}
@@ -0,0 +1,243 @@
+package svc
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ResponseVersion is the version of the response types of the operational
+// endpoints, sent in the Svc-Response-Version header of their responses. It is
+// incremented on breaking changes only; fields may be added within a version.
+// The JSON schemas of the version are published in schemas/, see
+// ResponseSchema.
+const ResponseVersion = "v1"
+
+//go:embed schemas/v1/*.json
+var responseSchemas embed.FS
+
+// ResponseSchema returns the JSON schema of the named response type of
+// ResponseVersion: "live", "startup", "ready", "ready-details", "events",
+// "termination", "status", "info", "buildinfo", "workers", "degradation" or
+// "run-report".
+func ResponseSchema(name string) ([]byte, error) {
+	return responseSchemas.ReadFile("schemas/" + ResponseVersion + "/" + name + ".json")
+}
+
+// LiveResponse defines the body served by the /live endpoint: the status if
+// alive, the failing workers' errors otherwise, and each worker's result.
+type LiveResponse struct {
+	Status          string        `json:"status,omitempty"`
+	Errors          []string      `json:"errors,omitempty"`
+	Results         []ProbeResult `json:"results"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	InstanceID      string        `json:"instance_id,omitempty"`
+}
+
+// ProbeResult describes a worker's check in a probe's response, with the
+// warm-up progress and when it is expected to recover, if known, while failing.
+type ProbeResult struct {
+	Worker            string       `json:"worker"`
+	Status            HealthStatus `json:"status"`
+	Detail            string       `json:"detail,omitempty"`
+	Progress          *float64     `json:"progress,omitempty"`
+	RetryAfterSeconds float64      `json:"retry_after_seconds,omitempty"`
+	DurationSeconds   float64      `json:"duration_seconds"`
+}
+
+// StartupResponse defines the body served by the /startup endpoint: the
+// status if started, the reasons it is not and the Retry-After header's value
+// otherwise.
+type StartupResponse struct {
+	Status            string   `json:"status,omitempty"`
+	Errors            []string `json:"errors,omitempty"`
+	RetryAfterSeconds int      `json:"retry_after_seconds,omitempty"`
+	InstanceID        string   `json:"instance_id,omitempty"`
+}
+
+// Ready statuses reported by the /ready endpoint.
+const (
+	ReadyStatusReady    = "ready"
+	ReadyStatusNotReady = "not_ready"
+)
+
+// ReadyResponse defines the body served by the /ready endpoint: the status,
+// the checked workers, the warnings and errors of those failing, each worker's
+// result, and the Retry-After header's value if not ready.
+type ReadyResponse struct {
+	Status            string        `json:"status"`
+	Checked           []string      `json:"checked"`
+	Warnings          []string      `json:"warnings,omitempty"`
+	Errors            []string      `json:"errors,omitempty"`
+	Results           []ProbeResult `json:"results"`
+	RetryAfterSeconds int           `json:"retry_after_seconds,omitempty"`
+	DurationSeconds   float64       `json:"duration_seconds"`
+	Timestamp         time.Time     `json:"timestamp"`
+	InstanceID        string        `json:"instance_id,omitempty"`
+}
+
+// ReadyDetailsResponse defines the body served by the /ready/details
+// endpoint.
+type ReadyDetailsResponse struct {
+	Ready             bool          `json:"ready"`
+	Failing           []ReadyDetail `json:"failing"`
+	RetryAfterSeconds int           `json:"retry_after_seconds,omitempty"`
+}
+
+// ReadyDetail describes a failing ready check.
+type ReadyDetail struct {
+	Worker       string       `json:"worker"`
+	Status       HealthStatus `json:"status"`
+	Detail       string       `json:"detail,omitempty"`
+	Action       string       `json:"action,omitempty"`
+	FailingSince time.Time    `json:"failing_since"`
+	FailingFor   string       `json:"failing_for"`
+	// Progress is the worker's warm-up progress, from 0 to 1, if reported.
+	Progress *float64 `json:"progress,omitempty"`
+	// ExpectedRecoveryAt is when the check is expected to recover, if known.
+	ExpectedRecoveryAt *time.Time `json:"expected_recovery_at,omitempty"`
+}
+
+// EventsResponse defines the body served by the /debug/events endpoint.
+type EventsResponse struct {
+	Events []Event `json:"events"`
+}
+
+// TerminationPeriods defines the body served and accepted by the
+// /termination endpoint. The periods are durations such as "30s".
+type TerminationPeriods struct {
+	WaitPeriod  string `json:"wait_period,omitempty"`
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// ServiceStatus defines the "svc" expvar variable served by /debug/vars.
+type ServiceStatus struct {
+	Name          string                         `json:"name"`
+	Version       string                         `json:"version"`
+	InstanceID    string                         `json:"instance_id"`
+	State         string                         `json:"state"`
+	UptimeSeconds float64                        `json:"uptime_seconds"`
+	Workers       map[string]ServiceWorkerStatus `json:"workers"`
+}
+
+// ServiceWorkerStatus describes a worker in ServiceStatus.
+type ServiceWorkerStatus struct {
+	Running bool   `json:"running"`
+	Health  string `json:"health,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// InfoResponse defines the body served by the /info endpoint.
+type InfoResponse struct {
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	InstanceID    string    `json:"instance_id"`
+	Role          string    `json:"role,omitempty"`
+	Build         BuildInfo `json:"build"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+}
+
+// BuildInfoResponse defines the body served by the /debug/buildinfo endpoint.
+type BuildInfoResponse struct {
+	GoVersion string         `json:"go_version"`
+	Path      string         `json:"path"`
+	Main      Module         `json:"main"`
+	Deps      []Module       `json:"deps"`
+	Settings  []BuildSetting `json:"settings"`
+}
+
+// Module describes a module in BuildInfoResponse, and the module replacing it,
+// if any.
+type Module struct {
+	Path    string  `json:"path"`
+	Version string  `json:"version"`
+	Sum     string  `json:"sum,omitempty"`
+	Replace *Module `json:"replace,omitempty"`
+}
+
+// BuildSetting describes a build setting in BuildInfoResponse, e.g.
+// "vcs.revision".
+type BuildSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// WorkersResponse defines the body served by the /healthz/workers endpoint.
+type WorkersResponse struct {
+	Workers []WorkerDetail `json:"workers"`
+}
+
+// WorkerDetail describes a worker in WorkersResponse: its state, the error it
+// failed to initialize, run or terminate with, if any, its uptime while
+// running, and the last error its health checks reported.
+type WorkerDetail struct {
+	Name              string     `json:"name"`
+	State             string     `json:"state"`
+	Error             string     `json:"error,omitempty"`
+	Health            string     `json:"health,omitempty"`
+	UptimeSeconds     float64    `json:"uptime_seconds"`
+	Restarts          int        `json:"restarts"`
+	LastHealthError   string     `json:"last_health_error,omitempty"`
+	LastHealthErrorAt *time.Time `json:"last_health_error_at,omitempty"`
+}
+
+// DegradationResponse defines the body served by the /degradation endpoint.
+type DegradationResponse struct {
+	Features []FeatureStatus `json:"features"`
+}
+
+// FeatureStatus describes a feature registered with Degrade: whether it is
+// degraded, its mode, and why and since when it is degraded, if it is.
+type FeatureStatus struct {
+	Name     string     `json:"name"`
+	Degraded bool       `json:"degraded"`
+	Mode     string     `json:"mode"`
+	Reason   string     `json:"reason,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+}
+
+// FeatureModeRequest defines the body accepted by the /degradation endpoint.
+type FeatureModeRequest struct {
+	Feature string `json:"feature"`
+	Mode    string `json:"mode"`
+}
+
+// RunReport defines the report written by WithRunReport once the service shut
+// down.
+type RunReport struct {
+	Service         string            `json:"service"`
+	Version         string            `json:"version"`
+	InstanceID      string            `json:"instance_id"`
+	Succeeded       bool              `json:"succeeded"`
+	Cause           string            `json:"cause"`
+	Error           string            `json:"error,omitempty"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      time.Time         `json:"finished_at"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Workers         []WorkerRunReport `json:"workers"`
+}
+
+// WorkerRunReport describes a worker's run in RunReport.
+type WorkerRunReport struct {
+	Worker          string      `json:"worker"`
+	Status          string      `json:"status"`
+	Error           string      `json:"error,omitempty"`
+	Result          interface{} `json:"result,omitempty"`
+	DurationSeconds float64     `json:"duration_seconds"`
+}
+
+// writeResponse writes the JSON body of an operational endpoint, setting the
+// headers before the status code.
+func writeResponse(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Svc-Response-Version", ResponseVersion)
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,102 @@
+package svc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResponseSchemas checks the published schemas match the response types.
+func TestResponseSchemas(t *testing.T) {
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	type schema struct {
+		object
+		Defs map[string]object `json:"$defs"`
+	}
+	// fields returns the JSON fields of the struct type, and the required
+	// ones, i.e. not omitted when empty.
+	fields := func(typ reflect.Type) (all, required []string) {
+		all, required = []string{}, []string{}
+		for i := 0; i < typ.NumField(); i++ {
+			name, opts, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			all = append(all, name)
+			if opts != "omitempty" {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(all)
+		sort.Strings(required)
+		return all, required
+	}
+	assertObject := func(t *testing.T, typ reflect.Type, o object) {
+		all, required := fields(typ)
+		props := make([]string, 0, len(o.Properties))
+		for p := range o.Properties {
+			props = append(props, p)
+		}
+		sort.Strings(props)
+		sort.Strings(o.Required)
+		assert.Equal(t, all, props, typ.Name())
+		assert.Equal(t, required, o.Required, typ.Name())
+	}
+
+	tests := []struct {
+		name string
+		typ  interface{}
+		defs map[string]interface{}
+	}{
+		{name: "live", typ: LiveResponse{}, defs: map[string]interface{}{"ProbeResult": ProbeResult{}}},
+		{name: "startup", typ: StartupResponse{}},
+		{name: "ready", typ: ReadyResponse{}, defs: map[string]interface{}{"ProbeResult": ProbeResult{}}},
+		{name: "ready-details", typ: ReadyDetailsResponse{}, defs: map[string]interface{}{"ReadyDetail": ReadyDetail{}}},
+		{name: "events", typ: EventsResponse{}, defs: map[string]interface{}{"Event": Event{}}},
+		{name: "termination", typ: TerminationPeriods{}},
+		{name: "status", typ: ServiceStatus{}, defs: map[string]interface{}{"ServiceWorkerStatus": ServiceWorkerStatus{}}},
+		{name: "info", typ: InfoResponse{}, defs: map[string]interface{}{"BuildInfo": BuildInfo{}}},
+		{name: "buildinfo", typ: BuildInfoResponse{}, defs: map[string]interface{}{"Module": Module{}, "BuildSetting": BuildSetting{}}},
+		{name: "workers", typ: WorkersResponse{}, defs: map[string]interface{}{"WorkerDetail": WorkerDetail{}}},
+		{name: "degradation", typ: DegradationResponse{}, defs: map[string]interface{}{"FeatureStatus": FeatureStatus{}}},
+		{name: "run-report", typ: RunReport{}, defs: map[string]interface{}{"WorkerRunReport": WorkerRunReport{}}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := ResponseSchema(tc.name)
+			require.NoError(t, err)
+			var s schema
+			require.NoError(t, json.Unmarshal(b, &s))
+
+			assertObject(t, reflect.TypeOf(tc.typ), s.object)
+			require.Len(t, s.Defs, len(tc.defs))
+			for name, typ := range tc.defs {
+				assertObject(t, reflect.TypeOf(typ), s.Defs[name])
+			}
+		})
+	}
+
+	_, err := ResponseSchema("unknown")
+	require.Error(t, err)
+}
+
+func TestResponseVersionHeader(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live", nil))
+	assert.Equal(t, ResponseVersion, rec.Header().Get("Svc-Response-Version"))
+	var res LiveResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, LiveResponse{Status: "Still Alive!", Results: []ProbeResult{}, DurationSeconds: res.DurationSeconds, InstanceID: s.InstanceID()}, res)
+}
@@ -0,0 +1,147 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"go.uber.org/zap"
+)
+
+const defaultRestartMaxDelay = time.Minute
+
+// RestartPolicy defines when a worker is restarted once its Run returned.
+type RestartPolicy int
+
+// Restart policies.
+const (
+	// RestartNever never restarts the worker, like AddWorker.
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts the worker if Run returned an error or
+	// panicked.
+	RestartOnFailure
+	// RestartAlways restarts the worker whenever Run returned.
+	RestartAlways
+)
+
+// errRunReturned is the error a worker run is retried on when it returned
+// successfully but is to be restarted anyway.
+var errRunReturned = errors.New("run returned")
+
+// AddWorkerWithRestart adds a named worker to the service, which is
+// re-initialized and re-run according to the policy instead of shutting the
+// service down, as long as the service is not shutting down. Restarts are
+// delayed according to the retry options, by default backing off
+// exponentially up to 1m and retrying forever; once the attempts are
+// exhausted, the worker fails as usual. Restarts are counted in the
+// svc_worker_restarts_total metric. The worker's Init must support being
+// called again.
+func (s *SVC) AddWorkerWithRestart(name string, w Worker, policy RestartPolicy, retryOpts []retry.Option) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.AddWorker(name, &restartWorker{
+		s:         s,
+		name:      name,
+		w:         w,
+		policy:    policy,
+		retryOpts: retryOpts,
+		ctx:       ctx,
+		cancel:    cancel,
+	})
+}
+
+var (
+	_ Worker    = (*restartWorker)(nil)
+	_ unwrapper = (*restartWorker)(nil)
+)
+
+// restartWorker supervises a worker according to its restart policy.
+type restartWorker struct {
+	s         *SVC
+	name      string
+	logger    *zap.Logger
+	w         Worker
+	policy    RestartPolicy
+	retryOpts []retry.Option
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// Init implements the Worker interface.
+func (r *restartWorker) Init(logger *zap.Logger) error {
+	r.logger = logger
+
+	return r.w.Init(logger)
+}
+
+// Run implements the Worker interface.
+func (r *restartWorker) Run() error {
+	if r.policy == RestartNever {
+		return r.w.Run()
+	}
+
+	opts := []retry.Option{
+		retry.Attempts(0),
+		retry.MaxDelay(defaultRestartMaxDelay),
+		retry.LastErrorOnly(true),
+	}
+	opts = append(opts, r.retryOpts...)
+	opts = append(opts,
+		retry.Context(r.ctx),
+		retry.OnRetry(func(n uint, err error) {
+			r.s.metrics.workerRestarts.WithLabelValues(r.name).Inc()
+			r.s.workerStates.restarted(r.name)
+			r.logger.Warn("Restarting worker", zap.Uint("restarts", n+1), zap.Error(err))
+		}),
+	)
+
+	first := true
+	err := retry.Do(func() error {
+		if !first {
+			if err := r.w.Init(r.logger); err != nil {
+				return fmt.Errorf("init: %w", err)
+			}
+		}
+		first = false
+
+		err := r.run()
+		switch {
+		case r.ctx.Err() != nil && err == nil:
+			return nil
+		case r.ctx.Err() != nil:
+			// Terminated, the run's result is final.
+			return retry.Unrecoverable(err)
+		case err == nil && r.policy == RestartAlways:
+			return errRunReturned
+		}
+		return err
+	}, opts...)
+	if errors.Is(err, errRunReturned) || (r.ctx.Err() != nil && errors.Is(err, context.Canceled)) {
+		return nil
+	}
+	return err
+}
+
+// run runs the worker, turning a panic into an error so it can be restarted.
+func (r *restartWorker) run() (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.s.reportPanic(r.name, p)
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.w.Run()
+}
+
+// Terminate implements the Worker interface. It stops restarting the worker
+// and terminates it.
+func (r *restartWorker) Terminate() error {
+	r.cancel()
+
+	return r.w.Terminate()
+}
+
+func (r *restartWorker) unwrapWorker() interface{} {
+	return unwrapWorker(r.w)
+}
@@ -0,0 +1,101 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAddWorkerWithRestart(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name     string
+		policy   RestartPolicy
+		runs     []func() error
+		inits    int
+		restarts float64
+		wantErr  bool
+	}{
+		{"on failure restarts failures", RestartOnFailure, []func() error{
+			func() error { return errBoom },
+		}, 2, 1, false},
+		{"on failure restarts panics", RestartOnFailure, []func() error{
+			func() error { panic("boom") },
+		}, 2, 1, false},
+		{"on failure keeps returns", RestartOnFailure, []func() error{
+			func() error { return nil },
+		}, 1, 0, false},
+		{"always restarts returns", RestartAlways, []func() error{
+			func() error { return nil },
+			func() error { return nil },
+		}, 3, 2, false},
+		{"never", RestartNever, []func() error{
+			func() error { return errBoom },
+		}, 1, 0, true},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0")
+			require.NoError(t, err)
+
+			var inits, runs int
+			done := make(chan struct{})
+			s.AddWorkerWithRestart("dummy-worker", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { inits++; return nil },
+				RunFunc: func() error {
+					runs++
+					if runs <= len(tc.runs) {
+						return tc.runs[runs-1]()
+					}
+					s.Shutdown()
+					<-done
+					return nil
+				},
+				TerminateFunc: func() error { close(done); return nil },
+			}, tc.policy, []retry.Option{retry.Delay(time.Millisecond)})
+			if tc.inits == 1 {
+				// The worker isn't restarted, shut down from another one.
+				s.AddWorker("shutdown-worker", &WorkerMock{
+					InitFunc:      func(*zap.Logger) error { return nil },
+					RunFunc:       func() error { time.Sleep(10 * time.Millisecond); s.Shutdown(); return nil },
+					TerminateFunc: func() error { return nil },
+				})
+			}
+
+			err = s.RunE()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.inits, inits)
+			assert.Equal(t, tc.restarts, testutil.ToFloat64(s.metrics.workerRestarts.WithLabelValues("dummy-worker")))
+		})
+	}
+}
+
+func TestAddWorkerWithRestartTerminated(t *testing.T) {
+	for _, policy := range []RestartPolicy{RestartOnFailure, RestartAlways} {
+		s, err := New("dummy-service", "v0.0.0")
+		require.NoError(t, err)
+		done := make(chan struct{})
+		s.AddWorkerWithRestart("dummy-worker", &WorkerMock{
+			InitFunc:      func(*zap.Logger) error { return nil },
+			RunFunc:       func() error { s.Shutdown(); <-done; return nil },
+			TerminateFunc: func() error { close(done); return nil },
+		}, policy, nil)
+
+		require.NoError(t, s.RunE())
+		for _, e := range s.Events() {
+			assert.NotEqual(t, EventWorkerFailed, e.Type, "policy %d: %s", policy, e.Message)
+		}
+		assert.Equal(t, 0.0, testutil.ToFloat64(s.metrics.workerPanics.WithLabelValues("dummy-worker")))
+	}
+}
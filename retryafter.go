@@ -0,0 +1,85 @@
+package svc
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultRetryAfter = 5 * time.Second
+
+// Progresser defines a worker that can report its warm-up progress, from 0 to
+// 1, e.g. the share of a cache loaded. While it is not ready or started, the
+// progress is reported by the probes, and the time it is expected to take to
+// complete, extrapolated since the service started, is hinted in their
+// Retry-After header.
+type Progresser interface {
+	Progress() float64
+}
+
+// WithHealthzRetryAfter is an option that sets the Retry-After header of the
+// ready and startup probes failing without any hint of when they are expected
+// to recover, from HealthResult.RetryAfter or Progresser. Defaults to 5s.
+func WithHealthzRetryAfter(d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return errors.New("healthz retry after must be positive")
+		}
+		s.retryAfter = d
+
+		return nil
+	}
+}
+
+// recoveryHint returns the worker's warm-up progress, if it reports one, and
+// when its failing check is expected to recover, if known: the check's own
+// hint, or else extrapolated from the progress.
+func (s *SVC) recoveryHint(w interface{}, res HealthResult) (*float64, time.Duration) {
+	pw, ok := unwrapWorker(w).(Progresser)
+	if !ok {
+		return nil, res.RetryAfter
+	}
+	progress := math.Max(0, math.Min(1, pw.Progress()))
+	eta := res.RetryAfter
+	if eta <= 0 && progress > 0 && progress < 1 {
+		elapsed := time.Since(s.startedAt)
+		eta = time.Duration(float64(elapsed) * (1 - progress) / progress)
+	}
+	return &progress, eta
+}
+
+// readyRetryAfter returns when the ready probe is expected to pass: once the
+// critical check expected to recover last does. Checks without hint are
+// expected to recover within the default.
+func (s *SVC) readyRetryAfter(checks []readyCheck) time.Duration {
+	var retryAfter time.Duration
+	for _, c := range checks {
+		if c.Status != HealthCritical {
+			continue
+		}
+		hint := c.RetryAfter
+		if hint <= 0 {
+			hint = s.retryAfter
+		}
+		if hint > retryAfter {
+			retryAfter = hint
+		}
+	}
+	if retryAfter <= 0 {
+		return s.retryAfter
+	}
+	return retryAfter
+}
+
+// setRetryAfter sets the Retry-After header, rounded up to the second, and
+// returns its value.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) int {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	return secs
+}
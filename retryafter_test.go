@@ -0,0 +1,168 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type progresserMock struct {
+	healthCheckerMock
+	progress float64
+}
+
+func (w *progresserMock) Progress() float64 {
+	return w.progress
+}
+
+type startingProgresserMock struct {
+	starterMock
+	progress float64
+}
+
+func (w *startingProgresserMock) Progress() float64 {
+	return w.progress
+}
+
+func TestReadyRetryAfter(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               []Option
+		workers            map[string]Worker
+		expectedRetryAfter int
+		expectedProgress   map[string]float64
+	}{
+		{
+			name: "no hint",
+			workers: map[string]Worker{
+				"db": &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused"}},
+			},
+			expectedRetryAfter: 5,
+		},
+		{
+			name: "configured default",
+			opts: []Option{WithHealthzRetryAfter(30 * time.Second)},
+			workers: map[string]Worker{
+				"db": &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused"}},
+			},
+			expectedRetryAfter: 30,
+		},
+		{
+			name: "check hint",
+			workers: map[string]Worker{
+				"db": &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused", RetryAfter: 1500 * time.Millisecond}},
+			},
+			expectedRetryAfter: 2,
+		},
+		{
+			name: "latest recovery",
+			workers: map[string]Worker{
+				"db":     &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused", RetryAfter: 20 * time.Second}},
+				"broker": &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused"}},
+				"cache":  &healthCheckerMock{result: HealthResult{Status: HealthWarn, Detail: "slow", RetryAfter: time.Minute}},
+			},
+			expectedRetryAfter: 20,
+		},
+		{
+			name: "warm-up progress",
+			workers: map[string]Worker{
+				"cache": &progresserMock{
+					healthCheckerMock: healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "warming up"}},
+					progress:          0.25,
+				},
+			},
+			// 10s elapsed for 25%.
+			expectedRetryAfter: 30,
+			expectedProgress:   map[string]float64{"cache": 0.25},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", append(tc.opts, WithHealthz())...)
+			require.NoError(t, err)
+			s.startedAt = time.Now().Add(-10 * time.Second)
+			for name, w := range tc.workers {
+				s.AddWorker(name, w)
+			}
+
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			var res ReadyResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.InDelta(t, tc.expectedRetryAfter, res.RetryAfterSeconds, 1)
+			assert.Equal(t, strconv.Itoa(res.RetryAfterSeconds), rec.Header().Get("Retry-After"))
+
+			progress := map[string]float64{}
+			for _, r := range res.Results {
+				if r.Progress != nil {
+					progress[r.Worker] = *r.Progress
+				}
+			}
+			if tc.expectedProgress == nil {
+				tc.expectedProgress = map[string]float64{}
+			}
+			assert.Equal(t, tc.expectedProgress, progress)
+		})
+	}
+}
+
+func TestReadyDetailsRetryAfter(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	s.startedAt = time.Now().Add(-10 * time.Second)
+	s.AddWorker("cache", &progresserMock{
+		healthCheckerMock: healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "warming up"}},
+		progress:          0.5,
+	})
+	s.AddWorker("db", &healthCheckerMock{result: HealthResult{Status: HealthCritical, Detail: "connection refused"}})
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready/details", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var res ReadyDetailsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.InDelta(t, 10, res.RetryAfterSeconds, 1)
+	require.Len(t, res.Failing, 2)
+
+	cache, db := res.Failing[0], res.Failing[1]
+	require.NotNil(t, cache.Progress)
+	assert.Equal(t, 0.5, *cache.Progress)
+	require.NotNil(t, cache.ExpectedRecoveryAt)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), *cache.ExpectedRecoveryAt, 2*time.Second)
+	assert.Nil(t, db.Progress)
+	assert.Nil(t, db.ExpectedRecoveryAt)
+}
+
+func TestStartupRetryAfter(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	s.self.setInitialized()
+	s.startedAt = time.Now().Add(-10 * time.Second)
+	s.AddWorker("cache", &startingProgresserMock{
+		starterMock: starterMock{err: errors.New("warming up")},
+		progress:    0.2,
+	})
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var res StartupResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.InDelta(t, 40, res.RetryAfterSeconds, 1)
+	assert.Equal(t, strconv.Itoa(res.RetryAfterSeconds), rec.Header().Get("Retry-After"))
+}
+
+func TestWithHealthzRetryAfterInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithHealthzRetryAfter(0))
+	require.Error(t, err)
+}
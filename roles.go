@@ -0,0 +1,98 @@
+package svc
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	roleFlag = "role"
+	roleEnv  = "SVC_ROLE"
+	// RoleAll is the role running all workers.
+	RoleAll = "all"
+)
+
+// WithRoles is an option that maps roles to the workers they run, so the same
+// binary can run different worker subsets per deployment. The role is selected
+// with the `--role` command-line argument, or else the SVC_ROLE environment
+// variable; no role or RoleAll runs all workers. Workers not listed in any
+// role always run. The argument is looked up without defining a flag, thus
+// applications parsing the command-line have to define it themselves, or use
+// the environment variable.
+//
+// The role is reflected in the logs and, with WithMetrics, in the svc_up
+// labels.
+func WithRoles(roles map[string][]string) Option {
+	return func(s *SVC) error {
+		role, ok := lookupArg(os.Args[1:], roleFlag)
+		if !ok {
+			role = os.Getenv(roleEnv)
+		}
+		if _, ok := roles[role]; !ok && role != "" && role != RoleAll {
+			known := make([]string, 0, len(roles))
+			for r := range roles {
+				known = append(known, r)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown role %q, expected one of: %s", role, strings.Join(known, ", "))
+		}
+
+		s.roles = roles
+		s.role = role
+
+		return nil
+	}
+}
+
+// Role returns the role the service runs as, or an empty string if no role was
+// selected.
+func (s *SVC) Role() string {
+	return s.role
+}
+
+// roleDisabledWorkers returns the workers not part of the selected role.
+func (s *SVC) roleDisabledWorkers() []string {
+	if s.role == "" || s.role == RoleAll {
+		return nil
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range s.roles[s.role] {
+		enabled[name] = true
+	}
+	var disabled []string
+	for _, names := range s.roles {
+		for _, name := range names {
+			if !enabled[name] {
+				disabled = append(disabled, name)
+			}
+		}
+	}
+	return disabled
+}
+
+// lookupArg looks up the flag with the given name in the command-line
+// arguments, ignoring any other flag, and returns its value.
+func lookupArg(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		n := strings.TrimLeft(arg, "-")
+		if n == arg {
+			continue
+		}
+		if n == name {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				return args[i+1], true
+			}
+			return "", true
+		}
+		if strings.HasPrefix(n, name+"=") {
+			return strings.TrimPrefix(n, name+"="), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,98 @@
+package svc
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLookupArg(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		found    bool
+	}{
+		{name: "no args", args: nil, expected: "", found: false},
+		{name: "separate value", args: []string{"-v", "--role", "api"}, expected: "api", found: true},
+		{name: "single dash with equal sign", args: []string{"-role=worker"}, expected: "worker", found: true},
+		{name: "without value", args: []string{"--role", "--other"}, expected: "", found: true},
+		{name: "after terminator", args: []string{"--", "--role=api"}, expected: "", found: false},
+		{name: "positional argument", args: []string{"role"}, expected: "", found: false},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			value, found := lookupArg(tc.args, roleFlag)
+			assert.Equal(t, tc.expected, value)
+			assert.Equal(t, tc.found, found)
+		})
+	}
+}
+
+func TestWithRoles(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	roles := map[string][]string{
+		"api":    {"http"},
+		"worker": {"consumer", "reconciler"},
+	}
+
+	os.Args = []string{"dummy", "--role=unknown"}
+	_, err := New("dummy-name", "dummy-version", WithRoles(roles))
+	require.EqualError(t, err, `unknown role "unknown", expected one of: api, worker`)
+
+	os.Args = []string{"dummy", "--role=worker"}
+	s, err := New("dummy-name", "dummy-version", WithRoles(roles))
+	require.NoError(t, err)
+	require.Equal(t, "worker", s.Role())
+
+	var initialized []string
+	for _, name := range []string{"http", "consumer", "reconciler", "unlisted"} {
+		name := name
+		s.AddWorker(name, &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				initialized = append(initialized, name)
+				return nil
+			},
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { return nil },
+		})
+	}
+	s.Run()
+
+	assert.Equal(t, []string{"consumer", "reconciler", "unlisted"}, initialized)
+}
+
+func TestWithRolesEnv(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"dummy"}
+	t.Setenv("SVC_ROLE", "api")
+
+	// The role labels svc_up regardless of the options order.
+	s, err := New("dummy-name", "dummy-version",
+		WithMetrics(), WithMetricsHandler(), WithRoles(map[string][]string{"api": {"http"}}))
+	require.NoError(t, err)
+	require.Equal(t, "api", s.Role())
+	// Defining the flag is left to the application.
+	assert.Nil(t, flag.Lookup("role"))
+
+	s.AddWorker("http", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	require.NoError(t, s.RunE())
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `svc_up{instance_id="`+s.InstanceID()+`",name="dummy-name",role="api",version="dummy-version"} 1`)
+}
@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// Handle registers the handler for the given pattern on the service's router.
+// Unlike registering on Router directly, a duplicate pattern does not panic
+// but is reported by Validate and Run.
+func (s *SVC) Handle(pattern string, handler http.Handler) {
+	s.handle(callerOwner(), pattern, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern on the
+// service's router. See Handle.
+func (s *SVC) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.handle(callerOwner(), pattern, http.HandlerFunc(handler))
+}
+
+// Validate reports configuration errors detected while applying options or
+// registering routes, such as duplicate route patterns.
+func (s *SVC) Validate() error {
+	return errors.Join(s.routeErrs...)
+}
+
+// handle registers the handler on the router, tracking which option or caller
+// (owner) registered which pattern.
+func (s *SVC) handle(owner, pattern string, handler http.Handler) {
+	if prev, exists := s.routes[pattern]; exists {
+		s.routeErrs = append(s.routeErrs,
+			fmt.Errorf("route %q registered by %s is already registered by %s", pattern, owner, prev))
+		return
+	}
+
+	// The pattern might have been registered directly on the router, in which
+	// case the router panics.
+	defer func() {
+		if r := recover(); r != nil {
+			s.routeErrs = append(s.routeErrs, fmt.Errorf("route %q registered by %s: %v", pattern, owner, r))
+		}
+	}()
+	s.Router.Handle(pattern, handler)
+	s.routes[pattern] = owner
+}
+
+// isOptionRoute returns whether the request is to a route registered by an
+// option, e.g. a probe, rather than a user route.
+func (s *SVC) isOptionRoute(r *http.Request) bool {
+	_, pattern := s.Router.Handler(r)
+	return isOptionOwner(s.routes[pattern])
+}
+
+// isOptionOwner returns whether the owner of a route is an option, which
+// registers its routes under its own name, rather than a caller.
+func isOptionOwner(owner string) bool {
+	return strings.HasPrefix(owner, "With")
+}
+
+func callerOwner() string {
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
@@ -0,0 +1,44 @@
+package svc
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDuplicateRoutes(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz(), WithHealthz())
+	require.NoError(t, err)
+
+	err = s.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `route "/live" registered by WithHealthz is already registered by WithHealthz`)
+	assert.Contains(t, err.Error(), `route "/ready" registered by WithHealthz is already registered by WithHealthz`)
+}
+
+func TestValidateDuplicateRoutesRegisteredOnRouter(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	s.Router.HandleFunc("/metrics", func(http.ResponseWriter, *http.Request) {})
+	require.NoError(t, WithMetricsHandler()(s))
+
+	err = s.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `route "/metrics" registered by WithMetricsHandler`)
+}
+
+func TestHandle(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	s.HandleFunc("/hello", func(http.ResponseWriter, *http.Request) {})
+	require.NoError(t, s.Validate())
+
+	s.HandleFunc("/hello", func(http.ResponseWriter, *http.Request) {})
+	err = s.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routes_test.go")
+}
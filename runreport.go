@@ -0,0 +1,165 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultRunReportTimeout = 10 * time.Second
+
+// Worker run statuses reported in a RunReport.
+const (
+	WorkerRunFinished = "finished"
+	WorkerRunFailed   = "failed"
+	WorkerRunRunning  = "running"
+	WorkerRunNotRun   = "not_run"
+)
+
+// Resulter defines a worker reporting a result once its Run returned, e.g. the
+// counts of a batch job, included in the run report.
+type Resulter interface {
+	Result() interface{}
+}
+
+// RunReportSink defines where the run report is written to, e.g. an object
+// store.
+type RunReportSink interface {
+	WriteRunReport(ctx context.Context, report RunReport) error
+}
+
+// RunReportWriter returns a RunReportSink writing the report as a JSON line to
+// w, e.g. os.Stdout.
+func RunReportWriter(w io.Writer) RunReportSink {
+	return runReportWriter{w}
+}
+
+type runReportWriter struct {
+	w io.Writer
+}
+
+// WriteRunReport implements the RunReportSink interface.
+func (w runReportWriter) WriteRunReport(_ context.Context, report RunReport) error {
+	return json.NewEncoder(w.w).Encode(report)
+}
+
+// RunReportFile returns a RunReportSink writing the report as JSON to the file
+// at path, e.g. an Argo Workflows output parameter.
+func RunReportFile(path string) RunReportSink {
+	return runReportFile(path)
+}
+
+type runReportFile string
+
+// WriteRunReport implements the RunReportSink interface.
+func (f runReportFile) WriteRunReport(_ context.Context, report RunReport) error {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(string(f), b, 0o644)
+}
+
+// WithRunReport is an option that writes a RunReport to the sinks once the
+// service shut down, with each worker's outcome and the result of those
+// implementing Resulter, so that pipeline orchestrators, e.g. Argo or Airflow,
+// can consume the outcome of services run as jobs without parsing logs.
+func WithRunReport(sinks ...RunReportSink) Option {
+	return func(s *SVC) error {
+		if s.runReport == nil {
+			s.runReport = &runReport{runs: map[string]*workerRun{}}
+		}
+		s.runReport.sinks = append(s.runReport.sinks, sinks...)
+
+		return nil
+	}
+}
+
+// runReport collects the workers' runs.
+type runReport struct {
+	sinks []RunReportSink
+
+	mu   sync.Mutex
+	runs map[string]*workerRun
+}
+
+type workerRun struct {
+	started, finished time.Time
+	failed            bool
+	err               string
+}
+
+// record records the worker run events.
+func (r *runReport) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch e.Type {
+	case EventWorkerStarted:
+		r.runs[e.Worker] = &workerRun{started: e.Time}
+	case EventWorkerFinished, EventWorkerFailed:
+		run, ok := r.runs[e.Worker]
+		if !ok || !run.finished.IsZero() {
+			return
+		}
+		run.finished = e.Time
+		run.failed, run.err = e.Type == EventWorkerFailed, e.Message
+	}
+}
+
+// writeRunReport writes the run report to the sinks, if any.
+func (s *SVC) writeRunReport(cause ShutdownCause, err error) {
+	if s.runReport == nil {
+		return
+	}
+
+	now := time.Now()
+	report := RunReport{
+		Service:         s.Name,
+		Version:         s.Version,
+		InstanceID:      s.instanceID,
+		Succeeded:       err == nil,
+		Cause:           cause.String(),
+		StartedAt:       s.startedAt,
+		FinishedAt:      now,
+		DurationSeconds: now.Sub(s.startedAt).Seconds(),
+		Workers:         make([]WorkerRunReport, 0, len(s.workersAdded)),
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.runReport.mu.Lock()
+	for _, name := range s.workersAdded {
+		wr := WorkerRunReport{Worker: name, Status: WorkerRunNotRun}
+		if run, ok := s.runReport.runs[name]; ok {
+			end := run.finished
+			switch {
+			case end.IsZero():
+				wr.Status, end = WorkerRunRunning, now
+			case run.failed:
+				wr.Status, wr.Error = WorkerRunFailed, run.err
+			default:
+				wr.Status = WorkerRunFinished
+			}
+			wr.DurationSeconds = end.Sub(run.started).Seconds()
+			if r, ok := unwrapWorker(s.workers[name]).(Resulter); ok && wr.Status != WorkerRunRunning {
+				wr.Result = r.Result()
+			}
+		}
+		report.Workers = append(report.Workers, wr)
+	}
+	s.runReport.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRunReportTimeout)
+	defer cancel()
+	for _, sink := range s.runReport.sinks {
+		if err := sink.WriteRunReport(ctx, report); err != nil {
+			s.logger.Error("Could not write run report", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type resultWorker struct {
+	*WorkerMock
+	result interface{}
+}
+
+func (w resultWorker) Result() interface{} {
+	return w.result
+}
+
+type failingRunReportSink struct{}
+
+func (failingRunReportSink) WriteRunReport(context.Context, RunReport) error {
+	return errors.New("dummy error")
+}
+
+func TestWithRunReport(t *testing.T) {
+	tests := []struct {
+		name              string
+		initErr           error
+		runErr            error
+		expectedSucceeded bool
+		expectedCause     string
+		expectedStatuses  map[string]string
+		expectedResult    bool
+	}{
+		{
+			name:              "completed",
+			expectedSucceeded: true,
+			expectedCause:     "completed",
+			expectedStatuses:  map[string]string{"job": WorkerRunFinished, "server": WorkerRunFinished},
+			expectedResult:    true,
+		},
+		{
+			name:             "worker failure",
+			runErr:           errors.New("dummy error"),
+			expectedCause:    "worker failure",
+			expectedStatuses: map[string]string{"job": WorkerRunFailed, "server": WorkerRunFinished},
+			expectedResult:   true,
+		},
+		{
+			name:             "init failure",
+			initErr:          errors.New("dummy error"),
+			expectedCause:    "startup failure",
+			expectedStatuses: map[string]string{"job": WorkerRunNotRun, "server": WorkerRunNotRun},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			path := filepath.Join(t.TempDir(), "report.json")
+			s, err := New("dummy-service", "v0.0.0",
+				WithRunReport(RunReportWriter(&buf), RunReportFile(path), failingRunReportSink{}))
+			require.NoError(t, err)
+
+			done := make(chan struct{})
+			s.AddWorker("job", resultWorker{
+				WorkerMock: &WorkerMock{
+					InitFunc:      func(*zap.Logger) error { return tc.initErr },
+					RunFunc:       func() error { return tc.runErr },
+					TerminateFunc: func() error { return nil },
+				},
+				result: map[string]int{"processed": 42},
+			})
+			s.AddWorker("server", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { return nil },
+				RunFunc: func() error {
+					// Serve until terminated once the job failed.
+					if tc.runErr != nil {
+						<-done
+					}
+					return nil
+				},
+				TerminateFunc: func() error {
+					close(done)
+					return nil
+				},
+			})
+
+			err = s.RunE()
+			require.Equal(t, tc.expectedSucceeded, err == nil)
+
+			var report RunReport
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var fileReport RunReport
+			require.NoError(t, json.Unmarshal(b, &fileReport))
+			assert.Equal(t, report.InstanceID, fileReport.InstanceID)
+
+			assert.Equal(t, "dummy-service", report.Service)
+			assert.Equal(t, s.InstanceID(), report.InstanceID)
+			assert.Equal(t, tc.expectedSucceeded, report.Succeeded)
+			assert.Equal(t, tc.expectedCause, report.Cause)
+			if !tc.expectedSucceeded {
+				assert.NotEmpty(t, report.Error)
+			}
+			assert.False(t, report.FinishedAt.Before(report.StartedAt))
+
+			require.Len(t, report.Workers, 2)
+			statuses := map[string]string{}
+			for _, w := range report.Workers {
+				statuses[w.Worker] = w.Status
+				if w.Status == WorkerRunFailed {
+					assert.Equal(t, "dummy error", w.Error)
+				}
+				if w.Worker == "job" {
+					assert.Equal(t, tc.expectedResult, w.Result != nil)
+				}
+			}
+			assert.Equal(t, tc.expectedStatuses, statuses)
+		})
+	}
+}
+
+func TestRunReportNotEnabled(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, s.runReport)
+	s.writeRunReport(ShutdownCompleted, nil)
+}
@@ -0,0 +1,147 @@
+package svc
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SelfHealthName is the name the framework's own health is reported under,
+// alongside the workers'.
+const SelfHealthName = "svc"
+
+// Framework anomalies.
+const (
+	anomalyErrorDropped  = "error_dropped"
+	anomalyWorkerLeaked  = "worker_leaked"
+	defaultLeakCheckWait = time.Second
+)
+
+var _ HealthChecker = (*selfHealth)(nil)
+
+// selfHealth tracks anomalies of the framework itself: worker errors that could
+// not be delivered, and worker goroutines still running after termination.
+// Anomalies are reported as warnings, they never flip readiness. It also holds
+// the reasons the service is held not ready, e.g. while draining, which do
+// flip readiness.
+type selfHealth struct {
+	initialized int32
+
+	mu        sync.Mutex
+	running   map[string]bool
+	anomalies map[string]string
+	notReady  map[string]string
+}
+
+func newSelfHealth() *selfHealth {
+	return &selfHealth{running: map[string]bool{}, anomalies: map[string]string{}, notReady: map[string]string{}}
+}
+
+// CheckHealth implements the HealthChecker interface.
+func (h *selfHealth) CheckHealth() HealthResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.anomalies) == 0 && len(h.notReady) == 0 {
+		return HealthResult{Status: HealthOK, CheckedAt: time.Now()}
+	}
+	status := HealthWarn
+	if len(h.notReady) > 0 {
+		status = HealthCritical
+	}
+	details := make([]string, 0, len(h.anomalies)+len(h.notReady))
+	for _, m := range []map[string]string{h.notReady, h.anomalies} {
+		start := len(details)
+		for kind, detail := range m {
+			details = append(details, kind+": "+detail)
+		}
+		sort.Strings(details[start:])
+	}
+	return HealthResult{Status: status, Detail: strings.Join(details, "; "), CheckedAt: time.Now()}
+}
+
+// setNotReady holds the service not ready for the given reason until
+// clearNotReady is called with the same key.
+func (s *SVC) setNotReady(key, reason string) {
+	s.self.mu.Lock()
+	s.self.notReady[key] = reason
+	s.self.mu.Unlock()
+}
+
+// clearNotReady removes the reason the service was held not ready for.
+func (s *SVC) clearNotReady(key string) {
+	s.self.mu.Lock()
+	delete(s.self.notReady, key)
+	s.self.mu.Unlock()
+}
+
+func (h *selfHealth) setInitialized() {
+	atomic.StoreInt32(&h.initialized, 1)
+}
+
+func (h *selfHealth) isInitialized() bool {
+	return atomic.LoadInt32(&h.initialized) == 1
+}
+
+func (h *selfHealth) started(name string) {
+	h.mu.Lock()
+	h.running[name] = true
+	h.mu.Unlock()
+}
+
+func (h *selfHealth) stopped(name string) {
+	h.mu.Lock()
+	delete(h.running, name)
+	h.mu.Unlock()
+}
+
+// runningWorkers returns the sorted names of the workers still running.
+func (h *selfHealth) runningWorkers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.running))
+	for name := range h.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reportAnomaly records a framework anomaly, logging only its first occurrence
+// per kind.
+func (s *SVC) reportAnomaly(kind, detail string) {
+	s.self.mu.Lock()
+	_, seen := s.self.anomalies[kind]
+	s.self.anomalies[kind] = detail
+	s.self.mu.Unlock()
+
+	if !seen {
+		s.logger.Warn("Framework anomaly detected", zap.String("anomaly", kind), zap.String("detail", detail))
+	}
+}
+
+// sendError delivers a worker error to Run without ever blocking the worker's
+// goroutine, reporting errors nobody is left to receive.
+func (s *SVC) sendError(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+		s.reportAnomaly(anomalyErrorDropped, err.Error())
+	}
+}
+
+// checkLeakedWorkers reports the workers whose Run did not return within the
+// wait period after termination.
+func (s *SVC) checkLeakedWorkers(wg *sync.WaitGroup, wait time.Duration) {
+	if !waitGroupTimeout(wg, wait) {
+		return
+	}
+	if leaked := s.self.runningWorkers(); len(leaked) > 0 {
+		s.reportAnomaly(anomalyWorkerLeaked, "still running after termination: "+strings.Join(leaked, ", "))
+	}
+}
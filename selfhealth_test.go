@@ -0,0 +1,40 @@
+package svc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfHealth(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	assert.Equal(t, HealthOK, s.self.CheckHealth().Status)
+
+	// An error nobody receives.
+	errs := make(chan error)
+	s.sendError(errs, errors.New("dummy error"))
+
+	// A worker still running after termination.
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	defer wg.Done()
+	s.self.started("leaky")
+	s.checkLeakedWorkers(&wg, time.Millisecond)
+
+	res := s.self.CheckHealth()
+	assert.Equal(t, HealthWarn, res.Status)
+	assert.Equal(t, "error_dropped: dummy error; "+
+		"worker_leaked: still running after termination: leaky", res.Detail)
+
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "worker svc: error_dropped")
+}
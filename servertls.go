@@ -0,0 +1,218 @@
+package svc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultTLSReloadInterval = 10 * time.Second
+
+// TLSOption defines WithHTTPServerTLS's option type.
+type TLSOption func(*certFiles)
+
+// TLSClientCAFile verifies clients' certificates against the CA certificates
+// in the PEM file, which is reloaded along with the server's certificate.
+func TLSClientCAFile(caFile string) TLSOption {
+	return func(c *certFiles) {
+		c.caFile = caFile
+	}
+}
+
+// TLSClientAuth sets how clients' certificates are verified when
+// TLSClientCAFile is used. Defaults to tls.RequireAndVerifyClientCert. Use
+// tls.VerifyClientCertIfGiven to keep serving clients that cannot present a
+// certificate, e.g. the kubelet's probes.
+func TLSClientAuth(auth tls.ClientAuthType) TLSOption {
+	return func(c *certFiles) {
+		c.clientAuth = auth
+	}
+}
+
+// TLSReloadInterval sets how often the files are checked for changes.
+// Defaults to 10s.
+func TLSReloadInterval(d time.Duration) TLSOption {
+	return func(c *certFiles) {
+		c.interval = d
+	}
+}
+
+// WithHTTPServerTLS is an option that serves the internal HTTP server over TLS
+// with the certificate and key in the PEM files, e.g. mounted from a
+// Kubernetes secret. The files are loaded on initialization and reloaded when
+// they change, managed as the "internal-tls-files" worker, which is not alive
+// once the certificate expired without being renewed. Clients' certificates
+// are verified with TLSClientCAFile. The configuration is also available to
+// other servers via MutualTLSConfig.
+func WithHTTPServerTLS(certFile, keyFile string, opts ...TLSOption) Option {
+	return func(s *SVC) error {
+		c := &certFiles{
+			certFile: certFile,
+			keyFile:  keyFile,
+			interval: defaultTLSReloadInterval,
+			done:     make(chan struct{}),
+		}
+		for _, o := range opts {
+			o(c)
+		}
+		if certFile == "" || keyFile == "" {
+			return errors.New("TLS requires a certificate and a key file")
+		}
+		if c.interval <= 0 {
+			return errors.New("TLS reload interval must be positive")
+		}
+
+		var cfg *tls.Config
+		if c.caFile == "" {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: c.getCertificate}
+		} else {
+			policy := MTLSPolicy{GetCertificate: c.getCertificate, ClientAuth: c.clientAuth}
+			cfg = newMutualTLSConfig(nil, policy)
+			// The CAs are reloaded too, thus are picked per connection.
+			cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				return newMutualTLSConfig(c.clientCAs(), policy), nil
+			}
+		}
+		s.useMutualTLS(cfg)
+		s.AddWorker("internal-tls-files", c)
+
+		return nil
+	}
+}
+
+var (
+	_ Worker = (*certFiles)(nil)
+	_ Aliver = (*certFiles)(nil)
+)
+
+// certFiles defines the internal worker reloading the TLS files.
+type certFiles struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+	interval                  time.Duration
+	logger                    *zap.Logger
+	done                      chan struct{}
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	cas     *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// Init implements the Worker interface. It loads the files so the servers can
+// serve TLS once running.
+func (c *certFiles) Init(logger *zap.Logger) error {
+	c.logger = logger
+
+	return c.reload()
+}
+
+// Run implements the Worker interface.
+func (c *certFiles) Run() error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.reload(); err != nil {
+				c.logger.Warn("Could not reload TLS files", zap.Error(err))
+			}
+		case <-c.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (c *certFiles) Terminate() error {
+	close(c.done)
+
+	return nil
+}
+
+// Alive implements the Aliver interface.
+func (c *certFiles) Alive() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.cert == nil {
+		return errors.New("no certificate loaded")
+	}
+	if leaf := c.cert.Leaf; time.Now().After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s without being renewed", leaf.NotAfter)
+	}
+	return nil
+}
+
+// reload loads the files if any changed since last loaded. The previous
+// certificate is kept on failure, e.g. while the files are being replaced.
+func (c *certFiles) reload() error {
+	files := []string{c.certFile, c.keyFile}
+	if c.caFile != "" {
+		files = append(files, c.caFile)
+	}
+	modTime := make(map[string]time.Time, len(files))
+	changed := false
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTime[f] = info.ModTime()
+		// Only reload writes modTime.
+		changed = changed || !c.modTime[f].Equal(info.ModTime())
+	}
+	if !changed {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	var cas *x509.CertPool
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return err
+		}
+		cas = x509.NewCertPool()
+		if !cas.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no CA certificate in %s", c.caFile)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert, c.cas, c.modTime = &cert, cas, modTime
+	c.logger.Info("TLS files loaded", zap.Time("expires_at", cert.Leaf.NotAfter))
+	return nil
+}
+
+func (c *certFiles) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return c.cert, nil
+}
+
+func (c *certFiles) clientCAs() *x509.CertPool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cas
+}
@@ -0,0 +1,115 @@
+package svc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeCertFiles writes the certificate and key of cert as PEM files.
+func writeCertFiles(t *testing.T, dir string, cert tls.Certificate, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	return certFile, keyFile
+}
+
+func TestWithHTTPServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueCert(t, nil, "")
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0o600))
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	cert1 := issueCert(t, &ca, "")
+	certFile, keyFile := writeCertFiles(t, dir, cert1, time.Now().Add(-time.Hour))
+	s, err := New("dummy-service", "v0.0.0", WithHTTPServer("0"), WithHTTPServerTLS(certFile, keyFile, TLSClientCAFile(caFile)))
+	require.NoError(t, err)
+	assert.NotNil(t, s.workers[internalHTTPServerName].(*httpServer).httpServer.TLSConfig)
+	files := s.workers["internal-tls-files"].(*certFiles)
+	require.NoError(t, files.Init(zap.NewNop()))
+	require.NoError(t, files.Alive())
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = s.MutualTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	servedCert := func(withClientCert bool) (*x509.Certificate, error) {
+		clientTLS := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		if withClientCert {
+			clientTLS.Certificates = []tls.Certificate{issueCert(t, &ca, "")}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			return nil, err
+		}
+		_ = res.Body.Close()
+		return res.TLS.PeerCertificates[0], nil
+	}
+	_, err = servedCert(false)
+	require.Error(t, err)
+	served, err := servedCert(true)
+	require.NoError(t, err)
+	assert.Equal(t, cert1.Leaf, served)
+
+	// Renewed certificate.
+	cert2 := issueCert(t, &ca, "")
+	writeCertFiles(t, dir, cert2, time.Now())
+	require.NoError(t, files.reload())
+	served, err = servedCert(true)
+	require.NoError(t, err)
+	assert.Equal(t, cert2.Leaf, served)
+
+	// Broken files keep the previous certificate.
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	require.Error(t, files.reload())
+	served, err = servedCert(true)
+	require.NoError(t, err)
+	assert.Equal(t, cert2.Leaf, served)
+}
+
+func TestWithHTTPServerTLSErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		certFile      string
+		opts          []TLSOption
+		expectedError string
+	}{
+		{
+			name:          "should require a certificate",
+			expectedError: "TLS requires a certificate and a key file",
+		},
+		{
+			name:          "should require a positive reload interval",
+			certFile:      "tls.crt",
+			opts:          []TLSOption{TLSReloadInterval(0)},
+			expectedError: "TLS reload interval must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New("dummy-service", "v0.0.0", WithHTTPServerTLS(tc.certFile, "tls.key", tc.opts...))
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package svc
+
+import (
+	"container/list"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShadowMaxPending = 1000
+	// shadowSuffix is appended to the name of the primary worker to name its
+	// shadow worker.
+	shadowSuffix = "-shadow"
+)
+
+// Shadow comparison results, as labeled in the svc_shadow_comparisons_total
+// metric.
+const (
+	ShadowMatch     = "match"
+	ShadowDiverged  = "diverged"
+	ShadowUnmatched = "unmatched"
+)
+
+// AddShadowWorker adds the primary worker under name, and a new version of it,
+// the shadow worker, under name followed by "-shadow", running alongside to be
+// validated before replacing the primary. The shadow's failures are logged
+// but neither shut down the service nor affect its health. Use a
+// ShadowComparator to compare the outputs of both.
+func (s *SVC) AddShadowWorker(name string, primary, shadow Worker) {
+	s.AddWorker(name, primary)
+	s.AddWorker(name+shadowSuffix, &shadowWorker{Worker: shadow, done: make(chan struct{})})
+}
+
+var _ Worker = (*shadowWorker)(nil)
+
+// shadowWorker contains the failures of a shadow worker. It does not expose
+// the shadow's health checks.
+type shadowWorker struct {
+	Worker
+	logger *zap.Logger
+	failed bool
+	done   chan struct{}
+}
+
+// Init implements the Worker interface.
+func (w *shadowWorker) Init(logger *zap.Logger) error {
+	w.logger = logger
+	if err := w.Worker.Init(logger); err != nil {
+		logger.Warn("Shadow worker failed to initialize, disabled", zap.Error(err))
+		w.failed = true
+	}
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (w *shadowWorker) Run() error {
+	if !w.failed {
+		err := w.Worker.Run()
+		if err == nil {
+			return nil
+		}
+		w.logger.Warn("Shadow worker failed", zap.Error(err))
+	}
+	// Keep the service running as if the shadow had not failed.
+	<-w.done
+	return nil
+}
+
+// Terminate implements the Worker interface.
+func (w *shadowWorker) Terminate() error {
+	close(w.done)
+	if w.failed {
+		return nil
+	}
+	if err := w.Worker.Terminate(); err != nil {
+		w.logger.Warn("Shadow worker failed to terminate", zap.Error(err))
+	}
+
+	return nil
+}
+
+// ShadowOption defines NewShadowComparator's option type.
+type ShadowOption func(*shadowConfig)
+
+type shadowConfig struct {
+	maxPending int
+}
+
+// ShadowMaxPending caps the number of outputs waiting for their counterpart.
+// Beyond it, the oldest are counted as unmatched and dropped. Defaults to
+// 1000.
+func ShadowMaxPending(n int) ShadowOption {
+	return func(c *shadowConfig) {
+		c.maxPending = n
+	}
+}
+
+// ShadowComparator compares the outputs of a primary worker and its shadow
+// worker, paired by key, e.g. a message ID. Results are counted in the
+// svc_shadow_comparisons_total metric and divergences are logged.
+type ShadowComparator[T any] struct {
+	s          *SVC
+	name       string
+	compare    func(primary, shadow T) error
+	maxPending int
+
+	mu      sync.Mutex
+	pending map[string]*list.Element
+	order   *list.List
+}
+
+type shadowOutputs[T any] struct {
+	key                   string
+	primary, shadow       T
+	hasPrimary, hasShadow bool
+}
+
+// NewShadowComparator returns a named ShadowComparator, comparing outputs
+// with compare, which returns why they diverge, if they do.
+func NewShadowComparator[T any](s *SVC, name string, compare func(primary, shadow T) error, opts ...ShadowOption) *ShadowComparator[T] {
+	cfg := shadowConfig{maxPending: defaultShadowMaxPending}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &ShadowComparator[T]{
+		s:          s,
+		name:       name,
+		compare:    compare,
+		maxPending: cfg.maxPending,
+		pending:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// Primary reports the primary worker's output for key.
+func (c *ShadowComparator[T]) Primary(key string, out T) {
+	c.report(key, func(o *shadowOutputs[T]) {
+		o.primary, o.hasPrimary = out, true
+	})
+}
+
+// Shadow reports the shadow worker's output for key.
+func (c *ShadowComparator[T]) Shadow(key string, out T) {
+	c.report(key, func(o *shadowOutputs[T]) {
+		o.shadow, o.hasShadow = out, true
+	})
+}
+
+func (c *ShadowComparator[T]) report(key string, set func(*shadowOutputs[T])) {
+	c.mu.Lock()
+	e, ok := c.pending[key]
+	if !ok {
+		e = c.order.PushBack(&shadowOutputs[T]{key: key})
+		c.pending[key] = e
+	}
+	o := e.Value.(*shadowOutputs[T])
+	set(o)
+	if !o.hasPrimary || !o.hasShadow {
+		for c.order.Len() > c.maxPending {
+			oldest := c.order.Remove(c.order.Front()).(*shadowOutputs[T])
+			delete(c.pending, oldest.key)
+			c.s.metrics.shadowComparisons.WithLabelValues(c.name, ShadowUnmatched).Inc()
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.order.Remove(e)
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	if err := c.compare(o.primary, o.shadow); err != nil {
+		c.s.metrics.shadowComparisons.WithLabelValues(c.name, ShadowDiverged).Inc()
+		c.s.logger.Warn("Shadow output diverged",
+			zap.String("shadow", c.name), zap.String("key", key), zap.Error(err))
+		return
+	}
+	c.s.metrics.shadowComparisons.WithLabelValues(c.name, ShadowMatch).Inc()
+}
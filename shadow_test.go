@@ -0,0 +1,97 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShadowComparator(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	c := NewShadowComparator(s, "consumer", func(primary, shadow int) error {
+		if primary != shadow {
+			return fmt.Errorf("%d != %d", primary, shadow)
+		}
+		return nil
+	}, ShadowMaxPending(1))
+
+	c.Primary("a", 1)
+	c.Shadow("a", 1)
+	c.Shadow("b", 2)
+	c.Primary("b", 3)
+	c.Primary("c", 4)
+	c.Primary("d", 5) // evicts c
+	c.Shadow("c", 4)  // evicts d
+
+	count := func(result string) float64 {
+		return testutil.ToFloat64(s.metrics.shadowComparisons.WithLabelValues("consumer", result))
+	}
+	assert.Equal(t, 1.0, count(ShadowMatch))
+	assert.Equal(t, 1.0, count(ShadowDiverged))
+	assert.Equal(t, 2.0, count(ShadowUnmatched))
+}
+
+func TestAddShadowWorker(t *testing.T) {
+	tests := []struct {
+		name        string
+		givenShadow func(failed chan struct{}) *WorkerMock
+	}{
+		{
+			name: "should keep running when the shadow fails to initialize",
+			givenShadow: func(failed chan struct{}) *WorkerMock {
+				return &WorkerMock{
+					InitFunc: func(*zap.Logger) error {
+						close(failed)
+						return errors.New("dummy error")
+					},
+				}
+			},
+		},
+		{
+			name: "should keep running when the shadow fails",
+			givenShadow: func(failed chan struct{}) *WorkerMock {
+				return &WorkerMock{
+					InitFunc: func(*zap.Logger) error { return nil },
+					RunFunc: func() error {
+						close(failed)
+						return errors.New("dummy error")
+					},
+					TerminateFunc: func() error { return nil },
+					HealthyFunc:   func() error { return errors.New("dummy error") },
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0")
+			require.NoError(t, err)
+
+			failed := make(chan struct{})
+			s.AddShadowWorker("consumer", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { return nil },
+				RunFunc: func() error {
+					<-failed
+					// Let the shadow's failure reach the service, if it does.
+					time.Sleep(10 * time.Millisecond)
+					s.Shutdown()
+					return nil
+				},
+				TerminateFunc: func() error { return nil },
+			}, tc.givenShadow(failed))
+			_, checked := s.checkHealth("consumer-shadow", s.workers["consumer-shadow"])
+			assert.False(t, checked)
+
+			require.NoError(t, s.RunE())
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package svc
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// shutdownSignals are the signals triggering the service shutdown by default,
+// see WithShutdownSignals.
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// WithSignalHandler is an option that calls fn whenever the service receives
+// the given signal while running, e.g. SIGUSR1 to trigger a cache dump.
+// Handlers are called one at a time. Shutdown signals cannot be handled. The
+// signal is caught from the start of Run; received while the workers
+// initialize, it is handled once they are.
+func WithSignalHandler(sig os.Signal, fn func()) Option {
+	return func(s *SVC) error {
+		for _, ss := range s.shutdownSignals {
+			if sig == ss {
+				return fmt.Errorf("signal %s is reserved for shutdown", sig)
+			}
+		}
+		s.signalHandlers[sig] = append(s.signalHandlers[sig], fn)
+
+		return nil
+	}
+}
+
+// userSignalsBuffer is the number of user-handled signals buffered until
+// dispatched.
+const userSignalsBuffer = 8
+
+// catchSignals catches the user-handled signals from now on, for them not to
+// hit their default action, e.g. killing the process, while the workers
+// initialize. They are buffered until dispatch is called, then dispatched to
+// their handlers until stop is called.
+func (s *SVC) catchSignals() (dispatch, stop func()) {
+	if len(s.signalHandlers) == 0 {
+		return func() {}, func() {}
+	}
+
+	sigs := make(chan os.Signal, userSignalsBuffer)
+	done := make(chan struct{})
+	for sig := range s.signalHandlers {
+		signal.Notify(sigs, sig)
+	}
+	dispatch = func() {
+		go func() {
+			for {
+				select {
+				case sig := <-sigs:
+					s.logger.Info("Caught user signal", zap.String("signal", sig.String()))
+					for _, fn := range s.signalHandlers[sig] {
+						s.callSignalHandler(sig, fn)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	stop = func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+	return dispatch, stop
+}
+
+func (s *SVC) callSignalHandler(sig os.Signal, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Signal handler panicked", zap.String("signal", sig.String()),
+				zap.Any("panic", r), zap.Stack("stack"))
+		}
+	}()
+	fn()
+}
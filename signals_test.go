@@ -0,0 +1,71 @@
+package svc
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithSignalHandler(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithSignalHandler(syscall.SIGTERM, func() {}))
+	require.EqualError(t, err, "signal terminated is reserved for shutdown")
+
+	called := make(chan struct{}, 1)
+	s, err := New("dummy-service", "v0.0.0",
+		WithSignalHandler(syscall.SIGUSR1, func() { panic("recovered") }),
+		WithSignalHandler(syscall.SIGUSR1, func() { called <- struct{}{} }),
+	)
+	require.NoError(t, err)
+
+	running := make(chan struct{})
+	stop := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { close(running); <-stop; return nil },
+		TerminateFunc: func() error { close(stop); return nil },
+	})
+	go s.Run()
+	defer s.Shutdown()
+	<-running
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	select {
+	case <-called:
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Signal handler has not been called")
+	}
+}
+
+func TestWithSignalHandlerDuringInit(t *testing.T) {
+	called := make(chan struct{}, 1)
+	s, err := New("dummy-service", "v0.0.0", WithSignalHandler(syscall.SIGUSR2, func() { called <- struct{}{} }))
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			// Would kill the process if not caught yet.
+			assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+			select {
+			case <-called:
+				t.Error("Signal handler called while initializing")
+			case <-time.After(50 * time.Millisecond):
+			}
+			return nil
+		},
+		RunFunc:       func() error { <-stop; return nil },
+		TerminateFunc: func() error { close(stop); return nil },
+	})
+	go s.Run()
+	defer s.Shutdown()
+
+	select {
+	case <-called:
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Signal handler has not been called")
+	}
+}
@@ -0,0 +1,80 @@
+package svc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SingleFlight coalesces concurrent calls with the same key into a single
+// execution whose result is shared by all callers.
+type SingleFlight struct {
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	counter *prometheus.CounterVec
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// SingleFlight returns a named SingleFlight tied to the service's life-cycle:
+// running calls get their context canceled once the workers are terminated.
+// Calls are counted in the svc_singleflight_calls_total metric.
+func (s *SVC) SingleFlight(name string) *SingleFlight {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &SingleFlight{
+		name:    name,
+		ctx:     ctx,
+		cancel:  cancel,
+		counter: s.metrics.singleflightCalls,
+		calls:   map[string]*flightCall{},
+	}
+	s.RegisterResource("singleflight-"+name, func(context.Context) error {
+		cancel()
+		return nil
+	}, 0)
+	return g
+}
+
+// Do executes fn once for all concurrent calls with the same key and returns
+// its result, and whether the result was shared with other callers. fn's
+// context is not canceled when the caller's context is; the caller stops
+// waiting instead.
+func (g *SingleFlight) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (v interface{}, shared bool, err error) {
+	g.mu.Lock()
+	c, shared := g.calls[key]
+	if !shared {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		g.mu.Unlock()
+		g.counter.WithLabelValues(g.name, "leader").Inc()
+
+		go func() {
+			defer func() {
+				g.mu.Lock()
+				delete(g.calls, key)
+				g.mu.Unlock()
+				close(c.done)
+			}()
+			c.value, c.err = fn(g.ctx)
+		}()
+	} else {
+		g.mu.Unlock()
+		g.counter.WithLabelValues(g.name, "shared").Inc()
+	}
+
+	select {
+	case <-c.done:
+		return c.value, shared, c.err
+	case <-ctx.Done():
+		return nil, shared, ctx.Err()
+	}
+}
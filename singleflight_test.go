@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleFlight(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	g := s.SingleFlight("lookups")
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func(context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	wg := sync.WaitGroup{}
+	results := make(chan interface{}, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _, err := g.Do(context.Background(), "key", fn)
+			assert.NoError(t, err)
+			results <- v
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(s.metrics.singleflightCalls.WithLabelValues("lookups", "shared")) == 2
+	}, 3*time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for v := range results {
+		assert.Equal(t, "value", v)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.metrics.singleflightCalls.WithLabelValues("lookups", "leader")))
+}
+
+func TestSingleFlightCanceledOnShutdown(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	g := s.SingleFlight("lookups")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = g.Do(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	s.Run()
+
+	_, _, err = g.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return nil, ctx.Err()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
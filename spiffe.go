@@ -0,0 +1,240 @@
+package svc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSPIFFERefreshInterval = time.Minute
+	defaultSPIFFEFetchTimeout    = 10 * time.Second
+)
+
+// SVID defines an X.509 SVID as served by the SPIFFE Workload API: the
+// workload's certificate chain and key, and the trust bundle to verify peers
+// with.
+type SVID struct {
+	Certificate tls.Certificate
+	Bundle      *x509.CertPool
+}
+
+// SVIDFetcher fetches the current X.509 SVID, e.g. from the SPIFFE Workload
+// API with go-spiffe's workloadapi.FetchX509Context, so the service does not
+// depend on the Workload API's gRPC client.
+type SVIDFetcher func(ctx context.Context) (*SVID, error)
+
+// SPIFFEOption defines WithSPIFFE's option type.
+type SPIFFEOption func(*svidSource)
+
+// SPIFFERefreshInterval sets how often the SVID is fetched to pick up
+// rotations. Defaults to 1m.
+func SPIFFERefreshInterval(d time.Duration) SPIFFEOption {
+	return func(src *svidSource) {
+		src.interval = d
+	}
+}
+
+// SPIFFEFetchTimeout bounds each fetch. Defaults to 10s.
+func SPIFFEFetchTimeout(d time.Duration) SPIFFEOption {
+	return func(src *svidSource) {
+		src.timeout = d
+	}
+}
+
+// WithSPIFFE is an option that fetches the service's X.509 SVID with fetch
+// and keeps it rotated, managed as the "internal-spiffe" worker. The SVID and
+// its trust bundle are used for mutual TLS of the internal HTTP server, as set
+// by policy (whose certificate fields are ignored), and for clients via
+// SPIFFEClientTLSConfig. The worker is not alive once the SVID expired without
+// being rotated.
+func WithSPIFFE(fetch SVIDFetcher, policy MTLSPolicy, opts ...SPIFFEOption) Option {
+	return func(s *SVC) error {
+		src := &svidSource{
+			fetch:    fetch,
+			interval: defaultSPIFFERefreshInterval,
+			timeout:  defaultSPIFFEFetchTimeout,
+			done:     make(chan struct{}),
+		}
+		for _, o := range opts {
+			o(src)
+		}
+		if src.interval <= 0 {
+			return errors.New("SPIFFE refresh interval must be positive")
+		}
+
+		policy.Certificates = nil
+		policy.GetCertificate = src.getCertificate
+		cfg := newMutualTLSConfig(nil, policy)
+		// The bundle rotates too, thus is picked per connection.
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return newMutualTLSConfig(src.bundle(), policy), nil
+		}
+		s.useMutualTLS(cfg)
+		s.svids = src
+		s.AddWorker("internal-spiffe", src)
+
+		return nil
+	}
+}
+
+// SPIFFEClientTLSConfig returns a TLS configuration for clients presenting
+// the service's SVID and verifying servers against its trust bundle,
+// authorizing them by their SPIFFE ID with verify, e.g. AllowSPIFFEIDs. It
+// returns nil unless WithSPIFFE is used.
+func (s *SVC) SPIFFEClientTLSConfig(verify func(id string) error) *tls.Config {
+	src := s.svids
+	if src == nil {
+		return nil
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return src.getCertificate(nil)
+		},
+		// SVIDs have no DNS names, the chain and SPIFFE ID are verified below.
+		InsecureSkipVerify: true, // nolint: gosec
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			certs := make([]*x509.Certificate, 0, len(rawCerts))
+			for _, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				certs = append(certs, cert)
+			}
+			if len(certs) == 0 {
+				return errors.New("no server certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range certs[1:] {
+				intermediates.AddCert(cert)
+			}
+			if _, err := certs[0].Verify(x509.VerifyOptions{
+				Roots:         src.bundle(),
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			}); err != nil {
+				return err
+			}
+			if verify == nil {
+				return nil
+			}
+			id, _ := SPIFFEID(certs[0])
+			return verify(id)
+		},
+	}
+}
+
+var (
+	_ Worker = (*svidSource)(nil)
+	_ Aliver = (*svidSource)(nil)
+)
+
+// svidSource defines the internal worker keeping the SVID rotated.
+type svidSource struct {
+	fetch    SVIDFetcher
+	interval time.Duration
+	timeout  time.Duration
+	logger   *zap.Logger
+	done     chan struct{}
+
+	mu      sync.RWMutex
+	svid    *SVID
+	lastErr error
+}
+
+// Init implements the Worker interface. It fetches the first SVID so the
+// servers can serve TLS once running.
+func (src *svidSource) Init(logger *zap.Logger) error {
+	src.logger = logger
+
+	return src.refresh()
+}
+
+// Run implements the Worker interface.
+func (src *svidSource) Run() error {
+	ticker := time.NewTicker(src.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := src.refresh(); err != nil {
+				src.logger.Warn("Could not rotate SVID", zap.Error(err))
+			}
+		case <-src.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (src *svidSource) Terminate() error {
+	close(src.done)
+
+	return nil
+}
+
+// Alive implements the Aliver interface.
+func (src *svidSource) Alive() error {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+
+	if src.svid == nil {
+		return errors.New("no SVID fetched")
+	}
+	if leaf := src.svid.Certificate.Leaf; leaf != nil && time.Now().After(leaf.NotAfter) {
+		return fmt.Errorf("SVID expired at %s without being rotated: %v", leaf.NotAfter, src.lastErr)
+	}
+	return nil
+}
+
+func (src *svidSource) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), src.timeout)
+	defer cancel()
+	svid, err := src.fetch(ctx)
+	if err == nil && len(svid.Certificate.Certificate) == 0 {
+		err = errors.New("SVID without certificate")
+	}
+	if err == nil && svid.Certificate.Leaf == nil {
+		svid.Certificate.Leaf, err = x509.ParseCertificate(svid.Certificate.Certificate[0])
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	src.lastErr = err
+	if err != nil {
+		return fmt.Errorf("fetch SVID: %w", err)
+	}
+	if src.svid == nil || !src.svid.Certificate.Leaf.Equal(svid.Certificate.Leaf) {
+		src.logger.Info("SVID rotated", zap.Time("expires_at", svid.Certificate.Leaf.NotAfter))
+	}
+	src.svid = svid
+	return nil
+}
+
+func (src *svidSource) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+
+	if src.svid == nil {
+		return nil, errors.New("no SVID fetched")
+	}
+	return &src.svid.Certificate, nil
+}
+
+func (src *svidSource) bundle() *x509.CertPool {
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+
+	if src.svid == nil {
+		return nil
+	}
+	return src.svid.Bundle
+}
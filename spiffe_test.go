@@ -0,0 +1,83 @@
+package svc
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithSPIFFE(t *testing.T) {
+	ca := issueCert(t, nil, "")
+	bundle := x509.NewCertPool()
+	bundle.AddCert(ca.Leaf)
+	serverID := "spiffe://example.org/service"
+
+	mu := sync.Mutex{}
+	current := issueCert(t, &ca, serverID)
+	var fetchErr error
+	fetch := func(context.Context) (*SVID, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return &SVID{Certificate: current, Bundle: bundle}, nil
+	}
+
+	s, err := New("dummy-service", "v0.0.0", WithSPIFFE(fetch, MTLSPolicy{
+		VerifySPIFFEID: AllowSPIFFEIDs(serverID),
+	}))
+	require.NoError(t, err)
+	src := s.workers["internal-spiffe"].(*svidSource)
+	require.NoError(t, src.Init(zap.NewNop()))
+	require.NoError(t, src.Alive())
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = s.MutualTLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The service calls itself: both ends present the SVID.
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: s.SPIFFEClientTLSConfig(AllowSPIFFEIDs(serverID))}}
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: s.SPIFFEClientTLSConfig(AllowSPIFFEIDs("spiffe://example.org/other"))}}
+	_, err = client.Get(srv.URL)
+	require.Error(t, err)
+
+	// Rotation
+	mu.Lock()
+	current = issueCert(t, &ca, serverID)
+	mu.Unlock()
+	require.NoError(t, src.refresh())
+	cert, err := src.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, current.Leaf, cert.Leaf)
+
+	// Failed rotation of an expired SVID
+	mu.Lock()
+	fetchErr = errors.New("dummy error")
+	mu.Unlock()
+	require.Error(t, src.refresh())
+	require.NoError(t, src.Alive())
+	src.svid.Certificate.Leaf.NotAfter = time.Now().Add(-time.Second)
+	require.ErrorContains(t, src.Alive(), "without being rotated: dummy error")
+}
+
+func TestSPIFFEClientTLSConfigWithoutSPIFFE(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, s.SPIFFEClientTLSConfig(nil))
+	assert.Nil(t, s.MutualTLSConfig())
+}
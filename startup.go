@@ -0,0 +1,67 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultStartupGateTimeout = time.Minute
+
+// WithStartupDelay is an option that delays initializing the workers by d,
+// e.g. to let a sidecar proxy start first.
+func WithStartupDelay(d time.Duration) Option {
+	return func(s *SVC) error {
+		s.startupDelay = d
+
+		return nil
+	}
+}
+
+// WithStartupGate is an option that blocks initializing the workers until fn
+// returns, e.g. to wait for DNS or a configuration service to be reachable.
+// Gates run in the order they were added after the startup delay, and the
+// service fails to start if a gate returns an error. The context passed to fn
+// is canceled once the gate timeout elapses, see WithStartupGateTimeout.
+func WithStartupGate(fn func(ctx context.Context) error) Option {
+	return func(s *SVC) error {
+		s.startupGates = append(s.startupGates, fn)
+
+		return nil
+	}
+}
+
+// WithStartupGateTimeout is an option that bounds the time all startup gates
+// may take together. Defaults to a minute.
+func WithStartupGateTimeout(d time.Duration) Option {
+	return func(s *SVC) error {
+		s.startupGateTimeout = d
+
+		return nil
+	}
+}
+
+// awaitStartup waits for the startup delay and gates.
+func (s *SVC) awaitStartup() error {
+	if s.startupDelay > 0 {
+		s.logger.Info("Delaying startup", zap.Duration("delay", s.startupDelay))
+		time.Sleep(s.startupDelay)
+	}
+	if len(s.startupGates) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), s.startupGateTimeout)
+	defer cancel()
+	for i, gate := range s.startupGates {
+		s.logger.Info("Waiting for startup gate", zap.Int("gate", i))
+		if err := gate(ctx); err != nil {
+			return fmt.Errorf("startup gate %d: %w", i, err)
+		}
+	}
+	s.logger.Info("Startup gates passed", zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
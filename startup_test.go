@@ -0,0 +1,60 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStartupGates(t *testing.T) {
+	var seq []string
+	s, err := New("dummy-service", "v0.0.0",
+		WithStartupDelay(time.Millisecond),
+		WithStartupGate(func(context.Context) error {
+			seq = append(seq, "gate1")
+			return nil
+		}),
+		WithStartupGate(func(context.Context) error {
+			seq = append(seq, "gate2")
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			seq = append(seq, "init")
+			return nil
+		},
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+
+	require.NoError(t, s.RunE())
+	assert.Equal(t, []string{"gate1", "gate2", "init"}, seq)
+}
+
+func TestStartupGateTimeout(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0",
+		WithStartupGateTimeout(time.Millisecond),
+		WithStartupGate(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			require.FailNow(t, "Worker should not be initialized")
+			return nil
+		},
+	})
+
+	err = s.RunE()
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualError(t, err, "startup gate 0: context deadline exceeded")
+}
@@ -2,12 +2,15 @@ package svc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/pprof"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
@@ -25,51 +29,170 @@ const (
 
 // SVC defines the worker life-cycle manager. It holds service metadata, router,
 // logger, and the workers.
+//
+// A service runs once: Run and RunE fail on later calls. Workers are added
+// before it runs; adding them while or after it runs is rejected. Shutdown is
+// safe to call from any goroutine, at any time.
 type SVC struct {
-	Name    string
-	Version string
-
-	Router *http.ServeMux
+	Name       string
+	Version    string
+	instanceID string
+	build      BuildInfo
+	startedAt  time.Time
+
+	options  []string
+	diagnose bool
+
+	Router      *http.ServeMux
+	routes      map[string]string
+	routeErrs   []error
+	middlewares []func(http.Handler) http.Handler
+	appHandler  http.Handler
+	handler     http.Handler
+	handlerOnce sync.Once
+
+	instrumentationExclusions []string
+	tlsConfig                 *tls.Config
+	svids                     *svidSource
+
+	lifecycleMu sync.Mutex
+	started     bool
 
 	TerminationGracePeriod time.Duration
 	TerminationWaitPeriod  time.Duration
+	terminationMu          sync.Mutex
+	terminating            bool
+	terminationWaited      bool
+	terminationDeadline    time.Time
+	concurrentTermination  bool
+	errorPolicy            ErrorPolicy
+	hooks                  map[EventType][]Hook
+	hookTimeout            time.Duration
+	maintenance            maintenance
+	exitCodes              map[ShutdownCause]int
+	runErr                 error
 	signals                chan os.Signal
+	drained                chan struct{}
+	signalHandlers         map[os.Signal][]func()
+	shutdownSignals        []os.Signal
+	configSources          []configSource
+	reloadMu               sync.Mutex
 
 	logger             *zap.Logger
 	zapOpts            []zap.Option
+	encoderConfigFuncs []func(*zapcore.EncoderConfig)
+	logBuffer          *logBufferConfig
+	logLevelSet        bool
+	logBuffers         []*zapcore.BufferedWriteSyncer
 	stdLogger          *log.Logger
 	atom               zap.AtomicLevel
+	workerLogLevels    map[string]zap.AtomicLevel
 	loggerRedirectUndo func()
 
 	workers             map[string]Worker
 	workerInitRetryOpts map[string][]retry.Option
+	workerTermRetryOpts map[string][]retry.Option
+	workerDeps          map[string][]string
+	workerEnvScopes     map[string]*ScopedEnv
+	workerTermTimeouts  map[string]time.Duration
+	tracesMu            sync.Mutex
+	workerTasks         map[string]workerTask
 	workersAdded        []string
 	workersInitialized  []string
+	initMu              sync.Mutex
+	parallelInit        bool
+	initTimeout         time.Duration
+	workersRan          bool
+	runCtx              context.Context
+	cancelRun           context.CancelFunc
+	workersDisabled     map[string]bool
+	workersDisabledEnv  bool
+	roles               map[string][]string
+	role                string
+
+	startupDelay       time.Duration
+	startupGates       []func(context.Context) error
+	startupGateTimeout time.Duration
 
 	gatherers        prometheus.Gatherers
+	upMetric         bool
+	metricsRoute     bool
 	internalRegister *prometheus.Registry
 	promHander       http.Handler
+	metrics          *metrics
+
+	deadLetterer DeadLetterer
+	panics       *panicReporting
+	events       *eventLog
+	resources    []resource
+	checkpoints  CheckpointStore
+	resourcesMu  sync.Mutex
+	self         *selfHealth
+
+	healthMu      sync.Mutex
+	healthFailing map[string]time.Time
+	healthCache   *healthCache
+	retryAfter    time.Duration
+
+	tasks taskTracker
+
+	crashMarker  *crashMarker
+	runReport    *runReport
+	workerStates *workerStates
+	degradation  *degradation
 }
 
 // New instantiates a new service by parsing configuration and initializing a
-// logger.
+// logger. An empty version is derived from the binary's build metadata, see
+// BuildInfo.
 func New(name, version string, opts ...Option) (*SVC, error) {
+	build := readBuildInfo()
+	if version == "" {
+		version = build.version()
+	}
 	s := &SVC{
-		Name:    name,
-		Version: version,
+		Name:       name,
+		Version:    version,
+		instanceID: NewULID(),
+		build:      build,
+		startedAt:  time.Now(),
 
 		Router: http.NewServeMux(),
+		routes: map[string]string{},
+
+		instrumentationExclusions: DefaultInstrumentationExclusions,
 
 		TerminationGracePeriod: defaultTerminationGracePeriod,
 		TerminationWaitPeriod:  defaultTerminationWaitPeriod,
+		hookTimeout:            defaultHookTimeout,
+		startupGateTimeout:     defaultStartupGateTimeout,
 		signals:                make(chan os.Signal, 3),
+		drained:                make(chan struct{}),
+		signalHandlers:         map[os.Signal][]func(){},
+		shutdownSignals:        shutdownSignals,
 
 		workers:             map[string]Worker{},
 		workersAdded:        []string{},
 		workersInitialized:  []string{},
 		workerInitRetryOpts: map[string][]retry.Option{},
+		workerTermRetryOpts: map[string][]retry.Option{},
+		workerDeps:          map[string][]string{},
+		workerEnvScopes:     map[string]*ScopedEnv{},
+		workerTermTimeouts:  map[string]time.Duration{},
+		workerLogLevels:     map[string]zap.AtomicLevel{},
+		workersDisabled:     map[string]bool{},
+		workerStates:        newWorkerStates(),
+
+		events: newEventLog(defaultEventLogSize),
+		self:   newSelfHealth(),
+
+		healthFailing: map[string]time.Time{},
+		retryAfter:    defaultRetryAfter,
+		exitCodes:     map[ShutdownCause]int{ShutdownWorkerFailure: 1, ShutdownCrashLoop: 1},
 	}
 
+	s.runCtx, s.cancelRun = context.WithCancel(context.Background())
+
 	if err := WithDevelopmentLogger()(s); err != nil {
 		return nil, err
 	}
@@ -77,29 +200,71 @@ func New(name, version string, opts ...Option) (*SVC, error) {
 	s.internalRegister = prometheus.NewRegistry()
 	s.gatherers = []prometheus.Gatherer{s.internalRegister, prometheus.DefaultGatherer}
 
+	m, err := newMetrics(s.internalRegister)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics = m
+
+	var defaults envDefaults
+	if err := LoadFromEnv(&defaults); err != nil {
+		return nil, err
+	}
+	s.applyEnvDefaultsBefore(defaults)
+
 	// Apply options
 	for _, o := range opts {
 		if err := o(s); err != nil {
 			return nil, err
 		}
+		s.options = append(s.options, optionName(o))
+	}
+
+	if err := s.applyEnvDefaultsAfter(defaults); err != nil {
+		return nil, err
+	}
+	// Once the logger and the instance ID are final.
+	if err := assignLogger(s, s.logger.With(zap.String("instance_id", s.instanceID)), s.atom); err != nil {
+		return nil, err
 	}
 
 	return s, nil
 }
 
+// ErrServiceStarted is returned when running a service that already ran, or is
+// running.
+var ErrServiceStarted = errors.New("service already started")
+
 // AddWorker adds a named worker to the service. Added workers order is
-// maintained.
+// maintained. Workers added once the service started are not run: the call
+// logs ErrServiceStarted.
 func (s *SVC) AddWorker(name string, w Worker) {
+	s.addWorker(name, w, nil)
+}
+
+// addWorker adds the worker, then calls attach, if any, to store its settings,
+// both atomically with respect to the service starting.
+func (s *SVC) addWorker(name string, w Worker, attach func()) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.started {
+		s.logger.Error("Worker added once the service started, ignoring it",
+			zap.String("worker", name), zap.Error(ErrServiceStarted), zap.Stack("stacktrace"))
+		return
+	}
 	if _, exists := s.workers[name]; exists {
 		s.logger.Fatal("Duplicate worker names!", zap.String("name", name), zap.Stack("stacktrace"))
 	}
-	if _, ok := w.(Healther); !ok {
+	impl := unwrapWorker(w)
+	_, isHealther := impl.(Healther)
+	_, isHealthChecker := impl.(HealthChecker)
+	if !isHealther && !isHealthChecker {
 		s.logger.Info("Worker does not implement Healther interface", zap.String("worker", name))
 	}
-	if _, ok := w.(Aliver); !ok {
+	if _, ok := impl.(Aliver); !ok {
 		s.logger.Info("Worker does not implement Aliver interface", zap.String("worker", name))
 	}
-	if g, ok := w.(Gatherer); ok {
+	if g, ok := impl.(Gatherer); ok {
 		s.AddGatherer(g.Gatherer())
 	} else {
 		s.logger.Info("Worker does not implement Gatherer interface", zap.String("worker", name))
@@ -107,13 +272,56 @@ func (s *SVC) AddWorker(name string, w Worker) {
 	// Track workers as ordered set to initialize them in order.
 	s.workersAdded = append(s.workersAdded, name)
 	s.workers[name] = w
+	if attach != nil {
+		attach()
+	}
+}
+
+// AddWorkers adds the named workers to the service in the given order, which
+// has to list every worker. Without order, workers are added sorted by name.
+func (s *SVC) AddWorkers(workers map[string]Worker, order ...string) {
+	if len(order) == 0 {
+		for name := range workers {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+	}
+	if len(order) != len(workers) {
+		s.logger.Fatal("Workers order does not match workers!", zap.Strings("order", order), zap.Stack("stacktrace"))
+	}
+	for _, name := range order {
+		w, ok := workers[name]
+		if !ok {
+			s.logger.Fatal("Workers order does not match workers!", zap.String("name", name), zap.Stack("stacktrace"))
+		}
+		s.AddWorker(name, w)
+	}
+}
+
+// AddWorkerFunc adds a named worker running the given function to the service.
+// The function's context is canceled when the worker is terminated.
+func (s *SVC) AddWorkerFunc(name string, run func(ctx context.Context) error) {
+	s.AddWorker(name, newFuncWorker(run))
+}
+
+// AddWorkerCtx adds a named context-aware worker to the service. Its context is
+// canceled when the service shuts down, after the termination wait period,
+// shared by all context-aware workers.
+func (s *SVC) AddWorkerCtx(name string, w WorkerCtx) {
+	s.AddWorker(name, newCtxWorker(s.runCtx, w))
 }
 
 // AddWorkerWithInitRetry adds a named worker to the service.
 // If the worker-initialization fails, it will be retried according to specified options.
 func (s *SVC) AddWorkerWithInitRetry(name string, w Worker, retryOpts []retry.Option) {
-	s.AddWorker(name, w)
-	s.workerInitRetryOpts[name] = retryOpts
+	s.addWorker(name, w, func() { s.workerInitRetryOpts[name] = retryOpts })
+}
+
+// AddWorkerWithTerminateRetry adds a named worker to the service.
+// If the worker-termination fails, it will be retried according to specified
+// options, bounded by the termination grace period.
+func (s *SVC) AddWorkerWithTerminateRetry(name string, w Worker, retryOpts []retry.Option) {
+	s.addWorker(name, w, func() { s.workerTermRetryOpts[name] = retryOpts })
 }
 
 func (s *SVC) AddGatherer(gatherer prometheus.Gatherer) {
@@ -122,62 +330,278 @@ func (s *SVC) AddGatherer(gatherer prometheus.Gatherer) {
 }
 
 // Run runs the service until either receiving an interrupt or a worker
-// terminates.
+// terminates. If a worker fails, the process exits according to the error
+// policy, see WithErrorPolicy.
 func (s *SVC) Run() {
-	s.logger.Info("Starting up service")
+	_, _ = s.run(true)
+}
+
+// RunE runs the service like Run, but returns the reason the service failed
+// instead of exiting the process: an *InitError if a worker failed to
+// initialize, or the error of the first failed worker. Workers are terminated
+// before RunE returns. A service runs once: later calls return
+// ErrServiceStarted.
+func (s *SVC) RunE() error {
+	_, err := s.run(false)
+	return err
+}
 
+func (s *SVC) run(exitOnFailure bool) (cause ShutdownCause, err error) {
+	if err := s.start(); err != nil {
+		s.logger.Error("Service can only run once", zap.Error(err))
+		return ShutdownCompleted, err
+	}
+	if s.diagnoseAndExit() {
+		return ShutdownCompleted, nil
+	}
+	if s.role != "" {
+		s.logger = s.logger.With(zap.String("role", s.role))
+	}
+	s.registerUpMetric()
+	s.logger.Info("Starting up service",
+		zap.String("revision", s.build.Revision),
+		zap.Bool("dirty", s.build.Dirty),
+		zap.String("go_version", s.build.GoVersion))
+	s.recordEvent(EventServiceStarting, "", nil)
+
+	// Exit once shut down, thus deferred first.
 	defer func() {
-		s.logger.Info("Shutting down service", zap.Duration("termination_grace_period", s.TerminationGracePeriod))
+		s.runErr = err
+		s.recordShutdown(cause)
+		s.writeRunReport(cause, err)
+		if code := s.exitCodes[cause]; exitOnFailure && code != 0 {
+			os.Exit(code)
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	defer func() {
+		// The grace period starts before the shutdown hooks, which it bounds.
+		_, grace := s.beginTermination()
+		s.logger.Info("Shutting down service", zap.Duration("termination_grace_period", grace))
+		s.recordEvent(EventServiceStopping, "", nil)
 		s.terminateWorkers()
+		s.checkLeakedWorkers(&wg, defaultLeakCheckWait)
+		s.closeResources()
 		s.logger.Info("Service shutdown completed")
-		_ = s.logger.Sync()
 		s.loggerRedirectUndo()
+		s.flushLogs()
 	}()
 
-	// Initializing workers in added order.
-	for _, name := range s.workersAdded {
-		s.logger.Debug("Initializing worker", zap.String("worker", name))
-		w := s.workers[name]
-		var err error
-		if opts, ok := s.workerInitRetryOpts[name]; ok {
-			//nolint:scopelint
-			err = retry.Do(func() error { return w.Init(s.logger.Named(name)) }, opts...)
-		} else {
-			err = w.Init(s.logger.Named(name))
-		}
-		if err != nil {
-			s.logger.Error("Could not initialize service", zap.String("worker", name), zap.Error(err))
-			return
+	// Caught before initializing, not to kill the process meanwhile.
+	dispatchSignals, stopSignals := s.catchSignals()
+	defer stopSignals()
+
+	if err = s.Validate(); err != nil {
+		s.logger.Error("Invalid service configuration", zap.Error(err))
+		return ShutdownStartupFailure, err
+	}
+	if err = s.disableWorkers(); err != nil {
+		s.logger.Error("Could not load worker configuration", zap.Error(err))
+		return ShutdownStartupFailure, err
+	}
+	if err = s.checkEnvScopes(); err != nil {
+		s.logger.Error("Could not load worker configuration", zap.Error(err))
+		return ShutdownStartupFailure, err
+	}
+	if err = s.orderWorkers(); err != nil {
+		s.logger.Error("Could not order workers", zap.Error(err))
+		return ShutdownStartupFailure, err
+	}
+	if err = s.checkCrashLoop(); err != nil {
+		s.logger.Error("Could not start service", zap.Error(err))
+		if errors.Is(err, errCrashLoop) {
+			return ShutdownCrashLoop, err
 		}
-		s.workersInitialized = append(s.workersInitialized, name)
+		return ShutdownStartupFailure, err
+	}
+	if err = s.awaitStartup(); err != nil {
+		s.logger.Error("Could not start service", zap.Error(err))
+		return ShutdownStartupFailure, err
 	}
 
-	errs := make(chan error)
-	wg := sync.WaitGroup{}
-	for name, w := range s.workers {
+	if err = s.initWorkers(); err != nil {
+		return ShutdownStartupFailure, err
+	}
+	s.self.setInitialized()
+	s.workersRan = true
+	s.recordEvent(EventServiceStarted, "", nil)
+
+	dispatchSignals()
+
+	errs := make(chan error, len(s.workers))
+	for _, name := range s.workersAdded {
+		w := s.workers[name]
 		wg.Add(1)
+		s.self.started(name)
 		go func(name string, w Worker) {
 			defer s.recoverWait(name, &wg, errs)
-			if err := w.Run(); err != nil {
+			s.recordEvent(EventWorkerStarted, name, nil)
+			var err error
+			// Attribute the worker's profile samples, and those of the
+			// goroutines it starts, to the worker.
+			s.traceWorker(name, "Run", func(ctx context.Context) {
+				pprof.Do(ctx, pprof.Labels("worker", name), func(context.Context) {
+					err = w.Run()
+				})
+			})
+			if err != nil {
+				s.recordEvent(EventWorkerFailed, name, err)
 				err = fmt.Errorf("worker %s exited: %w", name, err)
-				errs <- err
+				s.sendError(errs, err)
+				return
 			}
+			s.recordEvent(EventWorkerFinished, name, nil)
 		}(name, w)
 	}
 
-	signal.Notify(s.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Notify(s.signals, s.shutdownSignals...)
 
-	select {
-	case err := <-errs:
-		if !errors.Is(err, context.Canceled) {
-			s.logger.Fatal("Worker Init/Run failure", zap.Error(err))
+	finished := waitGroupToChan(&wg)
+	for {
+		select {
+		case err := <-errs:
+			if errors.Is(err, context.Canceled) {
+				s.logger.Warn("Worker context canceled", zap.Error(err))
+				return ShutdownCompleted, nil
+			}
+			switch {
+			case s.errorPolicy == Continue:
+				s.logger.Error("Worker Init/Run failure, continuing", zap.Error(err))
+				continue
+			case exitOnFailure && s.errorPolicy == Exit:
+				s.logger.Fatal("Worker Init/Run failure", zap.Error(err))
+			}
+			s.logger.Error("Worker Init/Run failure", zap.Error(err))
+			return ShutdownWorkerFailure, err
+		case sig := <-s.signals:
+			s.logger.Warn("Caught signal", zap.String("signal", sig.String()))
+			return ShutdownSignal, nil
+		case <-finished:
+			s.logger.Info("All workers have finished")
+			return ShutdownCompleted, nil
+		}
+	}
+}
+
+// initWorkers initializes the workers in added order, or concurrently with
+// WithParallelInit.
+func (s *SVC) initWorkers() error {
+	if s.parallelInit {
+		return s.initWorkersConcurrently()
+	}
+	for _, name := range s.workersAdded {
+		if err := s.initWorker(name); err != nil {
+			return s.initError(name, err)
+		}
+	}
+	return nil
+}
+
+// initWorker initializes the worker, bounded by the init timeout, if any, and
+// restores its checkpoint.
+func (s *SVC) initWorker(name string) error {
+	if info, ok := s.describeWorker(name); ok {
+		s.logger.Info("Initializing worker", zap.String("worker", name),
+			zap.String("description", info.Description),
+			zap.String("owner", info.Owner),
+			zap.Strings("dependencies", info.Dependencies),
+			zap.Strings("endpoints", info.Endpoints))
+	} else {
+		s.logger.Debug("Initializing worker", zap.String("worker", name))
+	}
+	w := s.workers[name]
+	init := func() error {
+		if opts, ok := s.workerInitRetryOpts[name]; ok {
+			return s.initWithRetry(name, w, opts)
+		}
+		return w.Init(s.workerLogger(name))
+	}
+	start := time.Now()
+	var err error
+	s.traceWorker(name, "Init", func(context.Context) {
+		if s.initTimeout > 0 {
+			err = callWithTimeout(init, s.initTimeout)
+		} else {
+			err = init()
+		}
+	})
+	s.metrics.workerInitSeconds.WithLabelValues(name).Set(time.Since(start).Seconds())
+	if err == nil {
+		// Terminate the worker even if restoring its checkpoint fails.
+		s.initMu.Lock()
+		s.workersInitialized = append(s.workersInitialized, name)
+		s.initMu.Unlock()
+		err = s.restoreCheckpoint(name, w)
+	}
+	if err != nil {
+		s.endWorkerTrace(name)
+		s.logger.Error("Could not initialize service", zap.String("worker", name), zap.Error(err))
+		s.recordEvent(EventWorkerInitFailed, name, err)
+		return err
+	}
+	s.recordEvent(EventWorkerInitialized, name, nil)
+	return nil
+}
+
+// initWithRetry initializes the worker, retrying according to the options.
+func (s *SVC) initWithRetry(name string, w Worker, opts []retry.Option) error {
+	start := time.Now()
+	attempts := 0
+	var errs []error
+	err := retry.Do(func() error {
+		attempts++
+		err := w.Init(s.workerLogger(name))
+		if err != nil {
+			errs = append(errs, err)
+			if len(errs) > initRetryErrorsKept {
+				errs = errs[1:]
+			}
+		}
+		return err
+	}, opts...)
+	if err == nil {
+		return nil
+	}
+
+	exhausted := &InitRetryExhaustedError{Worker: name, Attempts: attempts, Elapsed: time.Since(start), Errors: errs}
+	s.logger.Error("Worker init retries exhausted",
+		zap.String("worker", name),
+		zap.Int("attempts", exhausted.Attempts),
+		zap.Duration("elapsed", exhausted.Elapsed),
+		zap.Errors("errors", exhausted.Errors))
+	return exhausted
+}
+
+// initError returns the error of the worker failing to initialize, listing
+// the workers initialized before.
+func (s *SVC) initError(name string, err error) *InitError {
+	e := &InitError{Worker: name, Err: err}
+	initialized := map[string]bool{name: true}
+	for _, n := range s.workersInitialized {
+		initialized[n] = true
+		if n != name {
+			e.Initialized = append(e.Initialized, n)
+		}
+	}
+	for _, n := range s.workersAdded {
+		if !initialized[n] {
+			e.NotInitialized = append(e.NotInitialized, n)
 		}
-		s.logger.Warn("Worker context canceled", zap.Error(err))
-	case sig := <-s.signals:
-		s.logger.Warn("Caught signal", zap.String("signal", sig.String()))
-	case <-waitGroupToChan(&wg):
-		s.logger.Info("All workers have finished")
 	}
+	return e
+}
+
+// start marks the service as started, failing if it already was. Workers can
+// no longer be added from then on.
+func (s *SVC) start() error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.started {
+		return ErrServiceStarted
+	}
+	s.started = true
+	return nil
 }
 
 // Shutdown signals the framework to terminate any already started workers and
@@ -185,8 +609,15 @@ func (s *SVC) Run() {
 // The call is non-blocking. Terminating the workers comes with the guarantees
 // as the `Run` method: All workers are given a total terminate grace-period
 // until the service goes ahead completes the shutdown phase.
+// It is safe to call concurrently and repeatedly, including while or after the
+// workers terminate. Called before Run, the service shuts down once running.
 func (s *SVC) Shutdown() {
-	s.signals <- syscall.SIGTERM
+	// Never block on an already full signals channel, e.g. once nothing
+	// receives from it anymore.
+	select {
+	case s.signals <- syscall.SIGTERM:
+	default:
+	}
 }
 
 // MustInit is a convenience function to check for and halt on errors.
@@ -206,35 +637,219 @@ func (s *SVC) Logger() *zap.Logger {
 	return s.logger
 }
 
+// LogLevel returns the current level of the service's logger.
+func (s *SVC) LogLevel() zapcore.Level {
+	return s.atom.Level()
+}
+
+// SetLogLevel changes the level of the service's logger at runtime, e.g. to
+// debug while troubleshooting an incident.
+func (s *SVC) SetLogLevel(level zapcore.Level) {
+	s.atom.SetLevel(level)
+	s.logger.Info("Log level changed", zap.Stringer("level", level))
+}
+
+// disableWorkers removes the workers disabled by configuration from the set of
+// workers to initialize and run.
+func (s *SVC) disableWorkers() error {
+	disabled, err := s.disabledWorkers()
+	if err != nil {
+		return err
+	}
+
+	for name := range disabled {
+		if _, exists := s.workers[name]; !exists {
+			s.logger.Warn("Unknown worker cannot be disabled", zap.String("worker", name))
+			continue
+		}
+		s.logger.Info("Worker disabled", zap.String("worker", name))
+		delete(s.workers, name)
+	}
+
+	enabled := s.workersAdded[:0]
+	for _, name := range s.workersAdded {
+		if !disabled[name] {
+			enabled = append(enabled, name)
+		}
+	}
+	s.workersAdded = enabled
+
+	return nil
+}
+
+// disabledWorkers returns the workers disabled by configuration.
+func (s *SVC) disabledWorkers() (map[string]bool, error) {
+	disabled := map[string]bool{}
+	for name := range s.workersDisabled {
+		disabled[name] = true
+	}
+	for _, name := range s.roleDisabledWorkers() {
+		disabled[name] = true
+	}
+	if s.workersDisabledEnv {
+		var cfg workersConfig
+		if err := LoadFromEnv(&cfg); err != nil {
+			return nil, err
+		}
+		for _, name := range cfg.Disabled {
+			disabled[name] = true
+		}
+	}
+	return disabled, nil
+}
+
+type workersConfig struct {
+	Disabled []string `env:"WORKERS_DISABLED" envSeparator:","`
+}
+
+// terminateWorkers reports the service not ready, for load balancers to stop
+// sending traffic, waits the termination wait period, drains the workers
+// implementing Drainer, and terminates the workers, all within the grace
+// period.
 func (s *SVC) terminateWorkers() {
-	s.logger.Info("Terminating workers down service", zap.Duration("termination_grace_period", s.TerminationGracePeriod))
+	s.setNotReady(terminatingKey, "shutting down")
+	waitPeriod, gracePeriod := s.beginTermination()
+	s.logger.Info("Terminating workers down service", zap.Duration("termination_grace_period", gracePeriod))
+	start := time.Now()
 
 	// terminate only initialized workers
+	var mu sync.Mutex
+	pending := map[string]bool{}
+	for _, name := range s.workersInitialized {
+		pending[name] = true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		time.Sleep(s.TerminationWaitPeriod)
-		for _, name := range s.workersInitialized {
-			defer func(name string) {
-				w := s.workers[name]
-				if err := w.Terminate(); err != nil {
-					s.logger.Error("Terminated with error",
-						zap.String("worker", name),
-						zap.Error(err))
+		time.Sleep(waitPeriod)
+		s.drainWorkers(ctx)
+		s.waitTasks(ctx)
+		close(s.drained)
+		s.cancelRun()
+		terminated := func(name string) {
+			mu.Lock()
+			delete(pending, name)
+			mu.Unlock()
+		}
+		if s.concurrentTermination {
+			s.terminateConcurrently(ctx, terminated)
+			return
+		}
+		// Terminate in reverse initialization order.
+		for i := len(s.workersInitialized) - 1; i >= 0; i-- {
+			name := s.workersInitialized[i]
+			s.terminateWorker(ctx, name)
+			terminated(name)
+		}
+	}()
+	timedOut := waitGroupTimeout(&wg, gracePeriod)
+
+	mu.Lock()
+	exceeded := len(pending)
+	mu.Unlock()
+	stats := shutdownStats{Duration: time.Since(start), WorkersExceeded: exceeded, GracePeriodExceeded: timedOut}
+	s.recordShutdownStats(stats)
+	if timedOut {
+		s.logger.Warn("Termination grace period exceeded",
+			zap.Int("pending_workers", exceeded), zap.Duration("duration", stats.Duration))
+		return
+	}
+	s.logger.Info("All workers terminated", zap.Duration("duration", stats.Duration))
+}
+
+// terminateConcurrently terminates the workers concurrently, except that
+// workers declaring dependencies are terminated before them.
+func (s *SVC) terminateConcurrently(ctx context.Context, terminated func(name string)) {
+	done := map[string]chan struct{}{}
+	for _, name := range s.workersInitialized {
+		done[name] = make(chan struct{})
+	}
+	dependents := map[string][]string{}
+	for _, name := range s.workersInitialized {
+		for _, dep := range s.workerDeps[name] {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range s.workersInitialized {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer close(done[name])
+			for _, d := range dependents[name] {
+				select {
+				case <-done[d]:
+				case <-ctx.Done():
 				}
-				s.logger.Info("Worker terminated", zap.String("worker", name))
-			}(name)
+			}
+			s.terminateWorker(ctx, name)
+			terminated(name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// terminateWorker terminates the worker, bounded by its termination timeout,
+// if any.
+func (s *SVC) terminateWorker(ctx context.Context, name string) {
+	w := s.workers[name]
+	if !s.workersRan {
+		s.logger.Info("Terminating worker that never ran", zap.String("worker", name))
+	}
+	terminate := w.Terminate
+	if opts, ok := s.workerTermRetryOpts[name]; ok {
+		opts = append(opts[:len(opts):len(opts)], retry.Context(ctx))
+		terminate = func() error { return retry.Do(w.Terminate, opts...) }
+	}
+	var err error
+	s.traceWorker(name, "Terminate", func(context.Context) {
+		if timeout, ok := s.workerTermTimeouts[name]; ok {
+			err = callWithTimeout(terminate, timeout)
+		} else {
+			err = terminate()
 		}
+	})
+	s.endWorkerTrace(name)
+	if err != nil {
+		s.logger.Error("Terminated with error",
+			zap.String("worker", name),
+			zap.Error(err))
+		s.recordEvent(EventWorkerTermFailed, name, err)
+	} else {
+		s.recordEvent(EventWorkerTerminated, name, nil)
+		if s.workersRan {
+			s.saveCheckpoint(ctx, name, w)
+		}
+	}
+	s.logger.Info("Worker terminated", zap.String("worker", name))
+}
+
+// callWithTimeout calls fn, giving up on waiting for it after timeout.
+func callWithTimeout(fn func() error, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- fn()
 	}()
-	waitGroupTimeout(&wg, s.TerminationGracePeriod)
-	s.logger.Info("All workers terminated")
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
 }
 
-func waitGroupTimeout(wg *sync.WaitGroup, d time.Duration) {
+// waitGroupTimeout waits for the wait group or the given duration, and reports
+// whether the duration elapsed first.
+func waitGroupTimeout(wg *sync.WaitGroup, d time.Duration) bool {
 	select {
 	case <-waitGroupToChan(wg):
+		return false
 	case <-time.After(d):
+		return true
 	}
 }
 
@@ -248,14 +863,19 @@ func waitGroupToChan(wg *sync.WaitGroup) <-chan struct{} {
 }
 
 func (s *SVC) recoverWait(name string, wg *sync.WaitGroup, errors chan<- error) {
-	wg.Done()
+	// Deliver the panic before reporting the worker done, for Run not to
+	// take it as finished.
+	defer wg.Done()
+	defer s.self.stopped(name)
 	if r := recover(); r != nil {
+		s.reportPanic(name, r)
+		s.recordEvent(EventWorkerFailed, name, fmt.Errorf("panic: %v", r))
 		if err, ok := r.(error); ok {
 			s.logger.Error("recover panic", zap.String("worker", name),
 				zap.Error(err), zap.Stack("stack"))
-			errors <- err
+			s.sendError(errors, err)
 		} else {
-			errors <- fmt.Errorf("%v", r)
+			s.sendError(errors, fmt.Errorf("%v", r))
 		}
 	}
 }
@@ -1,12 +1,17 @@
 package svc
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"runtime/pprof"
 	"testing"
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -90,6 +95,67 @@ func TestShutdown(t *testing.T) {
 	}
 }
 
+func TestShutdownRepeated(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	done := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { s.Shutdown(); <-done; return nil },
+		TerminateFunc: func() error { s.Shutdown(); close(done); return nil },
+	})
+
+	for i := 0; i < 10; i++ {
+		s.Shutdown()
+	}
+	require.NoError(t, s.RunE())
+	for i := 0; i < 10; i++ {
+		s.Shutdown()
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	running, done := make(chan struct{}), make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { close(running); <-done; return nil },
+		TerminateFunc: func() error { close(done); return nil },
+	})
+
+	errs := make(chan error)
+	go func() { errs <- s.RunE() }()
+	<-running
+	require.ErrorIs(t, s.RunE(), ErrServiceStarted)
+
+	s.Shutdown()
+	require.NoError(t, <-errs)
+	require.ErrorIs(t, s.RunE(), ErrServiceStarted)
+}
+
+func TestAddWorkerStarted(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+	added := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			go func() {
+				defer close(added)
+				s.AddWorkerWithDeps("late-worker", &WorkerMock{}, "dummy-worker")
+			}()
+			<-added
+			return nil
+		},
+		TerminateFunc: func() error { return nil },
+	})
+
+	require.NoError(t, s.RunE())
+	assert.NotContains(t, s.workers, "late-worker")
+	assert.NotContains(t, s.workerDeps, "late-worker")
+}
+
 func TestContextCanceled(t *testing.T) {
 	dummyWorker := &WorkerMock{
 		InitFunc: func(*zap.Logger) error { return nil },
@@ -202,3 +268,359 @@ func TestSVC_AddWorkerWithInitRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkersDisabled(t *testing.T) {
+	t.Setenv("WORKERS_DISABLED", "w2,unknown")
+
+	s, err := New("dummy-name", "dummy-version", WithWorkersDisabled("w3"), WithWorkersDisabledFromEnv())
+	require.NoError(t, err)
+
+	var actualSeq []string
+	for _, name := range []string{"w1", "w2", "w3"} {
+		name := name
+		s.AddWorker(name, &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				actualSeq = append(actualSeq, name)
+				return nil
+			},
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { return nil },
+		})
+	}
+	s.Run()
+
+	assert.Equal(t, []string{"w1"}, actualSeq)
+}
+
+func TestTerminationMetrics(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	path := filepath.Join(t.TempDir(), "svc.crash")
+
+	s, err := New("dummy-service", "v0.0.0", WithTerminationGracePeriod(50*time.Millisecond), WithCrashMarker(path))
+	require.NoError(t, err)
+	// Workers are terminated in reverse order, thus fast first.
+	s.AddWorker("slow", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { <-block; return nil },
+	})
+	s.AddWorker("fast", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return nil },
+		TerminateFunc: func() error { return nil },
+	})
+	require.NoError(t, s.RunE())
+
+	// Exported by the next run.
+	next, err := New("dummy-service", "v0.0.0", WithCrashMarker(path))
+	require.NoError(t, err)
+	require.NoError(t, next.checkCrashLoop())
+	assert.Equal(t, 1.0, testutil.ToFloat64(next.metrics.shutdownGracePeriodExceeded))
+	assert.Equal(t, 1.0, testutil.ToFloat64(next.metrics.shutdownWorkersExceeded))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(next.metrics.shutdownDuration), 0.05)
+}
+
+func TestSVC_AddWorkerWithTerminateRetry(t *testing.T) {
+	var attempts uint
+	w := &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc:  func() error { return nil },
+		TerminateFunc: func() error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		},
+	}
+
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+
+	s.AddWorkerWithTerminateRetry("test", w, []retry.Option{retry.Attempts(10), retry.Delay(1 * time.Millisecond)})
+	s.Run()
+	require.Equal(t, uint(3), attempts)
+}
+
+func TestAddWorkers(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+
+	var actualSeq []string
+	workers := map[string]Worker{}
+	for _, name := range []string{"w1", "w2", "w3"} {
+		name := name
+		workers[name] = &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				actualSeq = append(actualSeq, name)
+				return nil
+			},
+			RunFunc:       func() error { return nil },
+			TerminateFunc: func() error { return nil },
+		}
+	}
+
+	s.AddWorkers(workers, "w3", "w1", "w2")
+	s.Run()
+
+	assert.Equal(t, []string{"w3", "w1", "w2"}, actualSeq)
+}
+
+func TestAddWorkerFunc(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+
+	running := make(chan struct{})
+	canceled := make(chan struct{})
+	s.AddWorkerFunc("func-worker", func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+
+	termSvcCh := make(chan struct{})
+	go func() { s.Run(); close(termSvcCh) }()
+	<-running
+	s.Shutdown()
+
+	select {
+	case <-termSvcCh:
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Service has not been shut down")
+	}
+	select {
+	case <-canceled:
+	default:
+		require.FailNow(t, "Worker context has not been canceled")
+	}
+}
+
+func TestRunEInitFailure(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+
+	var terminated []string
+	for _, name := range []string{"w1", "w2", "w3", "w4"} {
+		name := name
+		s.AddWorker(name, &WorkerMock{
+			InitFunc: func(*zap.Logger) error {
+				if name == "w3" {
+					return errors.New("dummy error")
+				}
+				return nil
+			},
+			RunFunc: func() error {
+				require.FailNow(t, "Worker should not run")
+				return nil
+			},
+			TerminateFunc: func() error {
+				terminated = append(terminated, name)
+				return nil
+			},
+		})
+	}
+
+	err = s.RunE()
+	var initErr *InitError
+	require.ErrorAs(t, err, &initErr)
+	assert.Equal(t, "w3", initErr.Worker)
+	assert.Equal(t, []string{"w1", "w2"}, initErr.Initialized)
+	assert.Equal(t, []string{"w4"}, initErr.NotInitialized)
+	assert.EqualError(t, err, "worker w3 failed to initialize (initialized: [w1, w2], not initialized: [w4]): dummy error")
+	assert.Equal(t, []string{"w2", "w1"}, terminated)
+}
+
+func TestRunEWorkerFailure(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc:      func(*zap.Logger) error { return nil },
+		RunFunc:       func() error { return errors.New("dummy error") },
+		TerminateFunc: func() error { return nil },
+	})
+
+	require.EqualError(t, s.RunE(), "worker dummy-worker exited: dummy error")
+	require.EqualError(t, s.Err(), "worker dummy-worker exited: dummy error")
+}
+
+func TestErr(t *testing.T) {
+	tests := []struct {
+		name          string
+		run           func(s *SVC, terminated chan struct{}) error
+		expectedCause ShutdownCause
+		expectedError string
+	}{
+		{
+			name:          "completed",
+			run:           func(*SVC, chan struct{}) error { return nil },
+			expectedCause: ShutdownCompleted,
+		},
+		{
+			name: "signal",
+			run: func(s *SVC, terminated chan struct{}) error {
+				s.Shutdown()
+				<-terminated
+				return nil
+			},
+			expectedCause: ShutdownSignal,
+		},
+		{
+			name:          "worker failure",
+			run:           func(*SVC, chan struct{}) error { return errors.New("dummy error") },
+			expectedCause: ShutdownWorkerFailure,
+			expectedError: "worker dummy-worker exited: dummy error",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-name", "dummy-version")
+			require.NoError(t, err)
+			terminated := make(chan struct{})
+			s.AddWorker("dummy-worker", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { return nil },
+				RunFunc:  func() error { return tc.run(s, terminated) },
+				TerminateFunc: func() error {
+					close(terminated)
+					return nil
+				},
+			})
+
+			cause, err := s.run(false)
+			assert.Equal(t, tc.expectedCause, cause)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				require.EqualError(t, s.Err(), tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, s.Err())
+		})
+	}
+}
+
+func TestWithExitCodes(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version", WithExitCodes(map[ShutdownCause]int{ShutdownSignal: 3}))
+	require.NoError(t, err)
+	assert.Equal(t, map[ShutdownCause]int{ShutdownSignal: 3, ShutdownWorkerFailure: 1, ShutdownCrashLoop: 1}, s.exitCodes)
+
+	_, err = New("dummy-name", "dummy-version", WithExitCodes(map[ShutdownCause]int{ShutdownStartupFailure: 130}))
+	require.EqualError(t, err, "exit code 130 of startup failure out of range 0-125")
+}
+
+func TestWithErrorPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        ErrorPolicy
+		expectedError string
+		expectedRuns  int
+	}{
+		{
+			name:          "shutdown gracefully",
+			policy:        ShutdownGracefully,
+			expectedError: "worker failing exited: dummy error",
+		},
+		{
+			name:          "exit shuts down gracefully with RunE",
+			policy:        Exit,
+			expectedError: "worker failing exited: dummy error",
+		},
+		{
+			name:         "continue",
+			policy:       Continue,
+			expectedRuns: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-name", "dummy-version", WithErrorPolicy(tc.policy))
+			require.NoError(t, err)
+			failed := make(chan struct{})
+			s.AddWorker("failing", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { return nil },
+				RunFunc: func() error {
+					defer close(failed)
+					return errors.New("dummy error")
+				},
+				TerminateFunc: func() error { return nil },
+			})
+			done := make(chan struct{})
+			runs := 0
+			s.AddWorker("remaining", &WorkerMock{
+				InitFunc: func(*zap.Logger) error { return nil },
+				RunFunc: func() error {
+					<-failed
+					select {
+					case <-done:
+						return nil
+					case <-time.After(50 * time.Millisecond):
+						runs++
+						s.Shutdown()
+					}
+					<-done
+					return nil
+				},
+				TerminateFunc: func() error {
+					close(done)
+					return nil
+				},
+			})
+
+			err = s.RunE()
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedRuns, runs)
+		})
+	}
+
+	_, err := New("dummy-name", "dummy-version", WithErrorPolicy(ErrorPolicy(42)))
+	require.EqualError(t, err, "unknown error policy 42")
+}
+
+func TestInitRetryExhaustedError(t *testing.T) {
+	s, err := New("dummy-name", "dummy-version")
+	require.NoError(t, err)
+	attempts := 0
+	s.AddWorkerWithInitRetry("test", &WorkerMock{
+		InitFunc: func(*zap.Logger) error {
+			attempts++
+			return fmt.Errorf("failed %d", attempts)
+		},
+	}, []retry.Option{retry.Attempts(7), retry.Delay(time.Millisecond)})
+
+	err = s.RunE()
+	var exhausted *InitRetryExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, "test", exhausted.Worker)
+	assert.Equal(t, 7, exhausted.Attempts)
+	assert.Positive(t, exhausted.Elapsed)
+	require.Len(t, exhausted.Errors, 5)
+	assert.Regexp(t, `^init of worker test failed after 7 attempts in \S+, last errors: #3: failed 3; #4: failed 4; #5: failed 5; #6: failed 6; #7: failed 7$`, exhausted.Error())
+}
+
+func TestWorkerProfileLabels(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var profile bytes.Buffer
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			err := pprof.Lookup("goroutine").WriteTo(&profile, 1)
+			s.Shutdown()
+			return err
+		},
+		TerminateFunc: func() error { return nil },
+	})
+	require.NoError(t, s.RunE())
+
+	assert.Contains(t, profile.String(), `labels: {"worker":"dummy-worker"}`)
+}
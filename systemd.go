@@ -0,0 +1,155 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WithSystemdNotify is an option that reports the service's state to systemd,
+// for services run as Type=notify units: READY=1 once all workers are
+// initialized, and STOPPING=1 when the shutdown starts. With WatchdogSec set,
+// the "internal-systemd-watchdog" worker sends WATCHDOG=1 at half the watchdog
+// interval as long as the workers implementing Aliver are alive, so systemd
+// restarts the service once they are not. It does nothing when the service is
+// not run by systemd, i.e. NOTIFY_SOCKET is not set.
+func WithSystemdNotify() Option {
+	return func(s *SVC) error {
+		socket := os.Getenv("NOTIFY_SOCKET")
+		if socket == "" {
+			return nil
+		}
+		n := &systemdNotifier{socket: socket}
+
+		s.OnStart(n.hook("READY=1"))
+		s.OnShutdown(n.hook("STOPPING=1"))
+
+		interval, err := systemdWatchdogInterval()
+		if err != nil {
+			return err
+		}
+		if interval > 0 {
+			s.AddWorker("internal-systemd-watchdog", &systemdWatchdog{
+				s:        s,
+				notifier: n,
+				interval: interval,
+				done:     make(chan struct{}),
+			})
+		}
+
+		return nil
+	}
+}
+
+// systemdWatchdogInterval returns the interval to ping the watchdog at, half
+// its timeout, or 0 if the watchdog is disabled or meant for another process.
+func systemdWatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	us, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || us <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(us) * time.Microsecond / 2, nil
+}
+
+// systemdNotifier sends state changes to systemd's notification socket.
+type systemdNotifier struct {
+	socket string
+}
+
+// notify sends the state, e.g. "READY=1".
+func (n *systemdNotifier) notify(state string) error {
+	addr := n.socket
+	// Abstract namespace socket.
+	if strings.HasPrefix(addr, "@") {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+func (n *systemdNotifier) hook(state string) Hook {
+	return func(_ context.Context, _ Event) error {
+		return n.notify(state)
+	}
+}
+
+var _ Worker = (*systemdWatchdog)(nil)
+
+// systemdWatchdog defines the internal worker pinging systemd's watchdog.
+type systemdWatchdog struct {
+	s        *SVC
+	logger   *zap.Logger
+	notifier *systemdNotifier
+	interval time.Duration
+	done     chan struct{}
+}
+
+// Init implements the Worker interface.
+func (w *systemdWatchdog) Init(logger *zap.Logger) error {
+	w.logger = logger
+
+	return nil
+}
+
+// Run implements the Worker interface.
+func (w *systemdWatchdog) Run() error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.ping()
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return nil
+		}
+	}
+}
+
+// Terminate implements the Worker interface.
+func (w *systemdWatchdog) Terminate() error {
+	close(w.done)
+
+	return nil
+}
+
+// ping pings the watchdog if all workers are alive.
+func (w *systemdWatchdog) ping() {
+	if err := w.alive(); err != nil {
+		w.logger.Warn("Not pinging systemd watchdog", zap.Error(err))
+		return
+	}
+	if err := w.notifier.notify("WATCHDOG=1"); err != nil {
+		w.logger.Warn("Could not ping systemd watchdog", zap.Error(err))
+	}
+}
+
+// alive returns the errors of the workers that are not alive.
+func (w *systemdWatchdog) alive() error {
+	var errs []error
+	for _, name := range w.s.workersAdded {
+		if _, err := w.s.checkAlive(name, w.s.workers[name]); err != nil {
+			errs = append(errs, fmt.Errorf("worker %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,141 @@
+package svc
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// listenNotifySocket listens on a notification socket set in NOTIFY_SOCKET,
+// returning the received states.
+func listenNotifySocket(t *testing.T) <-chan string {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	states := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			states <- string(buf[:n])
+		}
+	}()
+	return states
+}
+
+func receiveState(t *testing.T, states <-chan string) string {
+	select {
+	case state := <-states:
+		return state
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "No state received")
+		return ""
+	}
+}
+
+func TestWithSystemdNotify(t *testing.T) {
+	states := listenNotifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	s, err := New("dummy-service", "v0.0.0", WithSystemdNotify())
+	require.NoError(t, err)
+
+	alive := make(chan error, 1)
+	alive <- nil
+	running := make(chan struct{})
+	stop := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			close(running)
+			<-stop
+			return nil
+		},
+		TerminateFunc: func() error {
+			close(stop)
+			return nil
+		},
+		AliveFunc: func() error {
+			err := <-alive
+			alive <- err
+			return err
+		},
+	})
+	done := make(chan error)
+	go func() { done <- s.RunE() }()
+	<-running
+
+	// The watchdog may ping before READY=1 is sent.
+	received := []string{receiveState(t, states), receiveState(t, states)}
+	assert.Contains(t, received, "READY=1")
+	assert.Contains(t, received, "WATCHDOG=1")
+
+	<-alive
+	alive <- errors.New("dummy error")
+	// Drain the pings sent before the worker died.
+	time.Sleep(120 * time.Millisecond)
+	for len(states) > 0 {
+		<-states
+	}
+	select {
+	case state := <-states:
+		assert.Failf(t, "Watchdog pinged while not alive", "received %s", state)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	s.Shutdown()
+	require.NoError(t, <-done)
+	assert.Equal(t, "STOPPING=1", receiveState(t, states))
+}
+
+func TestWithSystemdNotifyDisabled(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	s, err := New("dummy-service", "v0.0.0", WithSystemdNotify())
+	require.NoError(t, err)
+	assert.Empty(t, s.hooks)
+	assert.Empty(t, s.workers)
+}
+
+func TestSystemdWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name             string
+		usec             string
+		pid              string
+		expectedInterval time.Duration
+		expectedError    bool
+	}{
+		{name: "disabled"},
+		{name: "enabled", usec: "30000000", expectedInterval: 15 * time.Second},
+		{name: "own pid", usec: "30000000", pid: strconv.Itoa(os.Getpid()), expectedInterval: 15 * time.Second},
+		{name: "other pid", usec: "30000000", pid: "1"},
+		{name: "invalid", usec: "30s", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tc.usec)
+			t.Setenv("WATCHDOG_PID", tc.pid)
+			interval, err := systemdWatchdogInterval()
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedInterval, interval)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// TrackTask registers a short-lived background task, e.g. a fire-and-forget
+// goroutine started by a handler, that the shutdown waits for before
+// terminating the workers, bounded by the termination grace period. The
+// returned function must be called once the task is done; calling it again is
+// a no-op. The task is not tied to a context, as e.g. a request's context is
+// canceled once its handler returns, while the task still runs.
+func (s *SVC) TrackTask() (done func()) {
+	s.tasks.add()
+	var once sync.Once
+	return func() { once.Do(s.tasks.release) }
+}
+
+// waitTasks waits for the tracked tasks until ctx is done.
+func (s *SVC) waitTasks(ctx context.Context) {
+	n, idle := s.tasks.pending()
+	if n == 0 {
+		return
+	}
+	s.logger.Info("Waiting for background tasks", zap.Int("tasks", n))
+	select {
+	case <-idle:
+		s.logger.Info("Background tasks done")
+	case <-ctx.Done():
+		n, _ = s.tasks.pending()
+		s.logger.Warn("Background tasks not done within grace period", zap.Int("tasks", n))
+	}
+}
+
+// taskTracker counts the running background tasks.
+type taskTracker struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+func (t *taskTracker) add() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.n == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.n++
+}
+
+func (t *taskTracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.n--
+	if t.n == 0 {
+		close(t.idle)
+	}
+}
+
+// pending returns the number of running tasks and a channel closed once none
+// is running.
+func (t *taskTracker) pending() (int, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.n, t.idle
+}
@@ -0,0 +1,86 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTrackTask(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var seq []string
+	appendSeq := func(v string) {
+		mu.Lock()
+		seq = append(seq, v)
+		mu.Unlock()
+	}
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			done := s.TrackTask()
+			go func() {
+				defer done()
+				time.Sleep(20 * time.Millisecond)
+				appendSeq("task")
+			}()
+			s.Shutdown()
+			return nil
+		},
+		TerminateFunc: func() error {
+			appendSeq("terminate")
+			return nil
+		},
+	})
+	s.Run()
+
+	assert.Equal(t, []string{"task", "terminate"}, seq)
+}
+
+func TestTrackTaskDone(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	// Released through done only, e.g. not when the request is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.TrackTask()
+	cancel()
+	<-ctx.Done()
+	n, idle := s.tasks.pending()
+	require.Equal(t, 1, n)
+
+	done()
+	done() // no-op
+	select {
+	case <-idle:
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Task has not been released")
+	}
+	n, _ = s.tasks.pending()
+	assert.Equal(t, 0, n)
+}
+
+func TestTrackTaskGracePeriod(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithTerminationGracePeriod(50*time.Millisecond))
+	require.NoError(t, err)
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			// Never done.
+			_ = s.TrackTask()
+			return nil
+		},
+		TerminateFunc: func() error { return nil },
+	})
+
+	start := time.Now()
+	require.NoError(t, s.RunE())
+	assert.Less(t, time.Since(start), time.Second)
+}
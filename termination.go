@@ -0,0 +1,196 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrShutdownStarted is returned when changing the termination periods after
+// the shutdown began.
+var ErrShutdownStarted = errors.New("shutdown already started")
+
+// SetTerminationWaitPeriod sets the termination wait period at runtime, e.g. to
+// lengthen drains during risky deploys. It fails once the shutdown began.
+func (s *SVC) SetTerminationWaitPeriod(d time.Duration) error {
+	return s.setTerminationPeriods(&d, nil)
+}
+
+// SetTerminationGracePeriod sets the termination grace period at runtime. It
+// fails once the shutdown began.
+func (s *SVC) SetTerminationGracePeriod(d time.Duration) error {
+	return s.setTerminationPeriods(nil, &d)
+}
+
+func (s *SVC) setTerminationPeriods(wait, grace *time.Duration) error {
+	if (wait != nil && *wait < 0) || (grace != nil && *grace < 0) {
+		return errors.New("termination periods must not be negative")
+	}
+
+	s.terminationMu.Lock()
+	defer s.terminationMu.Unlock()
+	if s.terminating {
+		return ErrShutdownStarted
+	}
+	if wait != nil {
+		s.TerminationWaitPeriod = *wait
+	}
+	if grace != nil {
+		s.TerminationGracePeriod = *grace
+	}
+	return nil
+}
+
+// WithWorkerTerminationTimeout is an option that bounds the named worker's
+// termination, so that a slow Terminate does not use up the grace period of
+// the workers terminated after it.
+func WithWorkerTerminationTimeout(name string, d time.Duration) Option {
+	return func(s *SVC) error {
+		if d <= 0 {
+			return fmt.Errorf("termination timeout of worker %s must be positive", name)
+		}
+		s.workerTermTimeouts[name] = d
+
+		return nil
+	}
+}
+
+// WithConcurrentTermination is an option that terminates the workers
+// concurrently rather than one at a time in reverse initialization order.
+// Workers declaring dependencies with AddWorkerWithDeps are still terminated
+// before their dependencies.
+func WithConcurrentTermination() Option {
+	return func(s *SVC) error {
+		s.concurrentTermination = true
+
+		return nil
+	}
+}
+
+// terminationPeriods returns the termination wait and grace periods.
+func (s *SVC) terminationPeriods() (wait, grace time.Duration) {
+	s.terminationMu.Lock()
+	defer s.terminationMu.Unlock()
+
+	return s.TerminationWaitPeriod, s.TerminationGracePeriod
+}
+
+// skipTerminationWait skips the termination wait period, as it already
+// elapsed, e.g. in the pre-stop hook.
+func (s *SVC) skipTerminationWait() {
+	s.terminationMu.Lock()
+	defer s.terminationMu.Unlock()
+
+	s.terminationWaited = true
+}
+
+// beginTermination freezes the termination periods, starting the grace period
+// on the first call, and returns the wait period and the remaining grace
+// period.
+func (s *SVC) beginTermination() (wait, grace time.Duration) {
+	s.terminationMu.Lock()
+	defer s.terminationMu.Unlock()
+
+	if !s.terminating {
+		s.terminating = true
+		s.terminationDeadline = time.Now().Add(s.TerminationGracePeriod)
+	}
+	grace = time.Until(s.terminationDeadline)
+	if s.terminationWaited {
+		return 0, grace
+	}
+	return s.TerminationWaitPeriod, grace
+}
+
+// graceDeadline returns the end of the grace period, if the termination began.
+func (s *SVC) graceDeadline() (time.Time, bool) {
+	s.terminationMu.Lock()
+	defer s.terminationMu.Unlock()
+
+	return s.terminationDeadline, s.terminating
+}
+
+// Life-cycle states, as reported by lifecycleState.
+const (
+	stateStarting    = "starting"
+	stateRunning     = "running"
+	stateTerminating = "terminating"
+)
+
+// lifecycleState returns the service's life-cycle state.
+func (s *SVC) lifecycleState() string {
+	s.terminationMu.Lock()
+	terminating := s.terminating
+	s.terminationMu.Unlock()
+
+	switch {
+	case terminating:
+		return stateTerminating
+	case s.self.isInitialized():
+		return stateRunning
+	default:
+		return stateStarting
+	}
+}
+
+// WithTerminationHandlers is an option that sets up an HTTP route to read
+// (GET) and change (PUT) the termination periods at runtime, e.g.
+// `{"grace_period": "60s"}`. Changes are rejected with 409 Conflict once the
+// shutdown began.
+func WithTerminationHandlers() Option {
+	return func(s *SVC) error {
+		s.handle("WithTerminationHandlers", "/termination", http.HandlerFunc(s.terminationHandler))
+
+		return nil
+	}
+}
+
+func (s *SVC) terminationHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload TerminationPeriods
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("invalid payload: %s", err), http.StatusBadRequest)
+			return
+		}
+		wait, err := parseOptionalDuration(payload.WaitPeriod)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid wait_period: %s", err), http.StatusBadRequest)
+			return
+		}
+		grace, err := parseOptionalDuration(payload.GracePeriod)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid grace_period: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := s.setTerminationPeriods(wait, grace); err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, ErrShutdownStarted) {
+				code = http.StatusConflict
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wait, grace := s.terminationPeriods()
+	writeResponse(w, http.StatusOK, TerminationPeriods{WaitPeriod: wait.String(), GracePeriod: grace.String()})
+}
+
+func parseOptionalDuration(v string) (*time.Duration, error) {
+	if v == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,146 @@
+package svc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSetTerminationPeriods(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetTerminationWaitPeriod(5*time.Second))
+	require.NoError(t, s.SetTerminationGracePeriod(time.Minute))
+	require.Error(t, s.SetTerminationGracePeriod(-time.Second))
+	wait, grace := s.terminationPeriods()
+	assert.Equal(t, 5*time.Second, wait)
+	assert.Equal(t, time.Minute, grace)
+
+	s.beginTermination()
+	require.ErrorIs(t, s.SetTerminationGracePeriod(time.Hour), ErrShutdownStarted)
+}
+
+func TestWithTerminationHandlers(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithTerminationHandlers())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "get",
+			method:       "GET",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"wait_period":"0s","grace_period":"15s"}`,
+		},
+		{
+			name:         "put grace period",
+			method:       "PUT",
+			body:         `{"grace_period":"1m"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"wait_period":"0s","grace_period":"1m0s"}`,
+		},
+		{
+			name:         "put invalid duration",
+			method:       "PUT",
+			body:         `{"wait_period":"soon"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "post",
+			method:       "POST",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Router.ServeHTTP(rec, httptest.NewRequest(tc.method, "/termination", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	s.beginTermination()
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, httptest.NewRequest("PUT", "/termination", strings.NewReader(`{"grace_period":"1h"}`)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestTerminationOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "reverse initialization order",
+			opts: []Option{WithWorkerTerminationTimeout("slow", 20*time.Millisecond)},
+			want: []string{"consumer", "cache", "db"},
+		},
+		{
+			name: "concurrent",
+			opts: []Option{WithConcurrentTermination(), WithWorkerTerminationTimeout("slow", 20*time.Millisecond)},
+			want: []string{"consumer", "cache", "db"},
+		},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := New("dummy-service", "v0.0.0", tc.opts...)
+			require.NoError(t, err)
+
+			var mu sync.Mutex
+			var terminated []string
+			block := make(chan struct{})
+			defer close(block)
+			worker := func(name string) Worker {
+				return &WorkerMock{
+					InitFunc: func(*zap.Logger) error { return nil },
+					RunFunc:  func() error { return nil },
+					TerminateFunc: func() error {
+						if name == "slow" {
+							<-block
+						}
+						mu.Lock()
+						terminated = append(terminated, name)
+						mu.Unlock()
+						return nil
+					},
+				}
+			}
+			s.AddWorker("db", worker("db"))
+			s.AddWorker("slow", worker("slow"))
+			s.AddWorkerWithDeps("cache", worker("cache"), "db")
+			s.AddWorkerWithDeps("consumer", worker("consumer"), "cache")
+
+			start := time.Now()
+			require.NoError(t, s.RunE())
+			assert.Less(t, time.Since(start), time.Second)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tc.want, terminated)
+		})
+	}
+}
+
+func TestWithWorkerTerminationTimeoutInvalid(t *testing.T) {
+	_, err := New("dummy-service", "v0.0.0", WithWorkerTerminationTimeout("db", 0))
+	require.EqualError(t, err, "termination timeout of worker db must be positive")
+}
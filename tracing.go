@@ -0,0 +1,45 @@
+package svc
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// workerTask defines the execution trace task spanning a worker's life, from
+// its initialization to its termination.
+type workerTask struct {
+	ctx  context.Context
+	task *trace.Task
+}
+
+// traceWorker calls fn in the worker's trace region, e.g. "Init", so execution
+// traces, e.g. captured from /debug/pprof/trace, show each worker's activity
+// in go tool trace's user-defined tasks and regions. fn's context carries the
+// task. Tracing costs next to nothing unless a trace is being captured.
+func (s *SVC) traceWorker(name, region string, fn func(ctx context.Context)) {
+	s.tracesMu.Lock()
+	t, ok := s.workerTasks[name]
+	if !ok {
+		t.ctx, t.task = trace.NewTask(context.Background(), "worker "+name)
+		if s.workerTasks == nil {
+			s.workerTasks = map[string]workerTask{}
+		}
+		s.workerTasks[name] = t
+	}
+	s.tracesMu.Unlock()
+
+	trace.WithRegion(t.ctx, region, func() { fn(t.ctx) })
+}
+
+// endWorkerTrace ends the worker's trace task, once terminated or failed to
+// initialize.
+func (s *SVC) endWorkerTrace(name string) {
+	s.tracesMu.Lock()
+	t, ok := s.workerTasks[name]
+	delete(s.workerTasks, name)
+	s.tracesMu.Unlock()
+
+	if ok {
+		t.task.End()
+	}
+}
@@ -0,0 +1,40 @@
+package svc
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWorkerTracing(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	s.AddWorker("dummy-worker", &WorkerMock{
+		InitFunc: func(*zap.Logger) error { return nil },
+		RunFunc: func() error {
+			s.Shutdown()
+			<-done
+			return nil
+		},
+		TerminateFunc: func() error {
+			close(done)
+			return nil
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Start(&buf))
+	s.Run()
+	trace.Stop()
+
+	for _, name := range []string{"worker dummy-worker", "Init", "Run", "Terminate"} {
+		assert.Contains(t, buf.String(), name)
+	}
+	assert.Empty(t, s.workerTasks)
+}
@@ -1,6 +1,9 @@
 package svc
 
 import (
+	"context"
+	"slices"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
@@ -17,6 +20,30 @@ type Aliver interface {
 	Alive() error
 }
 
+// Starter defines a worker that can report whether it completed its warm-up
+// phase, for startup probes.
+type Starter interface {
+	Started() error
+}
+
+// Describer defines a worker that can describe itself, for the admin API,
+// the startup logs, and the diagnostics report.
+type Describer interface {
+	Describe() WorkerInfo
+}
+
+// WorkerInfo defines the metadata a worker reports about itself.
+type WorkerInfo struct {
+	Description string `json:"description,omitempty"`
+	// Owner is the team or person owning the worker, e.g. to page.
+	Owner string `json:"owner,omitempty"`
+	// Dependencies are the systems the worker depends on, e.g. "postgres".
+	// Dependencies declared with AddWorkerWithDeps are appended.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Endpoints are the endpoints the worker exposes, e.g. ":8080/api".
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
 // Healther defines a worker that can report his healthz status.
 type Healther interface {
 	Healthy() error
@@ -27,3 +54,44 @@ type Healther interface {
 type Gatherer interface {
 	Gatherer() prometheus.Gatherer
 }
+
+// WorkerCtx defines a SVC worker whose Run is passed a context, canceled when
+// the service shuts down, after the termination wait period. It may also
+// implement Terminate() error, called once Run returned, and the optional
+// worker interfaces, e.g. Healther.
+type WorkerCtx interface {
+	Init(*zap.Logger) error
+	Run(ctx context.Context) error
+}
+
+// unwrapper defines a worker adapting another worker, which implements the
+// optional worker interfaces.
+type unwrapper interface {
+	unwrapWorker() interface{}
+}
+
+// unwrapWorker returns the worker adapted by w, if any, to look up the
+// optional worker interfaces it implements.
+func unwrapWorker(w interface{}) interface{} {
+	if u, ok := w.(unwrapper); ok {
+		return u.unwrapWorker()
+	}
+	return w
+}
+
+// describeWorker returns the worker's self-reported metadata, with its declared
+// dependencies, and whether there is any.
+func (s *SVC) describeWorker(name string) (WorkerInfo, bool) {
+	var info WorkerInfo
+	d, described := unwrapWorker(s.workers[name]).(Describer)
+	if described {
+		info = d.Describe()
+		info.Dependencies = append([]string{}, info.Dependencies...)
+	}
+	for _, dep := range s.workerDeps[name] {
+		if !slices.Contains(info.Dependencies, dep) {
+			info.Dependencies = append(info.Dependencies, dep)
+		}
+	}
+	return info, described || len(s.workerDeps[name]) > 0
+}
@@ -0,0 +1,318 @@
+// Package kafka provides a Kafka consumer group worker for svc, independent of
+// the Kafka client library.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/voi-oss/svc"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultCommitTimeout  = 10 * time.Second
+	defaultLagTimeout     = 5 * time.Second
+	deadLetterTimeout     = 10 * time.Second
+)
+
+var (
+	_ svc.Worker   = (*Worker)(nil)
+	_ svc.Healther = (*Worker)(nil)
+	_ svc.Drainer  = (*Worker)(nil)
+)
+
+// Message defines a consumed record.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string][]byte
+	Time      time.Time
+}
+
+// Consumer defines the consumer group client the worker manages, so the
+// service does not depend on a Kafka client, e.g. an adapter of a
+// *kafka.Reader of segmentio/kafka-go or a *kgo.Client of franz-go.
+type Consumer interface {
+	// Connect joins the consumer group. It is called again when the worker
+	// is added with svc.AddWorkerWithInitRetry and connecting failed.
+	Connect(ctx context.Context) error
+	// Poll returns the next messages, blocking until some are available or
+	// the context is done.
+	Poll(ctx context.Context) ([]Message, error)
+	// Commit commits the offsets of the messages.
+	Commit(ctx context.Context, msgs []Message) error
+	// Lag returns the number of messages of the assigned partitions not
+	// consumed yet.
+	Lag(ctx context.Context) (int64, error)
+	// Close leaves the consumer group.
+	Close() error
+}
+
+// Handler handles a message. Its context is canceled when the worker is
+// terminated.
+type Handler func(ctx context.Context, msg Message) error
+
+// Option defines Worker's option type.
+type Option func(*Worker)
+
+// WithMaxLag fails the worker's health check when the consumer group lags
+// behind by more than n messages. Unlimited by default.
+func WithMaxLag(n int64) Option {
+	return func(w *Worker) {
+		w.maxLag = n
+	}
+}
+
+// WithConnectTimeout sets how long Init waits for the consumer to connect.
+// Defaults to 10s.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(w *Worker) {
+		w.connectTimeout = d
+	}
+}
+
+// WithCommitTimeout sets how long committing the offsets may take. Defaults to
+// 10s.
+func WithCommitTimeout(d time.Duration) Option {
+	return func(w *Worker) {
+		w.commitTimeout = d
+	}
+}
+
+// WithHandlerRetry retries a failing handler according to the options, e.g.
+// retry.Attempts(3) and retry.Delay(time.Second), until the worker is
+// terminated. Handlers are not retried by default. The attempts must be
+// bounded, as the consumer does not progress in the meantime.
+func WithHandlerRetry(opts ...retry.Option) Option {
+	return func(w *Worker) {
+		w.retryOpts = opts
+	}
+}
+
+// WithDeadLetterer hands the messages still failing once the handler's retries
+// are exhausted to d, then commits them and continues consuming, rather than
+// failing the worker. Pass svc.DeadLettererFunc(s.DeadLetter) to use the
+// service's sink and metrics, see svc.WithDeadLetterer.
+func WithDeadLetterer(d svc.DeadLetterer) Option {
+	return func(w *Worker) {
+		w.deadLetterer = d
+	}
+}
+
+// Worker defines the worker consuming messages of a consumer group. Messages
+// are handled one at a time in the order they are polled, and the offsets of
+// each batch committed once handled. A handler failure, once retried with
+// WithHandlerRetry, is handed to the dead-letterer set with WithDeadLetterer.
+// Without one, or if dead-lettering fails, it fails the worker without
+// committing the message, so it is consumed again once restarted, e.g. with
+// svc.AddWorkerWithRestart.
+type Worker struct {
+	consumer       Consumer
+	handler        Handler
+	maxLag         int64
+	connectTimeout time.Duration
+	commitTimeout  time.Duration
+	retryOpts      []retry.Option
+	deadLetterer   svc.DeadLetterer
+
+	logger      *zap.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	pollCtx     context.Context
+	stopPolling context.CancelFunc
+	mu          sync.Mutex
+	running     sync.WaitGroup
+}
+
+// New returns a worker consuming the messages of the consumer with handler.
+func New(consumer Consumer, handler Handler, opts ...Option) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	w := &Worker{
+		consumer:       consumer,
+		handler:        handler,
+		connectTimeout: defaultConnectTimeout,
+		commitTimeout:  defaultCommitTimeout,
+		logger:         zap.NewNop(),
+		ctx:            ctx,
+		cancel:         cancel,
+		pollCtx:        pollCtx,
+		stopPolling:    stopPolling,
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// Init implements the svc.Worker interface. It connects the consumer.
+func (w *Worker) Init(logger *zap.Logger) error {
+	w.logger = logger
+
+	ctx, cancel := context.WithTimeout(w.ctx, w.connectTimeout)
+	defer cancel()
+	if err := w.consumer.Connect(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	return nil
+}
+
+// Run implements the svc.Worker interface. It polls and handles messages until
+// drained or terminated.
+func (w *Worker) Run() error {
+	w.mu.Lock()
+	if w.pollCtx.Err() != nil {
+		w.mu.Unlock()
+		return nil
+	}
+	w.running.Add(1)
+	w.mu.Unlock()
+	defer w.running.Done()
+
+	for {
+		msgs, err := w.consumer.Poll(w.pollCtx)
+		if w.pollCtx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("poll: %w", err)
+		}
+		if err := w.handle(msgs); err != nil || w.ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// handle handles the messages and commits those handled, including when the
+// worker is terminated in between.
+func (w *Worker) handle(msgs []Message) error {
+	var handleErr error
+	handled := 0
+	for _, msg := range msgs {
+		if w.ctx.Err() != nil {
+			break
+		}
+		if err := w.handleMessage(msg); err != nil {
+			if w.ctx.Err() == nil {
+				handleErr = fmt.Errorf("handle %s/%d@%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+			}
+			break
+		}
+		handled++
+	}
+	if handled == 0 {
+		return handleErr
+	}
+
+	// Not canceled by termination, for the handled messages to be committed.
+	ctx, cancel := context.WithTimeout(context.Background(), w.commitTimeout)
+	defer cancel()
+	if err := w.consumer.Commit(ctx, msgs[:handled]); err != nil {
+		return errors.Join(handleErr, fmt.Errorf("commit: %w", err))
+	}
+	return handleErr
+}
+
+// handleMessage handles the message, retrying the handler, and dead-letters it
+// if still failing. It fails if the message is neither handled nor
+// dead-lettered.
+func (w *Worker) handleMessage(msg Message) error {
+	attempts := 0
+	opts := append([]retry.Option{retry.Attempts(1)}, w.retryOpts...)
+	opts = append(opts, retry.Context(w.ctx), retry.LastErrorOnly(true))
+	err := retry.Do(func() error {
+		attempts++
+		return w.handler(w.ctx, msg)
+	}, opts...)
+	if err == nil || w.deadLetterer == nil || w.ctx.Err() != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = string(v)
+	}
+	// Not canceled by termination, as the message is committed once
+	// dead-lettered.
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	if dlErr := w.deadLetterer.DeadLetter(ctx, svc.DeadLetter{
+		Source:   msg.Topic,
+		Key:      msg.Key,
+		Payload:  msg.Value,
+		Headers:  headers,
+		Attempts: attempts,
+		Err:      err,
+		FailedAt: time.Now(),
+	}); dlErr != nil {
+		return errors.Join(err, fmt.Errorf("dead-letter: %w", dlErr))
+	}
+	w.logger.Warn("Message dead-lettered",
+		zap.String("topic", msg.Topic),
+		zap.Int32("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+		zap.Int("attempts", attempts),
+		zap.Error(err))
+	return nil
+}
+
+// Drain implements the svc.Drainer interface. It stops polling, and waits for
+// the messages already polled to be handled and their offsets committed, or
+// ctx to be done.
+func (w *Worker) Drain(ctx context.Context) error {
+	w.mu.Lock()
+	w.stopPolling()
+	w.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		w.running.Wait()
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Terminate implements the svc.Worker interface. It stops polling, waits for
+// the offsets of the handled messages to be committed, and closes the
+// consumer.
+func (w *Worker) Terminate() error {
+	w.mu.Lock()
+	w.cancel()
+	w.mu.Unlock()
+	w.running.Wait()
+
+	return w.consumer.Close()
+}
+
+// Healthy implements the svc.Healther interface. It fails if the consumer
+// group lags behind by more than the limit set with WithMaxLag.
+func (w *Worker) Healthy() error {
+	if w.maxLag <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultLagTimeout)
+	defer cancel()
+	lag, err := w.consumer.Lag(ctx)
+	if err != nil {
+		return fmt.Errorf("lag: %w", err)
+	}
+	if lag > w.maxLag {
+		return fmt.Errorf("lagging behind by %d messages, more than %d", lag, w.maxLag)
+	}
+	return nil
+}
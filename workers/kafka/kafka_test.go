@@ -0,0 +1,335 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voi-oss/svc"
+	"go.uber.org/zap"
+)
+
+type fakeConsumer struct {
+	connectErr error
+	lag        int64
+	batches    chan []Message
+
+	mu        sync.Mutex
+	committed []int64
+	closed    bool
+}
+
+func (c *fakeConsumer) Connect(context.Context) error { return c.connectErr }
+
+func (c *fakeConsumer) Poll(ctx context.Context) ([]Message, error) {
+	select {
+	case msgs := <-c.batches:
+		return msgs, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakeConsumer) Commit(_ context.Context, msgs []Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range msgs {
+		c.committed = append(c.committed, m.Offset)
+	}
+	return nil
+}
+
+func (c *fakeConsumer) Lag(context.Context) (int64, error) { return c.lag, nil }
+
+func (c *fakeConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConsumer) state() ([]int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int64{}, c.committed...), c.closed
+}
+
+func batch(offsets ...int64) []Message {
+	msgs := make([]Message, 0, len(offsets))
+	for _, o := range offsets {
+		msgs = append(msgs, Message{Topic: "orders", Offset: o})
+	}
+	return msgs
+}
+
+func TestWorker(t *testing.T) {
+	c := &fakeConsumer{batches: make(chan []Message, 2)}
+	var mu sync.Mutex
+	var handled []int64
+	w := New(c, func(_ context.Context, msg Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, msg.Offset)
+		return nil
+	})
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+	c.batches <- batch(1, 2)
+	c.batches <- batch(3)
+	require.Eventually(t, func() bool {
+		committed, _ := c.state()
+		return len(committed) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-errs)
+	committed, closed := c.state()
+	assert.Equal(t, []int64{1, 2, 3}, committed)
+	assert.Equal(t, []int64{1, 2, 3}, handled)
+	assert.True(t, closed)
+}
+
+func TestWorkerHandlerFailure(t *testing.T) {
+	c := &fakeConsumer{batches: make(chan []Message, 1)}
+	w := New(c, func(_ context.Context, msg Message) error {
+		if msg.Offset == 2 {
+			return errors.New("dummy error")
+		}
+		return nil
+	})
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	c.batches <- batch(1, 2, 3)
+	require.EqualError(t, w.Run(), "handle orders/0@2: dummy error")
+	committed, _ := c.state()
+	assert.Equal(t, []int64{1}, committed)
+}
+
+func TestWorkerDeadLetter(t *testing.T) {
+	tests := []struct {
+		name              string
+		deadLetterErr     error
+		expectedErr       string
+		expectedCommitted []int64
+	}{
+		{
+			name:              "dead-lettered",
+			expectedCommitted: []int64{1, 2, 3},
+		},
+		{
+			name:              "dead-letter failure",
+			deadLetterErr:     errors.New("dummy dead-letter error"),
+			expectedErr:       "handle orders/0@2: dummy error\ndead-letter: dummy dead-letter error",
+			expectedCommitted: []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			c := &fakeConsumer{batches: make(chan []Message, 1)}
+			attempts := map[int64]int{}
+			var dead []svc.DeadLetter
+			w := New(c, func(_ context.Context, msg Message) error {
+				attempts[msg.Offset]++
+				// The first message succeeds once retried.
+				if msg.Offset == 2 || (msg.Offset == 1 && attempts[msg.Offset] == 1) {
+					return errors.New("dummy error")
+				}
+				return nil
+			},
+				WithHandlerRetry(retry.Attempts(3), retry.Delay(time.Millisecond)),
+				WithDeadLetterer(svc.DeadLettererFunc(func(_ context.Context, msg svc.DeadLetter) error {
+					dead = append(dead, msg)
+					return tc.deadLetterErr
+				})))
+			require.NoError(t, w.Init(zap.NewNop()))
+
+			c.batches <- []Message{
+				{Topic: "orders", Offset: 1},
+				{Topic: "orders", Offset: 2, Key: []byte("dummy-key"), Headers: map[string][]byte{"dummy": []byte("header")}},
+				{Topic: "orders", Offset: 3},
+			}
+			if tc.expectedErr != "" {
+				require.EqualError(t, w.Run(), tc.expectedErr)
+			} else {
+				go func() {
+					assert.Eventually(t, func() bool {
+						committed, _ := c.state()
+						return len(committed) == 3
+					}, time.Second, 5*time.Millisecond)
+					assert.NoError(t, w.Terminate())
+				}()
+				require.NoError(t, w.Run())
+			}
+
+			committed, _ := c.state()
+			assert.Equal(t, tc.expectedCommitted, committed)
+			assert.Equal(t, 3, attempts[2])
+			require.Len(t, dead, 1)
+			assert.Equal(t, "orders", dead[0].Source)
+			assert.Equal(t, []byte("dummy-key"), dead[0].Key)
+			assert.Equal(t, map[string]string{"dummy": "header"}, dead[0].Headers)
+			assert.Equal(t, 3, dead[0].Attempts)
+			assert.EqualError(t, dead[0].Err, "dummy error")
+		})
+	}
+}
+
+func TestWorkerServiceDeadLetterer(t *testing.T) {
+	dead := make(chan svc.DeadLetter, 1)
+	s, err := svc.New("dummy-service", "v0.0.0", svc.WithDeadLetterer(svc.DeadLettererFunc(
+		func(_ context.Context, msg svc.DeadLetter) error {
+			dead <- msg
+			return nil
+		})))
+	require.NoError(t, err)
+
+	c := &fakeConsumer{batches: make(chan []Message, 1)}
+	w := New(c, func(context.Context, Message) error { return errors.New("dummy error") },
+		WithDeadLetterer(svc.DeadLettererFunc(s.DeadLetter)))
+	require.NoError(t, w.Init(zap.NewNop()))
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+
+	c.batches <- batch(1)
+	select {
+	case msg := <-dead:
+		assert.Equal(t, "orders", msg.Source)
+		assert.Equal(t, 1, msg.Attempts)
+	case <-time.After(3 * time.Second):
+		require.FailNow(t, "Message has not been dead-lettered")
+	}
+	require.Eventually(t, func() bool {
+		committed, _ := c.state()
+		return len(committed) == 1
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-errs)
+}
+
+func TestWorkerTerminateWhileHandling(t *testing.T) {
+	c := &fakeConsumer{batches: make(chan []Message, 1)}
+	started := make(chan struct{})
+	w := New(c, func(ctx context.Context, msg Message) error {
+		if msg.Offset == 2 {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+	c.batches <- batch(1, 2, 3)
+	<-started
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-errs)
+	committed, closed := c.state()
+	assert.Equal(t, []int64{1}, committed)
+	assert.True(t, closed)
+}
+
+func TestWorkerDrain(t *testing.T) {
+	c := &fakeConsumer{batches: make(chan []Message, 2)}
+	started, release := make(chan struct{}), make(chan struct{})
+	w := New(c, func(ctx context.Context, msg Message) error {
+		if msg.Offset == 2 {
+			close(started)
+			<-release
+		}
+		return ctx.Err()
+	})
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+	c.batches <- batch(1, 2, 3)
+	<-started
+	c.batches <- batch(4)
+
+	drained := make(chan error, 1)
+	go func() { drained <- w.Drain(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("drained before the messages in flight were handled")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-drained)
+	require.NoError(t, <-errs)
+	committed, closed := c.state()
+	assert.Equal(t, []int64{1, 2, 3}, committed)
+	assert.False(t, closed)
+
+	require.NoError(t, w.Terminate())
+	_, closed = c.state()
+	assert.True(t, closed)
+}
+
+func TestWorkerDrainTimeout(t *testing.T) {
+	c := &fakeConsumer{batches: make(chan []Message, 1)}
+	started := make(chan struct{})
+	w := New(c, func(ctx context.Context, msg Message) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, w.Init(zap.NewNop()))
+
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+	c.batches <- batch(1)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.Drain(ctx), context.DeadlineExceeded)
+	require.NoError(t, w.Terminate())
+	require.NoError(t, <-errs)
+}
+
+func TestWorkerInit(t *testing.T) {
+	w := New(&fakeConsumer{connectErr: errors.New("dummy error")}, nil)
+	require.EqualError(t, w.Init(zap.NewNop()), "connect: dummy error")
+}
+
+func TestWorkerHealthy(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		lag           int64
+		expectedError string
+	}{
+		{name: "no limit", lag: 1000},
+		{name: "within limit", opts: []Option{WithMaxLag(100)}, lag: 100},
+		{
+			name:          "lagging",
+			opts:          []Option{WithMaxLag(100)},
+			lag:           101,
+			expectedError: "lagging behind by 101 messages, more than 100",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			w := New(&fakeConsumer{lag: tc.lag}, nil, tc.opts...)
+			err := w.Healthy()
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
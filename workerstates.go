@@ -0,0 +1,123 @@
+package svc
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Worker states reported by the /healthz/workers endpoint.
+const (
+	WorkerStateAdded       = "added"
+	WorkerStateInitialized = "initialized"
+	WorkerStateRunning     = "running"
+	WorkerStateStopped     = "stopped"
+	WorkerStateTerminated  = "terminated"
+)
+
+// workerStates tracks the workers' life-cycle from the recorded events.
+type workerStates struct {
+	mu     sync.Mutex
+	states map[string]*workerState
+}
+
+type workerState struct {
+	state           string
+	runningSince    time.Time
+	restarts        int
+	err             string
+	lastHealthErr   string
+	lastHealthErrAt time.Time
+}
+
+func newWorkerStates() *workerStates {
+	return &workerStates{states: map[string]*workerState{}}
+}
+
+// get returns the worker's state. The lock must be held.
+func (w *workerStates) get(name string) *workerState {
+	st, ok := w.states[name]
+	if !ok {
+		st = &workerState{state: WorkerStateAdded}
+		w.states[name] = st
+	}
+	return st
+}
+
+// record updates the worker's state with the event.
+func (w *workerStates) record(e Event) {
+	if e.Worker == "" {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st := w.get(e.Worker)
+	switch e.Type {
+	case EventWorkerInitialized:
+		st.state = WorkerStateInitialized
+	case EventWorkerInitFailed:
+		st.err = e.Message
+	case EventWorkerStarted:
+		st.state, st.runningSince = WorkerStateRunning, e.Time
+	case EventWorkerFinished, EventWorkerFailed:
+		st.state = WorkerStateStopped
+		if e.Type == EventWorkerFailed {
+			st.err = e.Message
+		}
+	case EventWorkerTerminated, EventWorkerTermFailed:
+		st.state = WorkerStateTerminated
+		if e.Type == EventWorkerTermFailed {
+			st.err = e.Message
+		}
+	case EventWorkerUnhealthy, EventWorkerNotAlive:
+		st.lastHealthErr, st.lastHealthErrAt = e.Message, e.Time
+	}
+}
+
+// restarted records the worker was restarted by its restart policy.
+func (w *workerStates) restarted(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	st := w.get(name)
+	st.restarts++
+	st.runningSince = time.Now()
+}
+
+// workersHandler serves the state of each worker, for operators to find which
+// worker failed the probes without searching the logs.
+func (s *SVC) workersHandler(w http.ResponseWriter, _ *http.Request) {
+	health := map[string]HealthStatus{}
+	for _, c := range s.readyChecks() {
+		health[c.Worker] = c.Status
+	}
+
+	now := time.Now()
+	workers := make([]WorkerDetail, 0, len(s.workersAdded))
+	s.workerStates.mu.Lock()
+	for _, name := range s.workersAdded {
+		st := s.workerStates.get(name)
+		d := WorkerDetail{
+			Name:            name,
+			State:           st.state,
+			Error:           st.err,
+			Restarts:        st.restarts,
+			LastHealthError: st.lastHealthErr,
+		}
+		if st.state == WorkerStateRunning {
+			d.UptimeSeconds = now.Sub(st.runningSince).Seconds()
+		}
+		if status, ok := health[name]; ok {
+			d.Health = status.String()
+		}
+		if !st.lastHealthErrAt.IsZero() {
+			at := st.lastHealthErrAt
+			d.LastHealthErrorAt = &at
+		}
+		workers = append(workers, d)
+	}
+	s.workerStates.mu.Unlock()
+
+	writeResponse(w, http.StatusOK, WorkersResponse{Workers: workers})
+}
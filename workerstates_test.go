@@ -0,0 +1,73 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkersHandler(t *testing.T) {
+	s, err := New("dummy-service", "v0.0.0", WithHealthz())
+	require.NoError(t, err)
+	healthErr := errors.New("dummy error")
+	s.AddWorker("db", &WorkerMock{HealthyFunc: func() error { return healthErr }})
+	healthy := func() error { return nil }
+	s.AddWorker("consumer", &WorkerMock{HealthyFunc: healthy})
+	s.AddWorker("cache", &WorkerMock{HealthyFunc: healthy})
+	s.AddWorker("pending", &WorkerMock{HealthyFunc: healthy})
+
+	for _, name := range []string{"db", "consumer", "cache"} {
+		s.recordEvent(EventWorkerInitialized, name, nil)
+		s.recordEvent(EventWorkerStarted, name, nil)
+	}
+	s.workerStates.restarted("consumer")
+	s.recordEvent(EventWorkerFailed, "cache", errors.New("connection lost"))
+	s.recordEvent(EventWorkerTerminated, "cache", nil)
+
+	workers := func() map[string]WorkerDetail {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/workers", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp WorkersResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		details := map[string]WorkerDetail{}
+		for _, d := range resp.Workers {
+			details[d.Name] = d
+		}
+		return details
+	}
+
+	details := workers()
+	require.Len(t, details, 4)
+
+	db := details["db"]
+	assert.Equal(t, WorkerStateRunning, db.State)
+	assert.Equal(t, "critical", db.Health)
+	assert.Equal(t, "dummy error", db.LastHealthError)
+	assert.NotNil(t, db.LastHealthErrorAt)
+	assert.Greater(t, db.UptimeSeconds, float64(0))
+
+	consumer := details["consumer"]
+	assert.Equal(t, WorkerStateRunning, consumer.State)
+	assert.Equal(t, 1, consumer.Restarts)
+	assert.Equal(t, "ok", consumer.Health)
+	assert.Empty(t, consumer.LastHealthError)
+
+	cache := details["cache"]
+	assert.Equal(t, WorkerStateTerminated, cache.State)
+	assert.Equal(t, "connection lost", cache.Error)
+	assert.Zero(t, cache.UptimeSeconds)
+
+	assert.Equal(t, WorkerDetail{Name: "pending", State: WorkerStateAdded, Health: "ok"}, details["pending"])
+
+	// The last health error is kept once recovered.
+	healthErr = nil
+	db = workers()["db"]
+	assert.Equal(t, "ok", db.Health)
+	assert.Equal(t, "dummy error", db.LastHealthError)
+}